@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"nf-student-hub-backend/config"
+)
+
+// Batas kehadiran default: peringatan di bawah 80%, tidak boleh ikut UAS di bawah 75%
+const (
+	defaultAttendanceWarningPercent = 80.0
+	defaultAttendanceMinPercent     = 75.0
+	defaultAttendanceAlertMinHeld   = 3
+)
+
+// getAttendanceThresholds membaca ATTENDANCE_WARNING_PERCENT, ATTENDANCE_MIN_PERCENT
+// dan ATTENDANCE_ALERT_MIN_PERTEMUAN dari env
+func getAttendanceThresholds() (warning, minimum float64, minHeld int) {
+	warning, minimum, minHeld = defaultAttendanceWarningPercent, defaultAttendanceMinPercent, defaultAttendanceAlertMinHeld
+
+	if v, err := strconv.ParseFloat(os.Getenv("ATTENDANCE_WARNING_PERCENT"), 64); err == nil && v > 0 && v <= 100 {
+		warning = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("ATTENDANCE_MIN_PERCENT"), 64); err == nil && v > 0 && v <= 100 {
+		minimum = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("ATTENDANCE_ALERT_MIN_PERTEMUAN")); err == nil && v > 0 {
+		minHeld = v
+	}
+	if warning < minimum {
+		warning = minimum
+	}
+	return warning, minimum, minHeld
+}
+
+// checkAttendanceThresholds - Hitung rasio kehadiran tiap mahasiswa pada mata kuliah dan kirim
+// notifikasi ke mahasiswa, orangtua dan dosen saat melewati batas peringatan atau batas minimal.
+// Dipanggil setiap kali sesi absensi ditutup.
+func checkAttendanceThresholds(courseID string) {
+	warning, minimum, minHeld := getAttendanceThresholds()
+
+	held, ratios, err := courseAttendanceRatios(courseID)
+	if err != nil {
+		log.Printf("Attendance alert: gagal menghitung kehadiran %s: %v", courseID, err)
+		return
+	}
+
+	if held < minHeld {
+		return
+	}
+
+	for studentID, percent := range ratios {
+		switch {
+		case percent < minimum:
+			// Lompat langsung ke failing: tandai warning juga agar tidak dikirim belakangan
+			raiseAttendanceAlert(studentID, courseID, "failing", percent, minimum)
+			config.DB.Exec(`
+				INSERT IGNORE INTO attendance_alerts (student_id, course_id, level, percent, created_at)
+				VALUES (?, ?, 'warning', ?, NOW())
+			`, studentID, courseID, percent)
+		case percent < warning:
+			raiseAttendanceAlert(studentID, courseID, "warning", percent, minimum)
+		default:
+			// Kehadiran sudah pulih, peringatan boleh dikirim lagi jika turun kembali
+			config.DB.Exec("DELETE FROM attendance_alerts WHERE student_id = ? AND course_id = ?", studentID, courseID)
+		}
+	}
+}
+
+// courseAttendanceRatios - Jumlah pertemuan yang sudah ditutup dan persentase kehadiran
+// (hadir atau terlambat) tiap mahasiswa terdaftar pada mata kuliah di semester aktif
+func courseAttendanceRatios(courseID string) (int, map[int]float64, error) {
+	semester := semesterArgs(currentSemesterID())
+
+	var held int
+	config.DB.QueryRow(`
+		SELECT COUNT(DISTINCT pertemuan_ke)
+		FROM attendance_sessions
+		WHERE course_id = ? AND status = 'closed' AND `+semesterFilter("semester_id")+`
+	`, append([]interface{}{courseID}, semester...)...).Scan(&held)
+
+	rows, err := config.DB.Query(`
+		SELECT mmk.mahasiswa_id,
+			(
+				SELECT COUNT(DISTINCT asess.pertemuan_ke)
+				FROM attendance a
+				JOIN attendance_sessions asess ON a.session_id = asess.id
+				WHERE a.student_id = mmk.mahasiswa_id
+					AND asess.course_id = mmk.mata_kuliah_kode
+					AND asess.status = 'closed'
+					AND `+semesterFilter("asess.semester_id")+`
+					AND a.status IN ('hadir', 'terlambat')
+					AND `+countedAttendance("a")+`
+			) AS present
+		FROM mahasiswa_mata_kuliah mmk
+		WHERE mmk.mata_kuliah_kode = ? AND `+semesterFilter("mmk.semester_id")+`
+		GROUP BY mmk.mahasiswa_id
+	`, append(append(semester, courseID), semester...)...)
+	if err != nil {
+		return held, nil, err
+	}
+	defer rows.Close()
+
+	ratios := map[int]float64{}
+	for rows.Next() {
+		var studentID, present int
+		if rows.Scan(&studentID, &present) != nil {
+			continue
+		}
+		if held == 0 {
+			ratios[studentID] = 0
+			continue
+		}
+		ratios[studentID] = float64(present) / float64(held) * 100
+	}
+
+	return held, ratios, nil
+}
+
+// raiseAttendanceAlert - Catat alert (sekali per level) lalu buat notifikasi untuk
+// mahasiswa, orangtua yang terhubung, dan dosen pengampu
+func raiseAttendanceAlert(studentID int, courseID, level string, percent, minimum float64) {
+	result, err := config.DB.Exec(`
+		INSERT IGNORE INTO attendance_alerts (student_id, course_id, level, percent, created_at)
+		VALUES (?, ?, ?, ?, NOW())
+	`, studentID, courseID, level, percent)
+	if err != nil {
+		log.Printf("Attendance alert: gagal mencatat alert mahasiswa %d: %v", studentID, err)
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return
+	}
+	alertID, _ := result.LastInsertId()
+
+	var studentName, nim, courseName string
+	var studentUserID, dosenUserID int
+	err = config.DB.QueryRow(`
+		SELECT m.name, m.nim, m.user_id, mk.nama, d.user_id
+		FROM mahasiswa m
+		JOIN mata_kuliah mk ON mk.kode = ?
+		JOIN dosen d ON mk.dosen_id = d.id
+		WHERE m.id = ?
+	`, courseID, studentID).Scan(&studentName, &nim, &studentUserID, &courseName, &dosenUserID)
+	if err != nil {
+		return
+	}
+
+	var studentMsg, ortuMsg, dosenMsg string
+	if level == "failing" {
+		studentMsg = fmt.Sprintf("Kehadiran Anda di %s tinggal %.0f%%, di bawah batas minimal %.0f%%. "+
+			"Anda terancam tidak dapat mengikuti UAS.", courseName, percent, minimum)
+		ortuMsg = fmt.Sprintf("Kehadiran %s di %s tinggal %.0f%%, di bawah batas minimal %.0f%% untuk mengikuti UAS.",
+			studentName, courseName, percent, minimum)
+		dosenMsg = fmt.Sprintf("%s (%s) di %s: kehadiran %.0f%%, di bawah batas minimal %.0f%%.",
+			studentName, nim, courseName, percent, minimum)
+	} else {
+		studentMsg = fmt.Sprintf("Peringatan: kehadiran Anda di %s %.0f%%. Batas minimal untuk mengikuti UAS adalah %.0f%%.",
+			courseName, percent, minimum)
+		ortuMsg = fmt.Sprintf("Peringatan: kehadiran %s di %s %.0f%%, mendekati batas minimal %.0f%%.",
+			studentName, courseName, percent, minimum)
+		dosenMsg = fmt.Sprintf("Peringatan: %s (%s) di %s: kehadiran %.0f%%, mendekati batas minimal %.0f%%.",
+			studentName, nim, courseName, percent, minimum)
+	}
+
+	createSystemNotification(studentUserID, int(alertID), studentMsg)
+	createSystemNotification(dosenUserID, int(alertID), dosenMsg)
+
+	ortuRows, err := config.DB.Query("SELECT user_id FROM ortu WHERE child_id = ?", studentID)
+	if err != nil {
+		return
+	}
+	var ortuUserIDs []int
+	for ortuRows.Next() {
+		var userID int
+		if ortuRows.Scan(&userID) == nil {
+			ortuUserIDs = append(ortuUserIDs, userID)
+		}
+	}
+	ortuRows.Close()
+
+	for _, userID := range ortuUserIDs {
+		createSystemNotification(userID, int(alertID), ortuMsg)
+	}
+}
+
+// createSystemNotification - Tambah notifikasi bertipe system untuk user
+func createSystemNotification(userID, sourceID int, message string) {
+	if userID == 0 {
+		return
+	}
+
+	_, err := config.DB.Exec(`
+		INSERT INTO notifications (user_id, type, source_id, message, is_read, created_at)
+		VALUES (?, 'system', ?, ?, 0, NOW())
+	`, userID, sourceID, message)
+	if err != nil {
+		log.Printf("Gagal membuat notifikasi untuk user %d: %v", userID, err)
+	}
+}
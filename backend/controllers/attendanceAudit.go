@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Sumber perubahan status kehadiran yang dicatat di attendance_audit
+const (
+	auditSourceScan        = "scan"
+	auditSourceManual      = "manual"
+	auditSourceExcuse      = "excuse"
+	auditSourceAutoClose   = "auto_close"
+	auditSourceOfflineSync = "offline_sync"
+)
+
+// attendanceChange - asal, pelaku dan alasan perubahan status kehadiran
+type attendanceChange struct {
+	Source    string
+	ChangedBy int // users.id, 0 untuk perubahan oleh sistem
+	Reason    string
+}
+
+// recordAttendanceAudit - Catat perubahan status ke attendance_audit.
+// oldStatus kosong berarti record baru; status yang tidak berubah tidak dicatat.
+func recordAttendanceAudit(attendanceID, sessionID, studentID int, oldStatus, newStatus string, change attendanceChange) {
+	if oldStatus == newStatus {
+		return
+	}
+
+	var old, changedBy, reason interface{}
+	if oldStatus != "" {
+		old = oldStatus
+	}
+	if change.ChangedBy > 0 {
+		changedBy = change.ChangedBy
+	}
+	if change.Reason != "" {
+		reason = change.Reason
+	}
+
+	_, err := config.DB.Exec(`
+		INSERT INTO attendance_audit
+		(attendance_id, session_id, student_id, old_status, new_status, source, changed_by, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW())
+	`, attendanceID, sessionID, studentID, old, newStatus, change.Source, changedBy, reason)
+	if err != nil {
+		log.Printf("Gagal mencatat audit absensi sesi %d mahasiswa %d: %v", sessionID, studentID, err)
+	}
+}
+
+// listAttendanceAudit - Ambil riwayat perubahan status kehadiran dengan filter where
+func listAttendanceAudit(where string, args ...interface{}) ([]gin.H, error) {
+	rows, err := config.DB.Query(`
+		SELECT au.id, au.session_id, asess.course_id, mk.nama, asess.pertemuan_ke,
+		       au.student_id, m.nim, m.name, COALESCE(au.old_status, ''), au.new_status,
+		       au.source, au.changed_by, COALESCE(d.name, mc.name, u.email, ''), COALESCE(u.role, ''),
+		       COALESCE(au.reason, ''), au.created_at
+		FROM attendance_audit au
+		JOIN attendance_sessions asess ON au.session_id = asess.id
+		JOIN mata_kuliah mk ON asess.course_id = mk.kode
+		JOIN mahasiswa m ON au.student_id = m.id
+		LEFT JOIN users u ON au.changed_by = u.id
+		LEFT JOIN dosen d ON d.user_id = u.id
+		LEFT JOIN mahasiswa mc ON mc.user_id = u.id
+		WHERE `+where+`
+		ORDER BY au.created_at DESC, au.id DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []gin.H{}
+	for rows.Next() {
+		var id, sessionID, pertemuanKe, studentID int
+		var courseID, courseName, nim, name, oldStatus, newStatus, source, changedByName, changedByRole, reason string
+		var changedBy sql.NullInt64
+		var createdAt time.Time
+
+		err := rows.Scan(&id, &sessionID, &courseID, &courseName, &pertemuanKe,
+			&studentID, &nim, &name, &oldStatus, &newStatus,
+			&source, &changedBy, &changedByName, &changedByRole, &reason, &createdAt)
+		if err != nil {
+			continue
+		}
+
+		if !changedBy.Valid {
+			changedByName, changedByRole = "Sistem", "system"
+		}
+
+		entry := gin.H{
+			"id":               id,
+			"session_id":       sessionID,
+			"course_id":        courseID,
+			"course_name":      courseName,
+			"pertemuan_ke":     pertemuanKe,
+			"student_id":       studentID,
+			"nim":              nim,
+			"student_name":     name,
+			"old_status":       oldStatus,
+			"new_status":       newStatus,
+			"new_status_label": getStatusLabel(newStatus),
+			"source":           source,
+			"changed_by":       changedByName,
+			"changed_by_role":  changedByRole,
+			"reason":           reason,
+			"created_at":       createdAt.Format("2006-01-02 15:04:05"),
+		}
+		if oldStatus != "" {
+			entry["old_status_label"] = getStatusLabel(oldStatus)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// auditFilters - Filter session_id, student_id dan course_id dari query string
+func auditFilters(c *gin.Context, where string, args []interface{}) (string, []interface{}, bool) {
+	if v := c.Query("session_id"); v != "" {
+		sessionID, err := strconv.Atoi(v)
+		if err != nil {
+			utils.ValidationError(c, "Invalid session ID")
+			return "", nil, false
+		}
+		where += " AND au.session_id = ?"
+		args = append(args, sessionID)
+	}
+	if v := c.Query("student_id"); v != "" {
+		studentID, err := strconv.Atoi(v)
+		if err != nil {
+			utils.ValidationError(c, "Invalid student ID")
+			return "", nil, false
+		}
+		where += " AND au.student_id = ?"
+		args = append(args, studentID)
+	}
+	if courseID := c.Query("course_id"); courseID != "" {
+		where += " AND asess.course_id = ?"
+		args = append(args, courseID)
+	}
+	return where, args, true
+}
+
+// GetDosenAttendanceAudit - Riwayat perubahan status untuk sesi atau mahasiswa pada mata kuliah yang diampu dosen
+func GetDosenAttendanceAudit(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if c.Query("session_id") == "" && c.Query("student_id") == "" {
+		utils.ValidationError(c, "session_id atau student_id diperlukan")
+		return
+	}
+
+	var dosenID int
+	if err := config.DB.QueryRow("SELECT id FROM dosen WHERE user_id = ?", userID).Scan(&dosenID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Dosen not found")
+		return
+	}
+
+	where, args, ok := auditFilters(c, "mk.dosen_id = ?", []interface{}{dosenID})
+	if !ok {
+		return
+	}
+
+	entries, err := listAttendanceAudit(where, args...)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil riwayat absensi: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"audit": entries,
+		"total": len(entries),
+	}, "Riwayat perubahan absensi berhasil diambil")
+}
+
+// GetAdminAttendanceAudit - Riwayat perubahan status absensi seluruh kampus (admin)
+func GetAdminAttendanceAudit(c *gin.Context) {
+	if c.Query("session_id") == "" && c.Query("student_id") == "" {
+		utils.ValidationError(c, "session_id atau student_id diperlukan")
+		return
+	}
+
+	where, args, ok := auditFilters(c, "1 = 1", nil)
+	if !ok {
+		return
+	}
+
+	entries, err := listAttendanceAudit(where, args...)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil riwayat absensi: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"audit": entries,
+		"total": len(entries),
+	}, "Riwayat perubahan absensi berhasil diambil")
+}
+
+// GetMyAttendanceAudit - Riwayat perubahan status kehadiran milik mahasiswa yang login
+func GetMyAttendanceAudit(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var mahasiswaID int
+	if err := config.DB.QueryRow("SELECT id FROM mahasiswa WHERE user_id = ?", userID).Scan(&mahasiswaID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Mahasiswa not found")
+		return
+	}
+
+	where := "au.student_id = ?"
+	args := []interface{}{mahasiswaID}
+	if courseID := c.Query("course_id"); courseID != "" {
+		where += " AND asess.course_id = ?"
+		args = append(args, courseID)
+	}
+
+	entries, err := listAttendanceAudit(where, args...)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil riwayat absensi: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"audit": entries,
+		"total": len(entries),
+	}, "Riwayat perubahan absensi berhasil diambil")
+}
@@ -0,0 +1,369 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportPertemuanCount - jumlah kolom pertemuan pada rekap absensi
+const exportPertemuanCount = 16
+
+// attendanceStatusCode - Kode singkat status untuk sel rekap
+var attendanceStatusCode = map[string]string{
+	"hadir":     "H",
+	"terlambat": "T",
+	"izin":      "I",
+	"sakit":     "S",
+	"alpa":      "A",
+}
+
+// attendanceMatrix - Rekap absensi satu mata kuliah: mahasiswa x pertemuan 1-16
+type attendanceMatrix struct {
+	CourseID   string
+	CourseName string
+	Semester   int
+	DosenName  string
+	Rows       [][]interface{}
+}
+
+// buildAttendanceMatrix - Susun rekap absensi mata kuliah lengkap dengan persentase.
+// Hanya sesi dan peserta pada semesterID (NULL = semua semester)
+func buildAttendanceMatrix(courseID string, semesterID sql.NullInt64) (attendanceMatrix, error) {
+	m := attendanceMatrix{CourseID: courseID}
+
+	err := config.DB.QueryRow(`
+		SELECT mk.nama, mk.semester, d.name
+		FROM mata_kuliah mk
+		JOIN dosen d ON mk.dosen_id = d.id
+		WHERE mk.kode = ?
+	`, courseID).Scan(&m.CourseName, &m.Semester, &m.DosenName)
+	if err != nil {
+		return m, err
+	}
+
+	// Pertemuan yang sudah pernah dibuka sesinya
+	held := map[int]bool{}
+	heldRows, err := config.DB.Query(`
+		SELECT DISTINCT pertemuan_ke FROM attendance_sessions
+		WHERE course_id = ? AND `+semesterFilter("semester_id")+`
+	`, append([]interface{}{courseID}, semesterArgs(semesterID)...)...)
+	if err != nil {
+		return m, err
+	}
+	for heldRows.Next() {
+		var p int
+		if heldRows.Scan(&p) == nil {
+			held[p] = true
+		}
+	}
+	heldRows.Close()
+
+	// Status per mahasiswa per pertemuan (sesi terbaru menang jika ada lebih dari satu)
+	statuses := map[int]map[int]string{}
+	statusRows, err := config.DB.Query(`
+		SELECT a.student_id, asess.pertemuan_ke, a.status
+		FROM attendance a
+		JOIN attendance_sessions asess ON a.session_id = asess.id
+		WHERE asess.course_id = ? AND `+semesterFilter("asess.semester_id")+`
+		ORDER BY asess.created_at, a.created_at
+	`, append([]interface{}{courseID}, semesterArgs(semesterID)...)...)
+	if err != nil {
+		return m, err
+	}
+	for statusRows.Next() {
+		var studentID, pertemuanKe int
+		var status string
+		if statusRows.Scan(&studentID, &pertemuanKe, &status) != nil {
+			continue
+		}
+		if statuses[studentID] == nil {
+			statuses[studentID] = map[int]string{}
+		}
+		statuses[studentID][pertemuanKe] = status
+	}
+	statusRows.Close()
+
+	studentRows, err := config.DB.Query(`
+		SELECT DISTINCT m.id, m.nim, m.name
+		FROM mahasiswa_mata_kuliah mmk
+		JOIN mahasiswa m ON mmk.mahasiswa_id = m.id
+		WHERE mmk.mata_kuliah_kode = ? AND `+semesterFilter("mmk.semester_id")+`
+		ORDER BY m.nim
+	`, append([]interface{}{courseID}, semesterArgs(semesterID)...)...)
+	if err != nil {
+		return m, err
+	}
+	defer studentRows.Close()
+
+	header := []interface{}{"No", "NIM", "Nama"}
+	for p := 1; p <= exportPertemuanCount; p++ {
+		header = append(header, "P"+strconv.Itoa(p))
+	}
+	header = append(header, "H", "T", "I", "S", "A", "% Kehadiran")
+
+	m.Rows = [][]interface{}{
+		{"Mata Kuliah", fmt.Sprintf("%s - %s", courseID, m.CourseName)},
+		{"Dosen", m.DosenName},
+		{"Semester", m.Semester},
+		{},
+		header,
+	}
+
+	presentPerPertemuan := make([]int, exportPertemuanCount+1)
+	heldCount := len(held)
+	studentCount := 0
+
+	for studentRows.Next() {
+		var studentID int
+		var nim, name string
+		if studentRows.Scan(&studentID, &nim, &name) != nil {
+			continue
+		}
+		studentCount++
+
+		row := []interface{}{studentCount, nim, name}
+		counts := map[string]int{}
+		for p := 1; p <= exportPertemuanCount; p++ {
+			status := statuses[studentID][p]
+			row = append(row, attendanceStatusCode[status])
+			if status != "" {
+				counts[status]++
+			}
+			if status == "hadir" || status == "terlambat" {
+				presentPerPertemuan[p]++
+			}
+		}
+
+		var percent float64
+		if heldCount > 0 {
+			percent = float64(counts["hadir"]+counts["terlambat"]) / float64(heldCount) * 100
+		}
+
+		row = append(row, counts["hadir"], counts["terlambat"], counts["izin"], counts["sakit"], counts["alpa"], percent)
+		m.Rows = append(m.Rows, row)
+	}
+
+	// Baris total: persentase kehadiran per pertemuan
+	footer := []interface{}{"", "", "% Kehadiran"}
+	for p := 1; p <= exportPertemuanCount; p++ {
+		if !held[p] || studentCount == 0 {
+			footer = append(footer, "")
+			continue
+		}
+		footer = append(footer, float64(presentPerPertemuan[p])/float64(studentCount)*100)
+	}
+	m.Rows = append(m.Rows, footer,
+		[]interface{}{},
+		[]interface{}{"Keterangan", "H = Hadir, T = Terlambat, I = Izin, S = Sakit, A = Alpa"},
+	)
+
+	return m, nil
+}
+
+// writeAttendanceExport - Kirim rekap dalam format csv atau xlsx
+func writeAttendanceExport(c *gin.Context, filename string, matrices []attendanceMatrix) {
+	format := c.DefaultQuery("format", "xlsx")
+	var buf bytes.Buffer
+
+	switch format {
+	case "csv":
+		w := csv.NewWriter(&buf)
+		for i, m := range matrices {
+			if i > 0 {
+				w.Write([]string{})
+			}
+			for _, row := range m.Rows {
+				record := make([]string, len(row))
+				for j, cell := range row {
+					if f, ok := cell.(float64); ok {
+						record[j] = strconv.FormatFloat(f, 'f', 2, 64)
+					} else {
+						record[j] = fmt.Sprint(cell)
+					}
+				}
+				w.Write(record)
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat file export: "+err.Error())
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, filename))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+
+	case "xlsx":
+		sheets := make([]utils.XLSXSheet, 0, len(matrices))
+		for _, m := range matrices {
+			sheets = append(sheets, utils.XLSXSheet{Name: m.CourseID, Rows: m.Rows})
+		}
+		if err := utils.WriteXLSX(&buf, sheets); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat file export: "+err.Error())
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.xlsx"`, filename))
+		c.Data(http.StatusOK, utils.XLSXContentType, buf.Bytes())
+
+	default:
+		utils.ValidationError(c, "format harus csv atau xlsx")
+	}
+}
+
+// exportCourses - Bangun rekap untuk daftar mata kuliah lalu kirim sebagai file
+func exportCourses(c *gin.Context, filename string, courseIDs []string, semesterID sql.NullInt64) {
+	if len(courseIDs) == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Tidak ada mata kuliah untuk diexport")
+		return
+	}
+
+	matrices := make([]attendanceMatrix, 0, len(courseIDs))
+	for _, courseID := range courseIDs {
+		m, err := buildAttendanceMatrix(courseID, semesterID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyusun rekap "+courseID+": "+err.Error())
+			return
+		}
+		matrices = append(matrices, m)
+	}
+
+	writeAttendanceExport(c, filename, matrices)
+}
+
+// exportSemesterID - Semester akademik rekap dari ?semester_id=, default semester aktif
+func exportSemesterID(c *gin.Context) (sql.NullInt64, bool) {
+	v := c.Query("semester_id")
+	if v == "" {
+		return currentSemesterID(), true
+	}
+	s, err := loadSemester("id = ?", v)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Semester tidak ditemukan")
+		return sql.NullInt64{}, false
+	}
+	return sql.NullInt64{Int64: int64(s.ID), Valid: true}, true
+}
+
+// queryCourseIDs - Ambil kode mata kuliah sesuai filter
+func queryCourseIDs(where string, args ...interface{}) ([]string, error) {
+	rows, err := config.DB.Query(`
+		SELECT kode FROM mata_kuliah
+		WHERE deleted_at IS NULL AND `+where+`
+		ORDER BY semester, kode
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var courseIDs []string
+	for rows.Next() {
+		var kode string
+		if rows.Scan(&kode) == nil {
+			courseIDs = append(courseIDs, kode)
+		}
+	}
+	return courseIDs, nil
+}
+
+// ExportDosenAttendance - Export rekap absensi satu mata kuliah atau semua mata kuliah dosen
+// Query: course_id (opsional), semester (opsional, semester kurikulum mata kuliah),
+// semester_id (opsional, default semester aktif), format=csv|xlsx
+func ExportDosenAttendance(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var dosenID int
+	if err := config.DB.QueryRow("SELECT id FROM dosen WHERE user_id = ?", userID).Scan(&dosenID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Dosen not found")
+		return
+	}
+
+	where := "dosen_id = ?"
+	args := []interface{}{dosenID}
+	filename := "rekap-absensi"
+
+	if courseID := c.Query("course_id"); courseID != "" {
+		where += " AND kode = ?"
+		args = append(args, courseID)
+		filename += "-" + courseID
+	}
+
+	if semester := c.Query("semester"); semester != "" {
+		where += " AND semester = ?"
+		args = append(args, semester)
+		filename += "-smt" + semester
+	}
+
+	semesterID, ok := exportSemesterID(c)
+	if !ok {
+		return
+	}
+	if semesterID.Valid {
+		filename += "-sa" + strconv.FormatInt(semesterID.Int64, 10)
+	}
+
+	courseIDs, err := queryCourseIDs(where, args...)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil mata kuliah: "+err.Error())
+		return
+	}
+
+	if len(courseIDs) == 0 && c.Query("course_id") != "" {
+		utils.ErrorResponse(c, http.StatusForbidden, "Anda tidak mengampu mata kuliah ini")
+		return
+	}
+
+	exportCourses(c, filename+"-"+time.Now().Format("20060102"), courseIDs, semesterID)
+}
+
+// ExportCampusAttendance - Export rekap absensi seluruh kampus untuk laporan akreditasi (admin)
+// Query: semester (opsional, semester kurikulum mata kuliah), semester_id (opsional, default semester aktif),
+// course_id (opsional), format=csv|xlsx
+func ExportCampusAttendance(c *gin.Context) {
+	where := "1 = 1"
+	args := []interface{}{}
+	filename := "rekap-absensi-kampus"
+
+	if courseID := c.Query("course_id"); courseID != "" {
+		where += " AND kode = ?"
+		args = append(args, courseID)
+		filename += "-" + courseID
+	}
+
+	if semester := c.Query("semester"); semester != "" {
+		where += " AND semester = ?"
+		args = append(args, semester)
+		filename += "-smt" + semester
+	}
+
+	semesterID, ok := exportSemesterID(c)
+	if !ok {
+		return
+	}
+	if semesterID.Valid {
+		filename += "-sa" + strconv.FormatInt(semesterID.Int64, 10)
+	}
+
+	courseIDs, err := queryCourseIDs(where, args...)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil mata kuliah: "+err.Error())
+		return
+	}
+
+	exportCourses(c, filename+"-"+time.Now().Format("20060102"), courseIDs, semesterID)
+}
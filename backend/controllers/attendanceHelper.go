@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"net/http"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// saveAttendanceStatus - Insert atau update status kehadiran mahasiswa pada sebuah sesi,
+// lalu sinkronkan ke attendance_summary dan catat perubahan ke audit trail. Dipakai oleh
+// update manual dosen dan fitur lain yang perlu menulis status kehadiran.
+func saveAttendanceStatus(sessionID, studentID int, courseID string, pertemuanKe int, status string, change attendanceChange) (int64, error) {
+	var attendanceID int
+	var oldStatus string
+	err := config.DB.QueryRow(`
+		SELECT a.id, a.status
+		FROM attendance a
+		WHERE a.student_id = ?
+			AND a.session_id = ?
+		LIMIT 1
+	`, studentID, sessionID).Scan(&attendanceID, &oldStatus)
+
+	var rowsAffected int64
+	if err == nil && attendanceID > 0 {
+		// Update existing attendance
+		result, err := config.DB.Exec(`
+			UPDATE attendance
+			SET status = ?, pertemuan_ke = ?, updated_at = NOW()
+			WHERE id = ?
+		`, status, pertemuanKe, attendanceID)
+		if err != nil {
+			return 0, err
+		}
+		rowsAffected, _ = result.RowsAffected()
+	} else {
+		// Insert new attendance
+		var studentCode string
+		config.DB.QueryRow("SELECT nim FROM mahasiswa WHERE id = ?", studentID).Scan(&studentCode)
+
+		result, err := config.DB.Exec(`
+			INSERT INTO attendance (student_id, session_id, student_code, status, pertemuan_ke, created_at)
+			VALUES (?, ?, ?, ?, ?, NOW())
+		`, studentID, sessionID, studentCode, status, pertemuanKe)
+		if err != nil {
+			return 0, err
+		}
+		rowsAffected, _ = result.RowsAffected()
+		insertedID, _ := result.LastInsertId()
+		attendanceID = int(insertedID)
+	}
+
+	recordAttendanceAudit(attendanceID, sessionID, studentID, oldStatus, status, change)
+
+	syncAttendanceSummary(sessionID, studentID, courseID, status)
+
+	return rowsAffected, nil
+}
+
+// countedAttendance - Filter SQL absensi yang boleh dihitung ke rekap, persentase, alert dan nilai:
+// absensi offline yang masih menunggu tinjauan dosen belum dihitung. alias adalah alias tabel attendance.
+func countedAttendance(alias string) string {
+	return "COALESCE(" + alias + ".offline_review, '') <> 'pending'"
+}
+
+// syncAttendanceSummary - Update attendance_summary untuk satu mahasiswa pada sesi
+func syncAttendanceSummary(sessionID, studentID int, courseID, status string) {
+	var exists bool
+	config.DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM attendance_summary WHERE student_id = ? AND session_id = ?)
+	`, studentID, sessionID).Scan(&exists)
+
+	if exists {
+		config.DB.Exec(`
+			UPDATE attendance_summary
+			SET status = ?, attendance_time = NOW()
+			WHERE student_id = ? AND session_id = ?
+		`, status, studentID, sessionID)
+		return
+	}
+
+	config.DB.Exec(`
+		INSERT INTO attendance_summary
+		(student_id, nim, student_name, session_id, course_id, course_name, status,
+		 attendance_date, attendance_time, dosen_name, hari, jam_mulai, jam_selesai)
+		SELECT
+			m.id, m.nim, m.name, ?, mk.kode, mk.nama, ?,
+			CURDATE(), NOW(), d.name, mk.hari, mk.jam_mulai, mk.jam_selesai
+		FROM mahasiswa m
+		JOIN mata_kuliah mk ON mk.kode = ?
+		JOIN dosen d ON mk.dosen_id = d.id
+		WHERE m.id = ?
+		ON DUPLICATE KEY UPDATE
+			status = ?,
+			attendance_time = NOW()
+	`, sessionID, status, courseID, studentID, status)
+}
+
+// verifyDosenSession - Pastikan sesi absensi milik dosen yang sedang login.
+// Menulis response error dan mengembalikan false jika tidak.
+func verifyDosenSession(c *gin.Context, sessionID int) bool {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return false
+	}
+
+	var dosenID int
+	err := config.DB.QueryRow("SELECT dosen_id FROM attendance_sessions WHERE id = ?", sessionID).Scan(&dosenID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Sesi tidak ditemukan")
+		return false
+	}
+
+	var actualDosenID int
+	err = config.DB.QueryRow("SELECT id FROM dosen WHERE user_id = ?", userID).Scan(&actualDosenID)
+	if err != nil || dosenID != actualDosenID {
+		utils.ErrorResponse(c, http.StatusForbidden, "Anda tidak memiliki akses ke sesi ini")
+		return false
+	}
+
+	return true
+}
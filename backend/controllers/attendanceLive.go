@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/handlers"
+	"nf-student-hub-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attendanceHub - hub WebSocket untuk push absensi realtime (diset dari SetupRoutes)
+var attendanceHub *handlers.WebSocketHub
+
+// SetAttendanceHub - Pasang hub WebSocket yang dipakai untuk event absensi
+func SetAttendanceHub(hub *handlers.WebSocketHub) {
+	attendanceHub = hub
+}
+
+// publishAttendanceEvent - Kirim perubahan status kehadiran ke dosen pemilik sesi
+// (tampilan proyektor) dan konfirmasi ke semua perangkat mahasiswa yang bersangkutan.
+func publishAttendanceEvent(sessionID, studentID int, status, source string) {
+	if attendanceHub == nil {
+		return
+	}
+
+	var courseID, courseName string
+	var pertemuanKe, dosenUserID int
+	err := config.DB.QueryRow(`
+		SELECT asess.course_id, mk.nama, asess.pertemuan_ke, d.user_id
+		FROM attendance_sessions asess
+		JOIN mata_kuliah mk ON asess.course_id = mk.kode
+		JOIN dosen d ON asess.dosen_id = d.id
+		WHERE asess.id = ?
+	`, sessionID).Scan(&courseID, &courseName, &pertemuanKe, &dosenUserID)
+	if err != nil {
+		return
+	}
+
+	var nim, name string
+	var studentUserID int
+	config.DB.QueryRow("SELECT nim, name, user_id FROM mahasiswa WHERE id = ?", studentID).
+		Scan(&nim, &name, &studentUserID)
+
+	var recorded, hadir, terlambat, izin, sakit, alpa int
+	config.DB.QueryRow(`
+		SELECT COUNT(*),
+			COALESCE(SUM(CASE WHEN status = 'hadir' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'terlambat' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'izin' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'sakit' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'alpa' THEN 1 ELSE 0 END), 0)
+		FROM attendance
+		WHERE session_id = ?
+	`, sessionID).Scan(&recorded, &hadir, &terlambat, &izin, &sakit, &alpa)
+
+	var studentCount int
+	config.DB.QueryRow(`
+		SELECT COUNT(DISTINCT mahasiswa_id)
+		FROM mahasiswa_mata_kuliah
+		WHERE mata_kuliah_kode = ?
+	`, courseID).Scan(&studentCount)
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+
+	attendanceHub.BroadcastToUser(dosenUserID, models.WebsocketMessage{
+		Type: "attendance_update",
+		Data: gin.H{
+			"session_id":   sessionID,
+			"course_id":    courseID,
+			"course_name":  courseName,
+			"pertemuan_ke": pertemuanKe,
+			"source":       source,
+			"student": gin.H{
+				"id":   studentID,
+				"nim":  nim,
+				"name": name,
+			},
+			"status":        status,
+			"status_label":  getStatusLabel(status),
+			"status_color":  getStatusColor(status),
+			"student_count": studentCount,
+			"summary": gin.H{
+				"recorded":  recorded,
+				"hadir":     hadir,
+				"terlambat": terlambat,
+				"izin":      izin,
+				"sakit":     sakit,
+				"alpa":      alpa,
+				"belum":     studentCount - recorded,
+			},
+			"time": now,
+		},
+	})
+
+	if studentUserID > 0 {
+		attendanceHub.BroadcastToUser(studentUserID, models.WebsocketMessage{
+			Type: "attendance_confirmed",
+			Data: gin.H{
+				"session_id":   sessionID,
+				"course_id":    courseID,
+				"course_name":  courseName,
+				"pertemuan_ke": pertemuanKe,
+				"source":       source,
+				"status":       status,
+				"status_label": getStatusLabel(status),
+				"time":         now,
+			},
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attendancePolicy - Aturan jendela scan absensi untuk satu mata kuliah
+type attendancePolicy struct {
+	CourseID         string
+	EarlyMinutes     int
+	LateAfterMinutes int
+	CutoffMinutes    int
+	LateStatus       string
+	EnforceWeekday   bool
+	Source           string
+}
+
+// builtinAttendancePolicy dipakai jika tabel attendance_policies belum berisi default kampus
+var builtinAttendancePolicy = attendancePolicy{
+	EarlyMinutes:     15,
+	LateAfterMinutes: 15,
+	CutoffMinutes:    60,
+	LateStatus:       "terlambat",
+	EnforceWeekday:   true,
+	Source:           "builtin",
+}
+
+// hariToWeekday - Nama hari di mata_kuliah.hari ke time.Weekday
+var hariToWeekday = map[string]time.Weekday{
+	"Senin":  time.Monday,
+	"Selasa": time.Tuesday,
+	"Rabu":   time.Wednesday,
+	"Kamis":  time.Thursday,
+	"Jumat":  time.Friday,
+	"Sabtu":  time.Saturday,
+	"Minggu": time.Sunday,
+}
+
+// queryAttendancePolicy - Ambil satu baris kebijakan dari attendance_policies
+func queryAttendancePolicy(where string, args ...interface{}) (attendancePolicy, error) {
+	var p attendancePolicy
+	var courseID sql.NullString
+
+	err := config.DB.QueryRow(`
+		SELECT course_id, early_minutes, late_after_minutes, cutoff_minutes, late_status, enforce_weekday
+		FROM attendance_policies
+		WHERE `+where+`
+		ORDER BY id
+		LIMIT 1
+	`, args...).Scan(&courseID, &p.EarlyMinutes, &p.LateAfterMinutes, &p.CutoffMinutes,
+		&p.LateStatus, &p.EnforceWeekday)
+	if err != nil {
+		return p, err
+	}
+
+	p.CourseID = courseID.String
+	return p, nil
+}
+
+// loadDefaultAttendancePolicy - Kebijakan default kampus
+func loadDefaultAttendancePolicy() attendancePolicy {
+	p, err := queryAttendancePolicy("course_id IS NULL")
+	if err != nil {
+		return builtinAttendancePolicy
+	}
+	p.Source = "default"
+	return p
+}
+
+// loadAttendancePolicy - Kebijakan mata kuliah, atau default kampus jika belum diatur
+func loadAttendancePolicy(courseID string) attendancePolicy {
+	p, err := queryAttendancePolicy("course_id = ?", courseID)
+	if err != nil {
+		p = loadDefaultAttendancePolicy()
+		p.CourseID = courseID
+		return p
+	}
+	p.Source = "course"
+	return p
+}
+
+// parseClock - Parse jam dari kolom TIME ("15:04:05") maupun "15:04"
+func parseClock(value string) (time.Time, bool) {
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// clockOn - Gabungkan tanggal dari day dengan jam dari clock
+func clockOn(day time.Time, clock string) time.Time {
+	t, _ := parseClock(clock)
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location())
+}
+
+// scanWindow - Batas waktu scan pada hari tertentu berdasarkan kebijakan
+func (p attendancePolicy) scanWindow(day time.Time, jamMulai, jamSelesai string) (opens, lateFrom, closes time.Time) {
+	start := clockOn(day, jamMulai)
+	end := clockOn(day, jamSelesai)
+
+	opens = start.Add(-time.Duration(p.EarlyMinutes) * time.Minute)
+	lateFrom = start.Add(time.Duration(p.LateAfterMinutes) * time.Minute)
+	closes = end.Add(time.Duration(p.CutoffMinutes) * time.Minute)
+	return opens, lateFrom, closes
+}
+
+// evaluateScan - Tentukan status scan pada waktu now terhadap jadwal pertemuan.
+// Mengembalikan pesan error kosong jika scan diterima.
+func (p attendancePolicy) evaluateScan(now time.Time, sched courseSchedule) (string, string) {
+	if p.EnforceWeekday && !sched.fallsOn(now) {
+		if sched.isPengganti() {
+			return "", "Hari ini bukan jadwal pertemuan pengganti. Jadwal: " + sched.Hari + ", " + sched.Tanggal.Format("2006-01-02")
+		}
+		return "", "Hari ini bukan jadwal mata kuliah ini. Jadwal: " + sched.Hari
+	}
+
+	opens, lateFrom, closes := p.scanWindow(now, sched.JamMulai, sched.JamSelesai)
+
+	if now.Before(opens) {
+		return "", fmt.Sprintf("Belum waktunya absen. Bisa scan %d menit sebelum kelas dimulai.", p.EarlyMinutes)
+	}
+
+	if now.After(closes) {
+		return "", fmt.Sprintf("Waktu absen sudah berakhir. Maksimal %d menit setelah kelas selesai.", p.CutoffMinutes)
+	}
+
+	if now.After(lateFrom) {
+		return p.LateStatus, ""
+	}
+
+	return "hadir", ""
+}
+
+// canScanAt - Apakah waktu now berada di dalam jendela scan
+func (p attendancePolicy) canScanAt(now time.Time, jamMulai, jamSelesai string) bool {
+	opens, _, closes := p.scanWindow(now, jamMulai, jamSelesai)
+	return !now.Before(opens) && !now.After(closes)
+}
+
+// normalizeStatus - Status terlambat dicatat sebagai hadir jika kebijakan tidak memakai status terlambat
+func (p attendancePolicy) normalizeStatus(status string) string {
+	if status == "terlambat" && p.LateStatus != "terlambat" {
+		return "hadir"
+	}
+	return status
+}
+
+// toResponse - Representasi kebijakan untuk response API
+func (p attendancePolicy) toResponse() gin.H {
+	return gin.H{
+		"course_id":          p.CourseID,
+		"early_minutes":      p.EarlyMinutes,
+		"late_after_minutes": p.LateAfterMinutes,
+		"cutoff_minutes":     p.CutoffMinutes,
+		"late_status":        p.LateStatus,
+		"enforce_weekday":    p.EnforceWeekday,
+		"source":             p.Source,
+	}
+}
+
+// attendancePolicyInput - Body request untuk mengubah kebijakan (field kosong = tidak diubah)
+type attendancePolicyInput struct {
+	EarlyMinutes     *int   `json:"early_minutes" binding:"omitempty,min=0,max=240"`
+	LateAfterMinutes *int   `json:"late_after_minutes" binding:"omitempty,min=0,max=240"`
+	CutoffMinutes    *int   `json:"cutoff_minutes" binding:"omitempty,min=0,max=480"`
+	LateStatus       string `json:"late_status" binding:"omitempty,oneof=hadir terlambat"`
+	EnforceWeekday   *bool  `json:"enforce_weekday"`
+}
+
+// applyTo - Terapkan input ke kebijakan yang sedang berlaku
+func (in attendancePolicyInput) applyTo(p attendancePolicy) attendancePolicy {
+	if in.EarlyMinutes != nil {
+		p.EarlyMinutes = *in.EarlyMinutes
+	}
+	if in.LateAfterMinutes != nil {
+		p.LateAfterMinutes = *in.LateAfterMinutes
+	}
+	if in.CutoffMinutes != nil {
+		p.CutoffMinutes = *in.CutoffMinutes
+	}
+	if in.LateStatus != "" {
+		p.LateStatus = in.LateStatus
+	}
+	if in.EnforceWeekday != nil {
+		p.EnforceWeekday = *in.EnforceWeekday
+	}
+	return p
+}
+
+// getDosenCourseAccess - Pastikan dosen yang login mengampu mata kuliah.
+// Menulis response error dan mengembalikan false jika tidak.
+func getDosenCourseAccess(c *gin.Context, courseID string) (int, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return 0, false
+	}
+
+	var dosenID int
+	if err := config.DB.QueryRow("SELECT id FROM dosen WHERE user_id = ?", userID).Scan(&dosenID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Dosen not found")
+		return 0, false
+	}
+
+	var owns bool
+	config.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM mata_kuliah WHERE kode = ? AND dosen_id = ?)", courseID, dosenID).Scan(&owns)
+	if !owns {
+		utils.ErrorResponse(c, http.StatusForbidden, "Anda tidak mengampu mata kuliah ini")
+		return 0, false
+	}
+
+	return dosenID, true
+}
+
+// GetCourseAttendancePolicy - Kebijakan absensi yang berlaku untuk mata kuliah
+func GetCourseAttendancePolicy(c *gin.Context) {
+	courseID := c.Param("course_id")
+	if _, ok := getDosenCourseAccess(c, courseID); !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, loadAttendancePolicy(courseID).toResponse(), "Kebijakan absensi berhasil diambil")
+}
+
+// UpdateCourseAttendancePolicy - Atur kebijakan absensi khusus mata kuliah
+func UpdateCourseAttendancePolicy(c *gin.Context) {
+	courseID := c.Param("course_id")
+
+	var input attendancePolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	if _, ok := getDosenCourseAccess(c, courseID); !ok {
+		return
+	}
+
+	p := input.applyTo(loadAttendancePolicy(courseID))
+
+	_, err := config.DB.Exec(`
+		INSERT INTO attendance_policies
+		(course_id, early_minutes, late_after_minutes, cutoff_minutes, late_status, enforce_weekday, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW(), NOW())
+		ON DUPLICATE KEY UPDATE
+			early_minutes = VALUES(early_minutes),
+			late_after_minutes = VALUES(late_after_minutes),
+			cutoff_minutes = VALUES(cutoff_minutes),
+			late_status = VALUES(late_status),
+			enforce_weekday = VALUES(enforce_weekday),
+			updated_at = NOW()
+	`, courseID, p.EarlyMinutes, p.LateAfterMinutes, p.CutoffMinutes, p.LateStatus, p.EnforceWeekday)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan kebijakan absensi: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, loadAttendancePolicy(courseID).toResponse(), "Kebijakan absensi berhasil disimpan")
+}
+
+// ResetCourseAttendancePolicy - Hapus kebijakan khusus sehingga mata kuliah kembali memakai default kampus
+func ResetCourseAttendancePolicy(c *gin.Context) {
+	courseID := c.Param("course_id")
+	if _, ok := getDosenCourseAccess(c, courseID); !ok {
+		return
+	}
+
+	if _, err := config.DB.Exec("DELETE FROM attendance_policies WHERE course_id = ?", courseID); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mereset kebijakan absensi: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, loadAttendancePolicy(courseID).toResponse(), "Kebijakan absensi kembali ke default kampus")
+}
+
+// GetDefaultAttendancePolicy - Kebijakan absensi default kampus (admin)
+func GetDefaultAttendancePolicy(c *gin.Context) {
+	utils.SuccessResponse(c, loadDefaultAttendancePolicy().toResponse(), "Kebijakan absensi default berhasil diambil")
+}
+
+// UpdateDefaultAttendancePolicy - Ubah kebijakan absensi default kampus (admin)
+func UpdateDefaultAttendancePolicy(c *gin.Context) {
+	var input attendancePolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	current := loadDefaultAttendancePolicy()
+	p := input.applyTo(current)
+
+	var err error
+	if current.Source == "default" {
+		_, err = config.DB.Exec(`
+			UPDATE attendance_policies
+			SET early_minutes = ?, late_after_minutes = ?, cutoff_minutes = ?,
+			    late_status = ?, enforce_weekday = ?, updated_at = NOW()
+			WHERE course_id IS NULL
+		`, p.EarlyMinutes, p.LateAfterMinutes, p.CutoffMinutes, p.LateStatus, p.EnforceWeekday)
+	} else {
+		_, err = config.DB.Exec(`
+			INSERT INTO attendance_policies
+			(course_id, early_minutes, late_after_minutes, cutoff_minutes, late_status, enforce_weekday, created_at, updated_at)
+			VALUES (NULL, ?, ?, ?, ?, ?, NOW(), NOW())
+		`, p.EarlyMinutes, p.LateAfterMinutes, p.CutoffMinutes, p.LateStatus, p.EnforceWeekday)
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan kebijakan absensi: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, loadDefaultAttendancePolicy().toResponse(), "Kebijakan absensi default berhasil disimpan")
+}
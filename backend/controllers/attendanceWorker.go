@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"nf-student-hub-backend/config"
+)
+
+// defaultAttendanceWorkerInterval - jeda antar pengecekan sesi kadaluarsa (detik)
+const defaultAttendanceWorkerInterval = 60
+
+// getAttendanceWorkerInterval membaca interval worker dari env ATTENDANCE_WORKER_INTERVAL (detik)
+func getAttendanceWorkerInterval() time.Duration {
+	if v := os.Getenv("ATTENDANCE_WORKER_INTERVAL"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds >= 10 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return defaultAttendanceWorkerInterval * time.Second
+}
+
+// StartAttendanceExpiryWorker - Jalankan worker yang menutup sesi absensi kadaluarsa di background
+func StartAttendanceExpiryWorker() {
+	interval := getAttendanceWorkerInterval()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		closeExpiredAttendanceSessions()
+		for range ticker.C {
+			closeExpiredAttendanceSessions()
+		}
+	}()
+
+	log.Printf("Attendance expiry worker: jalan setiap %s", interval)
+}
+
+// closeExpiredAttendanceSessions - Tutup semua sesi aktif yang sudah melewati expires_at,
+// lalu ulangi backfill alpa untuk sesi tertutup yang backfill-nya belum selesai
+func closeExpiredAttendanceSessions() {
+	for _, id := range queryAttendanceSessionIDs(`
+		SELECT id FROM attendance_sessions
+		WHERE status = 'active' AND expires_at <= NOW()
+	`) {
+		backfilled, err := closeAttendanceSession(id)
+		if err == errAttendanceSessionClosed {
+			continue
+		}
+		if err != nil {
+			log.Printf("Attendance expiry worker: gagal menutup sesi %d: %v", id, err)
+			continue
+		}
+		log.Printf("Attendance expiry worker: sesi %d ditutup, %d mahasiswa dicatat alpa", id, backfilled)
+	}
+
+	// Beri jeda agar tidak berebut dengan backfill yang masih berjalan dari tutup manual
+	for _, id := range queryAttendanceSessionIDs(`
+		SELECT id FROM attendance_sessions
+		WHERE status = 'closed' AND alpa_backfilled_at IS NULL
+			AND closed_at <= NOW() - INTERVAL 5 MINUTE
+	`) {
+		backfilled, err := backfillAttendanceSession(id)
+		if err != nil {
+			log.Printf("Attendance expiry worker: gagal mengulang backfill sesi %d: %v", id, err)
+			continue
+		}
+		log.Printf("Attendance expiry worker: backfill sesi %d diulang, %d mahasiswa dicatat alpa", id, backfilled)
+	}
+}
+
+// queryAttendanceSessionIDs - Ambil daftar id sesi dari query tanpa argumen
+func queryAttendanceSessionIDs(query string) []int {
+	rows, err := config.DB.Query(query)
+	if err != nil {
+		log.Printf("Attendance expiry worker: gagal mengambil sesi: %v", err)
+		return nil
+	}
+	defer rows.Close()
+
+	var sessionIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err == nil {
+			sessionIDs = append(sessionIDs, id)
+		}
+	}
+	return sessionIDs
+}
+
+// errAttendanceSessionClosed - sesi sudah ditutup oleh pemanggil lain
+var errAttendanceSessionClosed = errors.New("sesi absensi sudah ditutup")
+
+// closeAttendanceSession - Tutup sesi lalu catat alpa untuk mahasiswa terdaftar yang belum punya record
+// pada pertemuan ini (termasuk dari sesi lain jika pertemuan dibuka ulang).
+// Dipakai oleh worker dan CloseAttendanceSession.
+func closeAttendanceSession(sessionID int) (int, error) {
+	// Hanya satu pemanggil (worker atau tutup manual) yang berhasil menutup sesi dan menjalankan backfill
+	result, err := config.DB.Exec(`
+		UPDATE attendance_sessions
+		SET status = 'closed', closed_at = NOW()
+		WHERE id = ? AND status = 'active'
+	`, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return 0, errAttendanceSessionClosed
+	}
+
+	return backfillAttendanceSession(sessionID)
+}
+
+// backfillAttendanceSession - Catat alpa untuk sesi yang sudah ditutup. Aman diulang: mahasiswa yang
+// sudah punya record dilewati, dan alpa_backfilled_at baru diisi setelah semua berhasil sehingga
+// worker mengulang backfill yang gagal di tengah jalan.
+// Izin/sakit yang sudah disetujui diterapkan lebih dulu, lalu batas kehadiran dicek.
+func backfillAttendanceSession(sessionID int) (int, error) {
+	var courseID string
+	var pertemuanKe int
+	var semesterID sql.NullInt64
+	err := config.DB.QueryRow(`
+		SELECT course_id, pertemuan_ke, semester_id FROM attendance_sessions WHERE id = ?
+	`, sessionID).Scan(&courseID, &pertemuanKe, &semesterID)
+	if err != nil {
+		return 0, err
+	}
+
+	applyApprovedExcuses(sessionID, courseID, pertemuanKe)
+
+	// Hanya peserta dan record pada semester sesi ini
+	args := append([]interface{}{courseID}, semesterArgs(semesterID)...)
+	args = append(args, courseID, pertemuanKe)
+	args = append(args, semesterArgs(semesterID)...)
+	rows, err := config.DB.Query(`
+		SELECT DISTINCT mmk.mahasiswa_id
+		FROM mahasiswa_mata_kuliah mmk
+		WHERE mmk.mata_kuliah_kode = ? AND `+semesterFilter("mmk.semester_id")+`
+			AND NOT EXISTS (
+				SELECT 1 FROM attendance a
+				JOIN attendance_sessions asess ON a.session_id = asess.id
+				WHERE asess.course_id = ? AND asess.pertemuan_ke = ? AND a.student_id = mmk.mahasiswa_id
+					AND `+semesterFilter("asess.semester_id")+`
+			)
+	`, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	var missing []int
+	for rows.Next() {
+		var studentID int
+		if err := rows.Scan(&studentID); err == nil {
+			missing = append(missing, studentID)
+		}
+	}
+	rows.Close()
+
+	backfilled := 0
+	for _, studentID := range missing {
+		if _, err := saveAttendanceStatus(sessionID, studentID, courseID, pertemuanKe, "alpa", attendanceChange{
+			Source: auditSourceAutoClose,
+			Reason: "Tidak ada kehadiran saat sesi ditutup",
+		}); err != nil {
+			return backfilled, err
+		}
+		backfilled++
+	}
+
+	if _, err := config.DB.Exec(`
+		UPDATE attendance_sessions SET alpa_backfilled_at = NOW() WHERE id = ?
+	`, sessionID); err != nil {
+		return backfilled, err
+	}
+
+	// Rasio kehadiran berubah: cek batas peringatan/minimal kehadiran
+	checkAttendanceThresholds(courseID)
+
+	return backfilled, nil
+}
@@ -0,0 +1,363 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// wib - zona waktu jam kuliah yang tersimpan di database
+var wib = time.FixedZone("WIB", 7*60*60)
+
+// requestBaseURL - skema dan host dari request (menghormati X-Forwarded-Proto di belakang proxy)
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// calendarFeedResponse - URL feed .ics untuk token tertentu
+func calendarFeedResponse(c *gin.Context, token string) gin.H {
+	path := "/api/kalender/ics/" + token + ".ics"
+	base := requestBaseURL(c)
+	return gin.H{
+		"token":      token,
+		"feed_url":   base + path,
+		"webcal_url": "webcal://" + strings.TrimPrefix(strings.TrimPrefix(base, "https://"), "http://") + path,
+	}
+}
+
+// GetCalendarFeedURL - URL feed kalender pribadi (token dibuat saat pertama kali diminta)
+func GetCalendarFeedURL(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var token string
+	err := config.DB.QueryRow("SELECT token FROM calendar_tokens WHERE user_id = ?", userID).Scan(&token)
+	if err == sql.ErrNoRows {
+		token = utils.GenerateRandomString(40)
+		_, err = config.DB.Exec("INSERT INTO calendar_tokens (user_id, token) VALUES (?, ?)", userID, token)
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyiapkan feed kalender: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, calendarFeedResponse(c, token), "Feed kalender berhasil diambil")
+}
+
+// ResetCalendarFeedToken - Ganti token feed; URL lama langsung tidak berlaku
+func ResetCalendarFeedToken(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	if userID == 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	token := utils.GenerateRandomString(40)
+	_, err := config.DB.Exec(`
+		INSERT INTO calendar_tokens (user_id, token) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE token = VALUES(token), created_at = NOW()
+	`, userID, token)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengganti token feed: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, calendarFeedResponse(c, token), "Token feed kalender berhasil diganti")
+}
+
+// GetCalendarFeed - Endpoint publik .ics; akses dilindungi token di URL
+func GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	var userID int
+	var role string
+	err := config.DB.QueryRow(`
+		SELECT u.id, u.role FROM calendar_tokens ct JOIN users u ON ct.user_id = u.id WHERE ct.token = ?
+	`, token).Scan(&userID, &role)
+	if err != nil {
+		c.String(http.StatusNotFound, "Feed kalender tidak ditemukan")
+		return
+	}
+
+	var events []utils.ICalEvent
+	var name string
+	switch role {
+	case "mahasiswa":
+		name, events, err = mahasiswaCalendarEvents(userID)
+	case "dosen":
+		name, events, err = dosenCalendarEvents(userID)
+	default:
+		c.String(http.StatusForbidden, "Feed kalender hanya tersedia untuk mahasiswa dan dosen")
+		return
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Gagal menyusun feed kalender")
+		return
+	}
+
+	config.DB.Exec("UPDATE calendar_tokens SET last_accessed_at = NOW() WHERE token = ?", token)
+
+	c.Header("Content-Disposition", `inline; filename="jadwal.ics"`)
+	c.Header("Cache-Control", "private, max-age=900")
+	c.Data(http.StatusOK, utils.ICalContentType, utils.BuildICal(name, events))
+}
+
+// calendarCourse - mata kuliah yang masuk ke feed
+type calendarCourse struct {
+	Kode       string
+	Nama       string
+	Dosen      string
+	Hari       string
+	JamMulai   string
+	JamSelesai string
+}
+
+// courseCalendarEvents - Kuliah mingguan berulang sepanjang semester aktif (libur, pekan ujian dan
+// pertemuan yang dijadwal ulang dikecualikan) ditambah event tunggal untuk pertemuan pengganti.
+func courseCalendarEvents(course calendarCourse, semester *semesterAkademik) []utils.ICalEvent {
+	var events []utils.ICalEvent
+	room := getCourseRoom(course.Kode)
+
+	weekday, hasDay := hariToWeekday[normalizeHari(course.Hari)]
+	start, ok1 := parseClock(course.JamMulai)
+	end, ok2 := parseClock(course.JamSelesai)
+	if hasDay && ok1 && ok2 {
+		weekly := utils.ICalEvent{
+			UID:         fmt.Sprintf("kuliah-%s@nf-studenthub", course.Kode),
+			Summary:     fmt.Sprintf("%s (%s)", course.Nama, course.Kode),
+			Description: "Dosen: " + course.Dosen,
+			Location:    room,
+			Local:       true,
+		}
+
+		var first time.Time
+		if semester != nil {
+			first = calendarDate(semester.TanggalMulai)
+			weekly.UID = fmt.Sprintf("kuliah-%s-%d@nf-studenthub", course.Kode, semester.ID)
+		} else {
+			first = calendarDate(time.Now()).AddDate(0, 0, -6)
+		}
+		for first.Weekday() != weekday {
+			first = first.AddDate(0, 0, 1)
+		}
+		at := func(d time.Time, clock time.Time) time.Time {
+			return time.Date(d.Year(), d.Month(), d.Day(), clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+		}
+		weekly.Start, weekly.End = at(first, start), at(first, end)
+
+		if semester == nil {
+			weekly.RRule = "FREQ=WEEKLY"
+		} else {
+			last := calendarDate(semester.TanggalSelesai)
+			until := time.Date(last.Year(), last.Month(), last.Day(), 23, 59, 59, 0, wib).UTC()
+			weekly.RRule = "FREQ=WEEKLY;UNTIL=" + until.Format("20060102T150405Z")
+
+			regular := map[string]bool{}
+			if meetings, err := courseMeetings(*semester, course.Kode); err == nil {
+				for _, m := range meetings {
+					if !m.Pengganti {
+						regular[m.Tanggal.Format("2006-01-02")] = true
+					}
+				}
+			}
+			for d := first; !d.After(last); d = d.AddDate(0, 0, 7) {
+				if !regular[d.Format("2006-01-02")] {
+					weekly.ExDates = append(weekly.ExDates, at(d, start))
+				}
+			}
+		}
+		events = append(events, weekly)
+	}
+
+	// Hanya pertemuan pengganti semester yang sedang ditampilkan
+	semesterID := currentSemesterID()
+	if semester != nil {
+		semesterID = sql.NullInt64{Int64: int64(semester.ID), Valid: true}
+	}
+	rows, err := config.DB.Query(`
+		SELECT id, pertemuan_ke, tanggal, jam_mulai, jam_selesai, COALESCE(ruangan, ''), COALESCE(alasan, '')
+		FROM pertemuan_pengganti
+		WHERE course_id = ? AND `+semesterFilter("semester_id")+`
+	`, append([]interface{}{course.Kode}, semesterArgs(semesterID)...)...)
+	if err != nil {
+		return events
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, pertemuanKe int
+		var tanggal time.Time
+		var jamMulai, jamSelesai, ruangan, alasan string
+		if rows.Scan(&id, &pertemuanKe, &tanggal, &jamMulai, &jamSelesai, &ruangan, &alasan) != nil {
+			continue
+		}
+		if ruangan == "" {
+			ruangan = room
+		}
+		d := calendarDate(tanggal)
+		events = append(events, utils.ICalEvent{
+			UID:         fmt.Sprintf("pengganti-%d@nf-studenthub", id),
+			Summary:     fmt.Sprintf("%s (%s) - Pertemuan %d (pengganti)", course.Nama, course.Kode, pertemuanKe),
+			Description: strings.TrimSpace("Dosen: " + course.Dosen + "\n" + alasan),
+			Location:    ruangan,
+			Start:       clockOn(d, jamMulai),
+			End:         clockOn(d, jamSelesai),
+			Local:       true,
+		})
+	}
+	return events
+}
+
+// loadCalendarCourses - Mata kuliah dari query (kode, nama, dosen, hari, jam_mulai, jam_selesai)
+func loadCalendarCourses(query string, args ...interface{}) ([]calendarCourse, error) {
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var courses []calendarCourse
+	for rows.Next() {
+		var course calendarCourse
+		if rows.Scan(&course.Kode, &course.Nama, &course.Dosen, &course.Hari, &course.JamMulai, &course.JamSelesai) == nil {
+			courses = append(courses, course)
+		}
+	}
+	return courses, nil
+}
+
+func activeSemesterPtr() *semesterAkademik {
+	if s, err := activeSemester(); err == nil {
+		return &s
+	}
+	return nil
+}
+
+// mahasiswaCalendarEvents - Jadwal kuliah dan deadline tugas mahasiswa
+func mahasiswaCalendarEvents(userID int) (string, []utils.ICalEvent, error) {
+	var mahasiswaID int
+	var name string
+	if err := config.DB.QueryRow("SELECT id, name FROM mahasiswa WHERE user_id = ?", userID).Scan(&mahasiswaID, &name); err != nil {
+		return "", nil, err
+	}
+
+	courses, err := loadCalendarCourses(`
+		SELECT DISTINCT mk.kode, mk.nama, COALESCE(d.name, ''), COALESCE(mk.hari, ''),
+		       COALESCE(mk.jam_mulai, ''), COALESCE(mk.jam_selesai, '')
+		FROM mahasiswa_mata_kuliah mmk
+		JOIN mata_kuliah mk ON mmk.mata_kuliah_kode = mk.kode
+		LEFT JOIN dosen d ON mk.dosen_id = d.id
+		WHERE mmk.mahasiswa_id = ? AND mk.deleted_at IS NULL
+	`, mahasiswaID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	semester := activeSemesterPtr()
+	var events []utils.ICalEvent
+	for _, course := range courses {
+		events = append(events, courseCalendarEvents(course, semester)...)
+	}
+
+	// Deadline tugas 30 hari terakhir dan yang akan datang
+	rows, err := config.DB.Query(`
+		SELECT t.id, t.title, t.course_id, mk.nama, t.pertemuan, t.due_date
+		FROM tugas t
+		JOIN mata_kuliah mk ON t.course_id = mk.kode
+		JOIN mahasiswa_mata_kuliah mmk ON mmk.mata_kuliah_kode = t.course_id AND mmk.mahasiswa_id = ?
+		WHERE t.type = 'tugas' AND t.deleted_at IS NULL AND t.due_date IS NOT NULL
+		  AND t.due_date >= NOW() - INTERVAL 30 DAY
+	`, mahasiswaID)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, pertemuan int
+		var title, courseID, courseName string
+		var dueDate time.Time
+		if rows.Scan(&id, &title, &courseID, &courseName, &pertemuan, &dueDate) != nil {
+			continue
+		}
+		events = append(events, utils.ICalEvent{
+			UID:         fmt.Sprintf("tugas-%d@nf-studenthub", id),
+			Summary:     fmt.Sprintf("Deadline: %s (%s)", title, courseID),
+			Description: fmt.Sprintf("%s - pertemuan %d", courseName, pertemuan),
+			Start:       dueDate.Add(-30 * time.Minute),
+			End:         dueDate,
+			Local:       true,
+			Alarm:       24 * time.Hour,
+		})
+	}
+
+	return "Jadwal " + name, events, nil
+}
+
+// dosenCalendarEvents - Jadwal mengajar dan sesi absensi dosen
+func dosenCalendarEvents(userID int) (string, []utils.ICalEvent, error) {
+	var dosenID int
+	var name string
+	if err := config.DB.QueryRow("SELECT id, name FROM dosen WHERE user_id = ?", userID).Scan(&dosenID, &name); err != nil {
+		return "", nil, err
+	}
+
+	courses, err := loadCalendarCourses(`
+		SELECT mk.kode, mk.nama, COALESCE(d.name, ''), COALESCE(mk.hari, ''),
+		       COALESCE(mk.jam_mulai, ''), COALESCE(mk.jam_selesai, '')
+		FROM mata_kuliah mk
+		LEFT JOIN dosen d ON mk.dosen_id = d.id
+		WHERE mk.dosen_id = ? AND mk.deleted_at IS NULL
+	`, dosenID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	semester := activeSemesterPtr()
+	var events []utils.ICalEvent
+	for _, course := range courses {
+		events = append(events, courseCalendarEvents(course, semester)...)
+	}
+
+	// Sesi absensi 60 hari terakhir; created_at/expires_at adalah waktu absolut
+	rows, err := config.DB.Query(`
+		SELECT asess.id, asess.course_id, mk.nama, asess.pertemuan_ke, asess.session_code,
+		       asess.created_at, asess.expires_at, asess.status
+		FROM attendance_sessions asess
+		JOIN mata_kuliah mk ON asess.course_id = mk.kode
+		WHERE asess.dosen_id = ? AND asess.created_at >= NOW() - INTERVAL 60 DAY
+	`, dosenID)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, pertemuanKe int
+		var courseID, courseName, code, status string
+		var createdAt, expiresAt time.Time
+		if rows.Scan(&id, &courseID, &courseName, &pertemuanKe, &code, &createdAt, &expiresAt, &status) != nil {
+			continue
+		}
+		events = append(events, utils.ICalEvent{
+			UID:         fmt.Sprintf("sesi-absensi-%d@nf-studenthub", id),
+			Summary:     fmt.Sprintf("Sesi absensi %s P%d", courseID, pertemuanKe),
+			Description: fmt.Sprintf("%s\nKode sesi: %s\nStatus: %s", courseName, code, status),
+			Start:       createdAt,
+			End:         expiresAt,
+		})
+	}
+
+	return "Jadwal Mengajar " + name, events, nil
+}
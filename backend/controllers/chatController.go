@@ -753,7 +753,7 @@ func (cc *ChatController) CreateMatkulGroup(c *gin.Context) {
 	tx.Model(&models.MahasiswaMataKuliah{}).
 		Select("mahasiswa.*").
 		Joins("JOIN mahasiswa ON mahasiswa.id = mahasiswa_mata_kuliah.mahasiswa_id").
-		Where("mahasiswa_mata_kuliah.mata_kuliah_id = ?", mataKuliah.ID).
+		Where("mahasiswa_mata_kuliah.mata_kuliah_kode = ?", mataKuliah.Kode).
 		Find(&mahasiswaList)
 
 	// Add all mahasiswa as members
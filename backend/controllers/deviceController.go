@@ -0,0 +1,357 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMaxDevicesPerMahasiswa - jumlah perangkat yang boleh didaftarkan satu mahasiswa
+const defaultMaxDevicesPerMahasiswa = 2
+
+// getMaxDevicesPerMahasiswa membaca batas perangkat dari env MAX_DEVICES_PER_MAHASISWA
+func getMaxDevicesPerMahasiswa() int {
+	if v := os.Getenv("MAX_DEVICES_PER_MAHASISWA"); v != "" {
+		if max, err := strconv.Atoi(v); err == nil && max > 0 {
+			return max
+		}
+	}
+	return defaultMaxDevicesPerMahasiswa
+}
+
+// hashDeviceFingerprint - Normalisasi fingerprint dari client menjadi hash SHA-256
+func hashDeviceFingerprint(raw string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(raw)))
+	return hex.EncodeToString(sum[:])
+}
+
+// bindMahasiswaDevice - Daftarkan perangkat ke mahasiswa atau pastikan perangkat sudah terdaftar.
+// Mengembalikan pesan error kosong jika perangkat boleh dipakai untuk absensi.
+func bindMahasiswaDevice(mahasiswaID int, fingerprint, deviceName string) string {
+	var deviceID int
+	err := config.DB.QueryRow(`
+		SELECT id FROM mahasiswa_devices
+		WHERE mahasiswa_id = ? AND device_fingerprint = ?
+	`, mahasiswaID, fingerprint).Scan(&deviceID)
+
+	if err == nil {
+		config.DB.Exec("UPDATE mahasiswa_devices SET last_used_at = NOW() WHERE id = ?", deviceID)
+		return ""
+	}
+
+	var deviceCount int
+	config.DB.QueryRow("SELECT COUNT(*) FROM mahasiswa_devices WHERE mahasiswa_id = ?", mahasiswaID).Scan(&deviceCount)
+
+	maxDevices := getMaxDevicesPerMahasiswa()
+	if deviceCount >= maxDevices {
+		return fmt.Sprintf("Perangkat ini belum terdaftar. Anda sudah mendaftarkan %d perangkat (maksimal %d). "+
+			"Hubungi admin untuk reset perangkat", deviceCount, maxDevices)
+	}
+
+	_, err = config.DB.Exec(`
+		INSERT INTO mahasiswa_devices (mahasiswa_id, device_fingerprint, device_name, created_at, last_used_at)
+		VALUES (?, ?, ?, NOW(), NOW())
+	`, mahasiswaID, fingerprint, deviceName)
+	if err != nil {
+		return "Gagal mendaftarkan perangkat: " + err.Error()
+	}
+
+	return ""
+}
+
+// getSuspiciousDevices - Perangkat yang dipakai scan oleh lebih dari satu mahasiswa pada sesi yang sama
+func getSuspiciousDevices(sessionID int) ([]gin.H, error) {
+	rows, err := config.DB.Query(`
+		SELECT a.device_fingerprint, a.student_id, m.nim, m.name, a.status,
+		       COALESCE(TIME_FORMAT(a.created_at, '%H:%i:%s'), '')
+		FROM attendance a
+		JOIN mahasiswa m ON a.student_id = m.id
+		WHERE a.session_id = ?
+			AND a.device_fingerprint IN (
+				SELECT device_fingerprint
+				FROM attendance
+				WHERE session_id = ? AND device_fingerprint IS NOT NULL
+				GROUP BY device_fingerprint
+				HAVING COUNT(DISTINCT student_id) > 1
+			)
+		ORDER BY a.device_fingerprint, a.created_at
+	`, sessionID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []gin.H
+	index := map[string]int{}
+
+	for rows.Next() {
+		var fingerprint, nim, name, status, scanTime string
+		var studentID int
+
+		if err := rows.Scan(&fingerprint, &studentID, &nim, &name, &status, &scanTime); err != nil {
+			continue
+		}
+
+		i, ok := index[fingerprint]
+		if !ok {
+			i = len(devices)
+			index[fingerprint] = i
+			devices = append(devices, gin.H{
+				"device_fingerprint": fingerprint,
+				"students":           []gin.H{},
+			})
+		}
+
+		devices[i]["students"] = append(devices[i]["students"].([]gin.H), gin.H{
+			"student_id":   studentID,
+			"nim":          nim,
+			"name":         name,
+			"status":       status,
+			"status_label": getStatusLabel(status),
+			"scan_time":    scanTime,
+		})
+	}
+
+	for _, device := range devices {
+		device["student_count"] = len(device["students"].([]gin.H))
+	}
+
+	return devices, nil
+}
+
+// GetSuspiciousScans - Laporan scan mencurigakan (satu perangkat untuk beberapa mahasiswa) pada sesi
+func GetSuspiciousScans(c *gin.Context) {
+	sessionID, err := strconv.Atoi(c.Param("session_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid session ID")
+		return
+	}
+
+	if !verifyDosenSession(c, sessionID) {
+		return
+	}
+
+	devices, err := getSuspiciousDevices(sessionID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil laporan scan mencurigakan: "+err.Error())
+		return
+	}
+
+	flaggedStudents := 0
+	for _, device := range devices {
+		flaggedStudents += device["student_count"].(int)
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"session_id":       sessionID,
+		"devices":          devices,
+		"flagged_devices":  len(devices),
+		"flagged_students": flaggedStudents,
+		"is_suspicious":    len(devices) > 0,
+	}, "Laporan scan mencurigakan berhasil diambil")
+}
+
+// DowngradeSuspiciousScans - Ubah status scan mencurigakan menjadi alpa
+func DowngradeSuspiciousScans(c *gin.Context) {
+	sessionID, err := strconv.Atoi(c.Param("session_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid session ID")
+		return
+	}
+
+	var input struct {
+		StudentIDs []int `json:"student_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	if !verifyDosenSession(c, sessionID) {
+		return
+	}
+
+	var courseID string
+	var pertemuanKe int
+	err = config.DB.QueryRow(`
+		SELECT course_id, pertemuan_ke FROM attendance_sessions WHERE id = ?
+	`, sessionID).Scan(&courseID, &pertemuanKe)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Sesi tidak ditemukan")
+		return
+	}
+
+	devices, err := getSuspiciousDevices(sessionID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil laporan scan mencurigakan: "+err.Error())
+		return
+	}
+
+	// Hanya mahasiswa yang memang ter-flag yang boleh diturunkan statusnya
+	flagged := map[int]bool{}
+	for _, device := range devices {
+		for _, student := range device["students"].([]gin.H) {
+			flagged[student["student_id"].(int)] = true
+		}
+	}
+
+	targets := input.StudentIDs
+	if len(targets) == 0 {
+		for studentID := range flagged {
+			targets = append(targets, studentID)
+		}
+	}
+
+	var downgraded []int
+	for _, studentID := range targets {
+		if !flagged[studentID] {
+			continue
+		}
+		if _, err := saveAttendanceStatus(sessionID, studentID, courseID, pertemuanKe, "alpa", attendanceChange{
+			Source:    auditSourceManual,
+			ChangedBy: c.GetInt("user_id"),
+			Reason:    "Scan mencurigakan: satu perangkat dipakai beberapa mahasiswa",
+		}); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menurunkan status: "+err.Error())
+			return
+		}
+		downgraded = append(downgraded, studentID)
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"session_id":   sessionID,
+		"pertemuan_ke": pertemuanKe,
+		"downgraded":   downgraded,
+		"total":        len(downgraded),
+	}, fmt.Sprintf("%d scan mencurigakan diubah menjadi Alpa", len(downgraded)))
+}
+
+// GetMyDevices - Daftar perangkat terdaftar milik mahasiswa
+func GetMyDevices(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var mahasiswaID int
+	if err := config.DB.QueryRow("SELECT id FROM mahasiswa WHERE user_id = ?", userID).Scan(&mahasiswaID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Mahasiswa not found")
+		return
+	}
+
+	devices, err := listMahasiswaDevices(mahasiswaID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil perangkat: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"devices":     devices,
+		"total":       len(devices),
+		"max_devices": getMaxDevicesPerMahasiswa(),
+	}, "Perangkat terdaftar berhasil diambil")
+}
+
+// GetMahasiswaDevices - Daftar perangkat terdaftar seorang mahasiswa (admin)
+func GetMahasiswaDevices(c *gin.Context) {
+	mahasiswaID, err := strconv.Atoi(c.Param("mahasiswa_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid mahasiswa ID")
+		return
+	}
+
+	devices, err := listMahasiswaDevices(mahasiswaID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil perangkat: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"mahasiswa_id": mahasiswaID,
+		"devices":      devices,
+		"total":        len(devices),
+		"max_devices":  getMaxDevicesPerMahasiswa(),
+	}, "Perangkat terdaftar berhasil diambil")
+}
+
+// ResetMahasiswaDevice - Hapus perangkat terdaftar mahasiswa agar bisa mendaftarkan perangkat baru (admin)
+func ResetMahasiswaDevice(c *gin.Context) {
+	mahasiswaID, err := strconv.Atoi(c.Param("mahasiswa_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid mahasiswa ID")
+		return
+	}
+
+	deviceID, err := strconv.Atoi(c.Param("device_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid device ID")
+		return
+	}
+
+	result, err := config.DB.Exec(`
+		DELETE FROM mahasiswa_devices WHERE id = ? AND mahasiswa_id = ?
+	`, deviceID, mahasiswaID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus perangkat: "+err.Error())
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Perangkat tidak ditemukan")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Perangkat berhasil dihapus")
+}
+
+// listMahasiswaDevices - Ambil perangkat terdaftar milik mahasiswa
+func listMahasiswaDevices(mahasiswaID int) ([]gin.H, error) {
+	rows, err := config.DB.Query(`
+		SELECT id, device_fingerprint, COALESCE(device_name, ''), created_at, last_used_at
+		FROM mahasiswa_devices
+		WHERE mahasiswa_id = ?
+		ORDER BY created_at
+	`, mahasiswaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []gin.H
+	for rows.Next() {
+		var id int
+		var fingerprint, name string
+		var createdAt time.Time
+		var lastUsedAt sql.NullTime
+
+		if err := rows.Scan(&id, &fingerprint, &name, &createdAt, &lastUsedAt); err != nil {
+			continue
+		}
+
+		device := gin.H{
+			"id":                 id,
+			"device_fingerprint": fingerprint,
+			"device_name":        name,
+			"created_at":         createdAt.Format("2006-01-02 15:04:05"),
+		}
+		if lastUsedAt.Valid {
+			device["last_used_at"] = lastUsedAt.Time.Format("2006-01-02 15:04:05")
+		}
+		devices = append(devices, device)
+	}
+
+	return devices, nil
+}
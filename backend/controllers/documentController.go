@@ -0,0 +1,506 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultDocumentVerifyURL - alamat publik verifikasi dokumen yang dicetak di PDF
+const defaultDocumentVerifyURL = "http://localhost:8080/api/dokumen/verify/"
+
+// verificationAlphabet - tanpa 0/O dan 1/I/L agar kode mudah diketik ulang
+const verificationAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// getDocumentVerifyURL membaca alamat verifikasi dari env DOCUMENT_VERIFY_URL
+func getDocumentVerifyURL() string {
+	if v := os.Getenv("DOCUMENT_VERIFY_URL"); v != "" {
+		return v
+	}
+	return defaultDocumentVerifyURL
+}
+
+// documentStudent - identitas mahasiswa pemilik dokumen
+type documentStudent struct {
+	ID   int
+	NIM  string
+	Name string
+}
+
+// resolveDocumentStudent - Mahasiswa pemilik dokumen: diri sendiri untuk mahasiswa, anak untuk orangtua
+func resolveDocumentStudent(c *gin.Context) (documentStudent, bool) {
+	var s documentStudent
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return s, false
+	}
+
+	role, _ := c.Get("role")
+	if role == "orangtua" {
+		err := config.DB.QueryRow(`
+			SELECT m.id, m.nim, m.name
+			FROM ortu o
+			JOIN mahasiswa m ON o.child_id = m.id
+			WHERE o.user_id = ?
+		`, userID).Scan(&s.ID, &s.NIM, &s.Name)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusNotFound, "Data orangtua tidak ditemukan")
+			return s, false
+		}
+		return s, true
+	}
+
+	err := config.DB.QueryRow("SELECT id, nim, name FROM mahasiswa WHERE user_id = ?", userID).Scan(&s.ID, &s.NIM, &s.Name)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Mahasiswa not found")
+		return s, false
+	}
+	return s, true
+}
+
+// generateVerificationCode - Kode acak berformat NF-XXXX-XXXX-XXXX
+func generateVerificationCode() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("NF")
+	for i, v := range buf {
+		if i%4 == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteByte(verificationAlphabet[int(v)%len(verificationAlphabet)])
+	}
+	return b.String(), nil
+}
+
+// issuedDocument - dokumen yang sedang diterbitkan
+type issuedDocument struct {
+	Jenis      string // khs, transkrip, rekap_absensi, kwitansi
+	Referensi  string // semester, id pembayaran, dst.
+	Ringkasan  string // ditampilkan di halaman verifikasi
+	Filename   string
+	Title      string
+	Student    documentStudent
+	RenderBody func(pdf *utils.PDF)
+}
+
+// issueDocument - Buat kode verifikasi, render PDF, catat ke dokumen_terbit lalu kirim ke klien
+func issueDocument(c *gin.Context, doc issuedDocument) {
+	var code string
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		code, err = generateVerificationCode()
+		if err != nil {
+			break
+		}
+		var exists int
+		config.DB.QueryRow("SELECT COUNT(*) FROM dokumen_terbit WHERE kode_verifikasi = ?", code).Scan(&exists)
+		if exists == 0 {
+			break
+		}
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat kode verifikasi")
+		return
+	}
+
+	now := time.Now()
+	pdf := utils.NewPDF(doc.Title)
+	pdf.Footer = fmt.Sprintf("Kode verifikasi: %s - %s%s", code, getDocumentVerifyURL(), code)
+
+	pdf.Text(utils.PDFMargin, pdf.Y+12, 12, true, "STT Terpadu Nurul Fikri")
+	pdf.Text(utils.PDFMargin, pdf.Y+26, 9, false, "NF StudentHub - Dokumen Akademik Resmi")
+	pdf.Y += 34
+	pdf.Rule()
+	pdf.Space(6)
+	pdf.Heading(doc.Title, 14)
+	pdf.Space(4)
+	pdf.KeyValue("Nama", doc.Student.Name, 90)
+	pdf.KeyValue("NIM", doc.Student.NIM, 90)
+	pdf.KeyValue("Tanggal terbit", now.Format("2006-01-02 15:04:05"), 90)
+	pdf.KeyValue("Kode verifikasi", code, 90)
+	pdf.Space(12)
+
+	doc.RenderBody(pdf)
+
+	pdf.Space(10)
+	pdf.Paragraph("Dokumen ini diterbitkan secara elektronik oleh NF StudentHub dan tidak memerlukan tanda tangan basah. "+
+		"Keaslian dokumen dapat diperiksa melalui "+getDocumentVerifyURL()+code+".", 8)
+
+	content := pdf.Bytes()
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	var issuedBy interface{}
+	if userID := c.GetInt("user_id"); userID != 0 {
+		issuedBy = userID
+	}
+
+	_, err = config.DB.Exec(`
+		INSERT INTO dokumen_terbit (kode_verifikasi, jenis, student_id, referensi, ringkasan, checksum, issued_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, code, doc.Jenis, doc.Student.ID, doc.Referensi, doc.Ringkasan, checksum, issuedBy, now)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mencatat dokumen: "+err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_%s.pdf"`, doc.Filename, doc.Student.NIM))
+	c.Header("X-Verification-Code", code)
+	c.Data(http.StatusOK, "application/pdf", content)
+}
+
+// khsColumns - kolom tabel nilai pada KHS dan transkrip
+var khsColumns = []utils.PDFColumn{
+	{Title: "Kode", Width: 60},
+	{Title: "Mata Kuliah", Width: 215},
+	{Title: "SKS", Width: 40, Align: "center"},
+	{Title: "Nilai", Width: 55, Align: "right"},
+	{Title: "Huruf", Width: 45, Align: "center"},
+	{Title: "Mutu", Width: 80, Align: "right"},
+}
+
+func khsTableRows(courses []gin.H) [][]string {
+	rows := make([][]string, 0, len(courses))
+	for _, course := range courses {
+		rows = append(rows, []string{
+			fmt.Sprint(course["kode"]),
+			fmt.Sprint(course["nama"]),
+			fmt.Sprint(course["sks"]),
+			fmt.Sprintf("%.2f", course["nilai_akhir"]),
+			fmt.Sprint(course["huruf"]),
+			fmt.Sprintf("%.2f", course["mutu"]),
+		})
+	}
+	return rows
+}
+
+// GetKHSDocument - PDF KHS satu semester (?semester=, default semester terakhir yang bernilai final)
+func GetKHSDocument(c *gin.Context) {
+	student, ok := resolveDocumentStudent(c)
+	if !ok {
+		return
+	}
+
+	semesters, err := buildKHS(student.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil KHS: "+err.Error())
+		return
+	}
+	if len(semesters) == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Belum ada nilai final")
+		return
+	}
+
+	selected := semesters[len(semesters)-1]
+	if v := c.Query("semester"); v != "" {
+		semester, err := strconv.Atoi(v)
+		if err != nil {
+			utils.ValidationError(c, "Semester tidak valid")
+			return
+		}
+		found := false
+		for _, s := range semesters {
+			if s.Semester == semester {
+				selected, found = s, true
+				break
+			}
+		}
+		if !found {
+			utils.ErrorResponse(c, http.StatusNotFound, "Belum ada nilai final untuk semester "+v)
+			return
+		}
+	}
+
+	issueDocument(c, issuedDocument{
+		Jenis:     "khs",
+		Referensi: strconv.Itoa(selected.Semester),
+		Ringkasan: fmt.Sprintf("KHS semester %d: %d SKS, IPS %.2f, IPK %.2f", selected.Semester, selected.SKS, selected.IPS, selected.IPK),
+		Filename:  fmt.Sprintf("khs_semester_%d", selected.Semester),
+		Title:     fmt.Sprintf("KARTU HASIL STUDI - SEMESTER %d", selected.Semester),
+		Student:   student,
+		RenderBody: func(pdf *utils.PDF) {
+			pdf.Table(khsColumns, khsTableRows(selected.Courses))
+			pdf.KeyValue("Total SKS", strconv.Itoa(selected.SKS), 90)
+			pdf.KeyValue("SKS lulus", strconv.Itoa(selected.SKSLulus), 90)
+			pdf.KeyValue("IPS", fmt.Sprintf("%.2f", selected.IPS), 90)
+			pdf.KeyValue("IPK", fmt.Sprintf("%.2f", selected.IPK), 90)
+		},
+	})
+}
+
+// GetTranskripDocument - PDF transkrip seluruh mata kuliah bernilai final
+func GetTranskripDocument(c *gin.Context) {
+	student, ok := resolveDocumentStudent(c)
+	if !ok {
+		return
+	}
+
+	semesters, err := buildKHS(student.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil transkrip: "+err.Error())
+		return
+	}
+	if len(semesters) == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Belum ada nilai final")
+		return
+	}
+
+	totalSKS, sksLulus := 0, 0
+	var ipk float64
+	for _, s := range semesters {
+		totalSKS += s.SKS
+		sksLulus += s.SKSLulus
+		ipk = s.IPK
+	}
+
+	issueDocument(c, issuedDocument{
+		Jenis:     "transkrip",
+		Referensi: fmt.Sprintf("semester 1-%d", semesters[len(semesters)-1].Semester),
+		Ringkasan: fmt.Sprintf("Transkrip: %d SKS, %d SKS lulus, IPK %.2f", totalSKS, sksLulus, ipk),
+		Filename:  "transkrip",
+		Title:     "TRANSKRIP NILAI",
+		Student:   student,
+		RenderBody: func(pdf *utils.PDF) {
+			for _, s := range semesters {
+				pdf.Space(4)
+				pdf.Y += 12
+				pdf.Text(utils.PDFMargin, pdf.Y, 10, true, fmt.Sprintf("Semester %d", s.Semester))
+				pdf.Space(6)
+				pdf.Table(khsColumns, khsTableRows(s.Courses))
+				pdf.Text(utils.PDFMargin, pdf.Y+4, 9, false,
+					fmt.Sprintf("SKS: %d    IPS: %.2f    IPK: %.2f", s.SKS, s.IPS, s.IPK))
+				pdf.Space(10)
+			}
+			pdf.Rule()
+			pdf.KeyValue("Total SKS", strconv.Itoa(totalSKS), 90)
+			pdf.KeyValue("SKS lulus", strconv.Itoa(sksLulus), 90)
+			pdf.KeyValue("IPK", fmt.Sprintf("%.2f", ipk), 90)
+		},
+	})
+}
+
+// GetRekapAbsensiDocument - PDF rekap kehadiran semua mata kuliah yang diambil mahasiswa
+func GetRekapAbsensiDocument(c *gin.Context) {
+	student, ok := resolveDocumentStudent(c)
+	if !ok {
+		return
+	}
+
+	// Hanya pertemuan dari sesi yang sudah ditutup yang dihitung
+	rows, err := config.DB.Query(`
+		SELECT mk.kode, mk.nama,
+			(SELECT COUNT(DISTINCT s.pertemuan_ke) FROM attendance_sessions s
+			 WHERE s.course_id = mk.kode AND s.status = 'closed') AS held,
+			COUNT(DISTINCT CASE WHEN a.status = 'hadir' THEN asess.pertemuan_ke END),
+			COUNT(DISTINCT CASE WHEN a.status = 'terlambat' THEN asess.pertemuan_ke END),
+			COUNT(DISTINCT CASE WHEN a.status = 'izin' THEN asess.pertemuan_ke END),
+			COUNT(DISTINCT CASE WHEN a.status = 'sakit' THEN asess.pertemuan_ke END),
+			COUNT(DISTINCT CASE WHEN a.status = 'alpa' THEN asess.pertemuan_ke END)
+		FROM mahasiswa_mata_kuliah mmk
+		JOIN mata_kuliah mk ON mmk.mata_kuliah_kode = mk.kode
+		LEFT JOIN attendance_sessions asess ON asess.course_id = mk.kode AND asess.status = 'closed'
+		LEFT JOIN attendance a ON a.session_id = asess.id AND a.student_id = mmk.mahasiswa_id
+			AND `+countedAttendance("a")+`
+		WHERE mmk.mahasiswa_id = ?
+		GROUP BY mk.kode, mk.nama
+		ORDER BY mk.kode
+	`, student.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil rekap absensi: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	var tableRows [][]string
+	totalHeld, totalPresent := 0, 0
+	for rows.Next() {
+		var kode, nama string
+		var held, hadir, terlambat, izin, sakit, alpa int
+		if rows.Scan(&kode, &nama, &held, &hadir, &terlambat, &izin, &sakit, &alpa) != nil {
+			continue
+		}
+
+		percentage := "-"
+		if held > 0 {
+			percentage = fmt.Sprintf("%.1f%%", float64(hadir+terlambat)/float64(held)*100)
+		}
+		totalHeld += held
+		totalPresent += hadir + terlambat
+
+		tableRows = append(tableRows, []string{
+			kode, nama, strconv.Itoa(held), strconv.Itoa(hadir), strconv.Itoa(terlambat),
+			strconv.Itoa(izin), strconv.Itoa(sakit), strconv.Itoa(alpa), percentage,
+		})
+	}
+
+	if len(tableRows) == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Belum ada mata kuliah yang diambil")
+		return
+	}
+
+	overall := 0.0
+	if totalHeld > 0 {
+		overall = float64(totalPresent) / float64(totalHeld) * 100
+	}
+
+	issueDocument(c, issuedDocument{
+		Jenis:     "rekap_absensi",
+		Referensi: time.Now().Format("2006-01-02"),
+		Ringkasan: fmt.Sprintf("Rekap absensi %d mata kuliah, kehadiran %.1f%% (%d dari %d pertemuan)", len(tableRows), overall, totalPresent, totalHeld),
+		Filename:  "rekap_absensi",
+		Title:     "REKAP KEHADIRAN PERKULIAHAN",
+		Student:   student,
+		RenderBody: func(pdf *utils.PDF) {
+			pdf.Table([]utils.PDFColumn{
+				{Title: "Kode", Width: 55},
+				{Title: "Mata Kuliah", Width: 170},
+				{Title: "Ptm", Width: 35, Align: "center"},
+				{Title: "H", Width: 30, Align: "center"},
+				{Title: "T", Width: 30, Align: "center"},
+				{Title: "I", Width: 30, Align: "center"},
+				{Title: "S", Width: 30, Align: "center"},
+				{Title: "A", Width: 30, Align: "center"},
+				{Title: "Hadir", Width: 85, Align: "right"},
+			}, tableRows)
+			pdf.Paragraph("Ptm: pertemuan yang sudah ditutup. H: hadir, T: terlambat, I: izin, S: sakit, A: alpa. "+
+				"Persentase kehadiran menghitung hadir dan terlambat.", 8)
+			pdf.KeyValue("Kehadiran total", fmt.Sprintf("%.1f%% (%d dari %d pertemuan)", overall, totalPresent, totalHeld), 90)
+		},
+	})
+}
+
+// formatRupiah - 1500000 -> "Rp 1.500.000"
+func formatRupiah(amount float64) string {
+	digits := strconv.FormatInt(int64(amount+0.5), 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	return "Rp " + b.String()
+}
+
+// GetKwitansiDocument - PDF kwitansi untuk pembayaran UKT yang sudah berhasil
+func GetKwitansiDocument(c *gin.Context) {
+	student, ok := resolveDocumentStudent(c)
+	if !ok {
+		return
+	}
+
+	paymentID, err := strconv.Atoi(c.Param("payment_id"))
+	if err != nil {
+		utils.ValidationError(c, "ID pembayaran tidak valid")
+		return
+	}
+
+	var invoiceUUID, metode, status string
+	var paymentMethod, orderID sql.NullString
+	var nominal, biayaAdmin, totalDibayar float64
+	var tanggal time.Time
+	err = config.DB.QueryRow(`
+		SELECT invoice_uuid, metode, payment_method, nominal, biaya_admin, total_dibayar, pakasir_order_id, status, tanggal
+		FROM riwayat_pembayaran
+		WHERE id = ? AND mahasiswa_id = ?
+	`, paymentID, student.ID).Scan(&invoiceUUID, &metode, &paymentMethod, &nominal, &biayaAdmin, &totalDibayar, &orderID, &status, &tanggal)
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(c, http.StatusNotFound, "Pembayaran tidak ditemukan")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pembayaran: "+err.Error())
+		return
+	}
+	if status != "success" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Kwitansi hanya tersedia untuk pembayaran yang sudah berhasil")
+		return
+	}
+
+	var sisaUKT float64
+	config.DB.QueryRow("SELECT COALESCE(sisa_ukt, 0) FROM mahasiswa WHERE id = ?", student.ID).Scan(&sisaUKT)
+
+	method := strings.ToUpper(metode)
+	if paymentMethod.Valid && paymentMethod.String != "" {
+		method += " (" + paymentMethod.String + ")"
+	}
+
+	issueDocument(c, issuedDocument{
+		Jenis:     "kwitansi",
+		Referensi: strconv.Itoa(paymentID),
+		Ringkasan: fmt.Sprintf("Kwitansi UKT %s sebesar %s tanggal %s", invoiceUUID, formatRupiah(nominal), tanggal.Format("2006-01-02")),
+		Filename:  "kwitansi_" + strconv.Itoa(paymentID),
+		Title:     "KWITANSI PEMBAYARAN UKT",
+		Student:   student,
+		RenderBody: func(pdf *utils.PDF) {
+			pdf.KeyValue("No. invoice", invoiceUUID, 110)
+			if orderID.Valid && orderID.String != "" {
+				pdf.KeyValue("Order ID", orderID.String, 110)
+			}
+			pdf.KeyValue("Tanggal bayar", tanggal.Format("2006-01-02 15:04:05"), 110)
+			pdf.KeyValue("Metode", method, 110)
+			pdf.Space(10)
+			pdf.Table([]utils.PDFColumn{
+				{Title: "Uraian", Width: 335},
+				{Title: "Jumlah", Width: 160, Align: "right"},
+			}, [][]string{
+				{"Pembayaran Uang Kuliah Tunggal (UKT)", formatRupiah(nominal)},
+				{"Biaya admin", formatRupiah(biayaAdmin)},
+				{"Total dibayar", formatRupiah(totalDibayar)},
+			})
+			pdf.KeyValue("Sisa UKT saat ini", formatRupiah(sisaUKT), 110)
+			pdf.KeyValue("Status", "LUNAS", 110)
+		},
+	})
+}
+
+// VerifyDokumen - Endpoint publik: cek apakah dokumen dengan kode verifikasi benar diterbitkan
+func VerifyDokumen(c *gin.Context) {
+	code := strings.ToUpper(strings.TrimSpace(c.Param("kode")))
+
+	var jenis, referensi, ringkasan, checksum, nim, name string
+	var createdAt time.Time
+	err := config.DB.QueryRow(`
+		SELECT dt.jenis, COALESCE(dt.referensi, ''), COALESCE(dt.ringkasan, ''), dt.checksum, dt.created_at, m.nim, m.name
+		FROM dokumen_terbit dt
+		JOIN mahasiswa m ON dt.student_id = m.id
+		WHERE dt.kode_verifikasi = ?
+	`, code).Scan(&jenis, &referensi, &ringkasan, &checksum, &createdAt, &nim, &name)
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(c, http.StatusNotFound, "Dokumen dengan kode tersebut tidak pernah diterbitkan")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memverifikasi dokumen")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"valid":           true,
+		"kode_verifikasi": code,
+		"jenis":           jenis,
+		"referensi":       referensi,
+		"ringkasan":       ringkasan,
+		"nim":             nim,
+		"name":            name,
+		"issued_at":       createdAt.Format("2006-01-02 15:04:05"),
+		"checksum":        checksum,
+	}, "Dokumen terverifikasi")
+}
@@ -29,7 +29,7 @@ func CreateAttendanceSession(c *gin.Context) {
 	var input struct {
 		CourseID    string `json:"course_id" binding:"required"`
 		Duration    int    `json:"duration" binding:"required,min=5,max=120"`
-		PertemuanKe int    `json:"pertemuan_ke" binding:"required,min=1,max=16"`
+		PertemuanKe int    `json:"pertemuan_ke" binding:"omitempty,min=1,max=16"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -37,6 +37,24 @@ func CreateAttendanceSession(c *gin.Context) {
 		return
 	}
 
+	// Kalender akademik: tolak hari libur/ujian dan isi pertemuan_ke otomatis dari tanggal
+	semesterID := sql.NullInt64{}
+	if semester, err := activeSemester(); err == nil {
+		semesterID = sql.NullInt64{Int64: int64(semester.ID), Valid: true}
+		todayPertemuan, _ := pertemuanForDate(semester, input.CourseID, time.Now())
+		if kind, keterangan := semester.dayKind(time.Now()); todayPertemuan == 0 && (kind == "libur" || kind == "uts" || kind == "uas") {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Tidak ada perkuliahan hari ini: "+keterangan)
+			return
+		}
+		if input.PertemuanKe == 0 {
+			input.PertemuanKe = todayPertemuan
+		}
+	}
+	if input.PertemuanKe == 0 {
+		utils.ValidationError(c, "pertemuan_ke wajib diisi karena hari ini tidak ada di kalender pertemuan mata kuliah")
+		return
+	}
+
 	// Get dosen ID
 	var dosenID int
 	err := config.DB.QueryRow("SELECT id FROM dosen WHERE user_id = ?", userID).Scan(&dosenID)
@@ -90,17 +108,18 @@ func CreateAttendanceSession(c *gin.Context) {
 	sessionCode := fmt.Sprintf("ABS-%s-P%d-%s", input.CourseID, input.PertemuanKe,
 		time.Now().Format("020106150405"))
 	qrToken := utils.GenerateRandomString(32)
+	qrSecret := utils.GenerateQRSecret()
 	expiresAt := time.Now().Add(time.Duration(input.Duration) * time.Minute)
 
 	// Insert ke attendance_sessions
 	query := `
 		INSERT INTO attendance_sessions 
-		(dosen_id, course_id, pertemuan_ke, session_token, session_code, qr_token, expires_at, status, created_at) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, 'active', NOW())
+		(dosen_id, course_id, pertemuan_ke, session_token, session_code, qr_token, qr_secret, expires_at, status, semester_id, created_at) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'active', ?, NOW())
 	`
 
 	result, err := config.DB.Exec(query, dosenID, input.CourseID, input.PertemuanKe,
-		sessionToken, sessionCode, qrToken, expiresAt)
+		sessionToken, sessionCode, qrToken, qrSecret, expiresAt, semesterID)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat sesi: "+err.Error())
 		return
@@ -108,6 +127,9 @@ func CreateAttendanceSession(c *gin.Context) {
 
 	sessionID, _ := result.LastInsertId()
 
+	// Izin/sakit yang sudah disetujui untuk pertemuan ini langsung dicatat
+	applyApprovedExcuses(int(sessionID), input.CourseID, input.PertemuanKe)
+
 	// Get schedule info
 	var jamMulai, jamSelesai string
 	config.DB.QueryRow(`
@@ -131,6 +153,7 @@ func CreateAttendanceSession(c *gin.Context) {
 		"jam_mulai":     jamMulai,
 		"jam_selesai":   jamSelesai,
 		"qr_url":        fmt.Sprintf("/api/dosen/absensi/qr/%s", sessionToken),
+		"qr":            buildRotatingQR(int(sessionID), sessionToken, input.CourseID, input.PertemuanKe, qrSecret),
 		"created_at":    time.Now().Format("2006-01-02 15:04:05"),
 	}, "Sesi absensi berhasil dibuat untuk pertemuan ke-"+strconv.Itoa(input.PertemuanKe))
 }
@@ -186,15 +209,17 @@ func RefreshSessionToken(c *gin.Context) {
 		return
 	}
 
-	// Generate new token (simple token untuk QR)
+	// Generate new token (simple token untuk QR). Secret QR ikut diganti sehingga
+	// semua kode QR yang sudah beredar langsung tidak berlaku.
 	newToken := fmt.Sprintf("%s-%d", utils.GenerateRandomString(20), time.Now().Unix())
+	newSecret := utils.GenerateQRSecret()
 
 	// Update token
 	_, err = config.DB.Exec(`
 		UPDATE attendance_sessions 
-		SET session_token = ?, updated_at = NOW() 
+		SET session_token = ?, qr_secret = ?, updated_at = NOW() 
 		WHERE id = ?
-	`, newToken, input.SessionID)
+	`, newToken, newSecret, input.SessionID)
 
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal refresh token: "+err.Error())
@@ -230,6 +255,7 @@ func RefreshSessionToken(c *gin.Context) {
 		"updated_at":        time.Now().Format("2006-01-02 15:04:05"),
 		"time_left_seconds": int(time.Until(expiresAt).Seconds()),
 		"qr_url":            fmt.Sprintf("/api/dosen/absensi/qr/%s", sessionToken),
+		"qr":                buildRotatingQR(input.SessionID, sessionToken, courseID, pertemuanKe, newSecret),
 	}, "Token berhasil di-refresh")
 }
 
@@ -315,7 +341,7 @@ func GetAttendanceSessionDetail(c *gin.Context) {
 	defer rows.Close()
 
 	var students []gin.H
-	var totalStudents, hadirCount, izinCount, sakitCount, alpaCount int
+	var totalStudents, hadirCount, terlambatCount, izinCount, sakitCount, alpaCount int
 
 	for rows.Next() {
 		var studentID int
@@ -331,6 +357,8 @@ func GetAttendanceSessionDetail(c *gin.Context) {
 		switch attendanceStatus {
 		case "hadir":
 			hadirCount++
+		case "terlambat":
+			terlambatCount++
 		case "izin":
 			izinCount++
 		case "sakit":
@@ -360,9 +388,10 @@ func GetAttendanceSessionDetail(c *gin.Context) {
 	`, courseID).Scan(&hari, &jamMulai, &jamSelesai)
 
 	// Calculate percentages
-	var hadirPercent, izinPercent, sakitPercent, alpaPercent float64
+	var hadirPercent, terlambatPercent, izinPercent, sakitPercent, alpaPercent float64
 	if totalStudents > 0 {
 		hadirPercent = float64(hadirCount) / float64(totalStudents) * 100
+		terlambatPercent = float64(terlambatCount) / float64(totalStudents) * 100
 		izinPercent = float64(izinCount) / float64(totalStudents) * 100
 		sakitPercent = float64(sakitCount) / float64(totalStudents) * 100
 		alpaPercent = float64(alpaCount) / float64(totalStudents) * 100
@@ -394,6 +423,9 @@ func GetAttendanceSessionDetail(c *gin.Context) {
 		}
 	}
 
+	// Perangkat yang dipakai scan untuk lebih dari satu mahasiswa
+	suspiciousDevices, _ := getSuspiciousDevices(id)
+
 	utils.SuccessResponse(c, gin.H{
 		"session_id":        id,
 		"course_id":         courseID,
@@ -409,10 +441,12 @@ func GetAttendanceSessionDetail(c *gin.Context) {
 		"jam_selesai":       jamSelesai,
 		"total_students":    totalStudents,
 		"hadir_count":       hadirCount,
+		"terlambat_count":   terlambatCount,
 		"izin_count":        izinCount,
 		"sakit_count":       sakitCount,
 		"alpa_count":        alpaCount,
 		"hadir_percent":     hadirPercent,
+		"terlambat_percent": terlambatPercent,
 		"izin_percent":      izinPercent,
 		"sakit_percent":     sakitPercent,
 		"alpa_percent":      alpaPercent,
@@ -420,6 +454,11 @@ func GetAttendanceSessionDetail(c *gin.Context) {
 		"time_left":         int(time.Until(expiresAt).Seconds()),
 		"is_active":         expiresAt.After(time.Now()),
 		"pertemuan_summary": pertemuanSummary,
+		"policy":            loadAttendancePolicy(courseID).toResponse(),
+		"suspicious_scans": gin.H{
+			"flagged_devices": len(suspiciousDevices),
+			"report_url":      fmt.Sprintf("/api/dosen/absensi/%d/suspicious", id),
+		},
 	}, "Detail sesi berhasil diambil")
 }
 
@@ -428,7 +467,8 @@ func UpdateAttendanceStatus(c *gin.Context) {
 	var input struct {
 		SessionID int    `json:"session_id" binding:"required"`
 		StudentID int    `json:"student_id" binding:"required"`
-		Status    string `json:"status" binding:"required,oneof=hadir izin sakit alpa"`
+		Status    string `json:"status" binding:"required,oneof=hadir terlambat izin sakit alpa"`
+		Reason    string `json:"reason"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -485,59 +525,18 @@ func UpdateAttendanceStatus(c *gin.Context) {
 		return
 	}
 
-	// Check if attendance already exists untuk pertemuan ini (hari ini)
-	var attendanceID int
-	err = config.DB.QueryRow(`
-		SELECT a.id 
-		FROM attendance a
-		WHERE a.student_id = ? 
-			AND a.session_id = ?
-			AND DATE(a.created_at) = CURDATE()
-		LIMIT 1
-	`, input.StudentID, input.SessionID).Scan(&attendanceID)
-
-	var result sql.Result
-	if err == nil && attendanceID > 0 {
-		// Update existing attendance
-		result, err = config.DB.Exec(`
-			UPDATE attendance 
-			SET status = ?, pertemuan_ke = ?, updated_at = NOW() 
-			WHERE id = ?
-		`, input.Status, pertemuanKe, attendanceID)
-	} else {
-		// Insert new attendance
-		var studentCode string
-		config.DB.QueryRow("SELECT nim FROM mahasiswa WHERE id = ?", input.StudentID).Scan(&studentCode)
-
-		result, err = config.DB.Exec(`
-			INSERT INTO attendance (student_id, session_id, student_code, status, pertemuan_ke, created_at)
-			VALUES (?, ?, ?, ?, ?, NOW())
-		`, input.StudentID, input.SessionID, studentCode, input.Status, pertemuanKe)
-	}
+	// Terlambat hanya dipakai jika kebijakan mata kuliah memakai status terlambat
+	input.Status = loadAttendancePolicy(courseID).normalizeStatus(input.Status)
 
+	// Simpan status ke attendance dan attendance_summary
+	rowsAffected, err := saveAttendanceStatus(input.SessionID, input.StudentID, courseID, pertemuanKe, input.Status,
+		attendanceChange{Source: auditSourceManual, ChangedBy: c.GetInt("user_id"), Reason: input.Reason})
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal update status: "+err.Error())
 		return
 	}
 
-	// Update attendance_summary
-	_, err = config.DB.Exec(`
-		INSERT INTO attendance_summary 
-		(student_id, nim, student_name, session_id, course_id, course_name, status, 
-		 attendance_date, attendance_time, dosen_name, hari, jam_mulai, jam_selesai)
-		SELECT 
-			m.id, m.nim, m.name, ?, mk.kode, mk.nama, ?,
-			CURDATE(), NOW(), d.name, mk.hari, mk.jam_mulai, mk.jam_selesai
-		FROM mahasiswa m
-		JOIN mata_kuliah mk ON mk.kode = ?
-		JOIN dosen d ON mk.dosen_id = d.id
-		WHERE m.id = ?
-		ON DUPLICATE KEY UPDATE
-			status = ?,
-			attendance_time = NOW()
-	`, input.SessionID, input.Status, courseID, input.StudentID, input.Status)
-
-	rowsAffected, _ := result.RowsAffected()
+	publishAttendanceEvent(input.SessionID, input.StudentID, input.Status, "manual")
 
 	// Get student info for response
 	var studentName, nim string
@@ -567,12 +566,12 @@ func GetQRCode(c *gin.Context) {
 
 	var sessionID, pertemuanKe int
 	var expiresAt time.Time
-	var courseID string
+	var courseID, qrSecret string
 	err := config.DB.QueryRow(`
-		SELECT id, expires_at, course_id, pertemuan_ke 
+		SELECT id, expires_at, course_id, pertemuan_ke, COALESCE(qr_secret, qr_token, '')
 		FROM attendance_sessions 
 		WHERE session_token = ? AND status = 'active'
-	`, token).Scan(&sessionID, &expiresAt, &courseID, &pertemuanKe)
+	`, token).Scan(&sessionID, &expiresAt, &courseID, &pertemuanKe, &qrSecret)
 
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -606,6 +605,8 @@ func GetQRCode(c *gin.Context) {
 		timeLeft = 0
 	}
 
+	qr := buildRotatingQR(sessionID, token, courseID, pertemuanKe, qrSecret)
+
 	c.JSON(http.StatusOK, gin.H{
 		"valid":         true,
 		"session_token": token,
@@ -619,10 +620,88 @@ func GetQRCode(c *gin.Context) {
 		"expires_at":    expiresAt.Format("2006-01-02 15:04:05"),
 		"time_left":     timeLeft,
 		"current_time":  time.Now().Format("2006-01-02 15:04:05"),
-		"qr_data":       fmt.Sprintf("%s|%s|%d|%d", token, courseID, pertemuanKe, time.Now().Unix()),
+		"qr_data":       qr["qr_data"],
+		"qr_payload":    qr["qr_payload"],
+		"qr_code":       qr["qr_code"],
+		"qr_period":     qr["qr_period"],
+		"qr_refresh_in": qr["qr_refresh_in"],
+		"qr_image_png":  qr["qr_image_png"],
+		"qr_image_svg":  qr["qr_image_svg"],
 	})
 }
 
+// buildRotatingQR - Data QR dengan kode yang diturunkan dari secret sesi dan window waktu saat ini.
+// Kode berganti setiap periode QR_TOKEN_PERIOD, jadi frontend cukup polling ulang
+// setelah qr_refresh_in detik tanpa perlu memanggil refresh-token.
+// qr_data berisi payload bertanda tangan (sesi, mata kuliah, kode, expiry) yang dienkode ke gambar QR.
+func buildRotatingQR(sessionID int, sessionToken, courseID string, pertemuanKe int, qrSecret string) gin.H {
+	period := utils.GetQRTokenPeriod()
+	now := time.Now()
+	code, remaining := utils.CurrentQRToken(qrSecret, now, period)
+	payload := utils.SignQRPayload(utils.NewQRPayload(sessionID, courseID, pertemuanKe, code, now, period))
+
+	return gin.H{
+		"qr_data":       payload,
+		"qr_payload":    payload,
+		"qr_code":       code,
+		"qr_period":     period,
+		"qr_refresh_in": remaining,
+		"qr_image_png":  fmt.Sprintf("/api/dosen/absensi/qr/%s/image?format=png", sessionToken),
+		"qr_image_svg":  fmt.Sprintf("/api/dosen/absensi/qr/%s/image?format=svg", sessionToken),
+	}
+}
+
+// defaultQRImageScale - ukuran piksel per modul untuk gambar PNG
+const defaultQRImageScale = 8
+
+// GetQRCodeImage - Render QR absensi yang sedang berlaku sebagai PNG atau SVG
+func GetQRCodeImage(c *gin.Context) {
+	token := c.Param("token")
+	if token == "" {
+		utils.ValidationError(c, "Token diperlukan")
+		return
+	}
+
+	var sessionID, pertemuanKe int
+	var courseID, qrSecret string
+	err := config.DB.QueryRow(`
+		SELECT id, course_id, pertemuan_ke, COALESCE(qr_secret, qr_token, '')
+		FROM attendance_sessions
+		WHERE session_token = ? AND status = 'active' AND expires_at > NOW()
+	`, token).Scan(&sessionID, &courseID, &pertemuanKe, &qrSecret)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Sesi tidak ditemukan atau sudah tidak aktif")
+		return
+	}
+
+	qr := buildRotatingQR(sessionID, token, courseID, pertemuanKe, qrSecret)
+	code, err := utils.EncodeQR([]byte(qr["qr_payload"].(string)))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat QR Code: "+err.Error())
+		return
+	}
+
+	// Gambar hanya berlaku satu window kode, jangan di-cache
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-QR-Refresh-In", strconv.Itoa(qr["qr_refresh_in"].(int)))
+
+	if c.DefaultQuery("format", "png") == "svg" {
+		c.Data(http.StatusOK, "image/svg+xml", []byte(code.SVG()))
+		return
+	}
+
+	scale, err := strconv.Atoi(c.DefaultQuery("scale", strconv.Itoa(defaultQRImageScale)))
+	if err != nil || scale < 1 || scale > 32 {
+		scale = defaultQRImageScale
+	}
+	pngData, err := code.PNG(scale)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat QR Code: "+err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "image/png", pngData)
+}
+
 // CloseAttendanceSession - Tutup sesi absensi (FIXED)
 func CloseAttendanceSession(c *gin.Context) {
 	var input struct {
@@ -670,13 +749,12 @@ func CloseAttendanceSession(c *gin.Context) {
 		GROUP BY asess.id
 	`, input.SessionID).Scan(&courseID, &courseName, &pertemuanKe, &attendanceCount)
 
-	// Close session
-	_, err = config.DB.Exec(`
-		UPDATE attendance_sessions 
-		SET status = 'closed'
-		WHERE id = ?
-	`, input.SessionID)
-
+	// Close session dan catat alpa untuk mahasiswa yang belum absen
+	backfilled, err := closeAttendanceSession(input.SessionID)
+	if err == errAttendanceSessionClosed {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Sesi sudah ditutup")
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menutup sesi: "+err.Error())
 		return
@@ -688,6 +766,7 @@ func CloseAttendanceSession(c *gin.Context) {
 		"course_name":      courseName,
 		"pertemuan_ke":     pertemuanKe,
 		"attendance_count": attendanceCount,
+		"alpa_backfilled":  backfilled,
 		"closed_at":        time.Now().Format("2006-01-02 15:04:05"),
 	}, "Sesi berhasil ditutup untuk pertemuan ke-"+strconv.Itoa(pertemuanKe))
 }
@@ -728,6 +807,7 @@ func GetActiveSessions(c *gin.Context) {
 			asess.pertemuan_ke,
 			COUNT(DISTINCT a.id) as attendance_count,
 			COUNT(DISTINCT CASE WHEN a.status = 'hadir' THEN a.id END) as hadir_count,
+			COUNT(DISTINCT CASE WHEN a.status = 'terlambat' THEN a.id END) as terlambat_count,
 			COUNT(DISTINCT CASE WHEN a.status = 'izin' THEN a.id END) as izin_count,
 			COUNT(DISTINCT CASE WHEN a.status = 'sakit' THEN a.id END) as sakit_count,
 			COUNT(DISTINCT CASE WHEN a.status = 'alpa' THEN a.id END) as alpa_count,
@@ -770,13 +850,13 @@ func GetActiveSessions(c *gin.Context) {
 
 	var sessions []gin.H
 	for rows.Next() {
-		var id, pertemuanKe, attendanceCount, hadirCount, izinCount, sakitCount, alpaCount, totalStudents int
+		var id, pertemuanKe, attendanceCount, hadirCount, terlambatCount, izinCount, sakitCount, alpaCount, totalStudents int
 		var courseID, courseName, hari, jamMulai, jamSelesai, sessionToken, sessionCode, status string
 		var expiresAt, createdAt time.Time
 
 		err := rows.Scan(&id, &courseID, &courseName, &hari, &jamMulai, &jamSelesai,
 			&sessionToken, &sessionCode, &expiresAt, &createdAt, &status, &pertemuanKe,
-			&attendanceCount, &hadirCount, &izinCount, &sakitCount, &alpaCount, &totalStudents)
+			&attendanceCount, &hadirCount, &terlambatCount, &izinCount, &sakitCount, &alpaCount, &totalStudents)
 		if err != nil {
 			continue
 		}
@@ -809,6 +889,7 @@ func GetActiveSessions(c *gin.Context) {
 			"pertemuan_ke":      pertemuanKe,
 			"attendance_count":  attendanceCount,
 			"hadir_count":       hadirCount,
+			"terlambat_count":   terlambatCount,
 			"izin_count":        izinCount,
 			"sakit_count":       sakitCount,
 			"alpa_count":        alpaCount,
@@ -1068,7 +1149,7 @@ func GetRealtimeAttendance(c *gin.Context) {
 	defer rows.Close()
 
 	var students []gin.H
-	var hadirCount, izinCount, sakitCount, alpaCount, belumCount int
+	var hadirCount, terlambatCount, izinCount, sakitCount, alpaCount, belumCount int
 
 	for rows.Next() {
 		var nim, name, status, waktuAbsen string
@@ -1080,6 +1161,8 @@ func GetRealtimeAttendance(c *gin.Context) {
 		switch status {
 		case "hadir":
 			hadirCount++
+		case "terlambat":
+			terlambatCount++
 		case "izin":
 			izinCount++
 		case "sakit":
@@ -1101,16 +1184,24 @@ func GetRealtimeAttendance(c *gin.Context) {
 	}
 
 	// Get session info
-	var courseID, courseName string
+	var courseID, courseName, sessionToken, qrSecret, sessionStatus string
+	var pertemuanKe int
 	var expiresAt time.Time
 	config.DB.QueryRow(`
-		SELECT asess.course_id, mk.nama, asess.expires_at
+		SELECT asess.course_id, mk.nama, asess.expires_at, asess.pertemuan_ke,
+		       COALESCE(asess.session_token, ''), COALESCE(asess.qr_secret, asess.qr_token, ''), asess.status
 		FROM attendance_sessions asess
 		JOIN mata_kuliah mk ON asess.course_id = mk.kode
 		WHERE asess.id = ?
-	`, id).Scan(&courseID, &courseName, &expiresAt)
+	`, id).Scan(&courseID, &courseName, &expiresAt, &pertemuanKe, &sessionToken, &qrSecret, &sessionStatus)
+
+	// QR selalu segar untuk tampilan proyektor selama sesi masih aktif
+	var qr gin.H
+	if sessionStatus == "active" && expiresAt.After(time.Now()) {
+		qr = buildRotatingQR(id, sessionToken, courseID, pertemuanKe, qrSecret)
+	}
 
-	totalStudents := hadirCount + izinCount + sakitCount + alpaCount + belumCount
+	totalStudents := hadirCount + terlambatCount + izinCount + sakitCount + alpaCount + belumCount
 	var hadirPercent float64
 	if totalStudents > 0 {
 		hadirPercent = float64(hadirCount) / float64(totalStudents) * 100
@@ -1122,10 +1213,12 @@ func GetRealtimeAttendance(c *gin.Context) {
 		"course_name": courseName,
 		"expires_at":  expiresAt.Format("2006-01-02 15:04:05"),
 		"time_left":   int(time.Until(expiresAt).Seconds()),
+		"qr":          qr,
 		"students":    students,
 		"summary": gin.H{
 			"total_students": totalStudents,
 			"hadir":          hadirCount,
+			"terlambat":      terlambatCount,
 			"izin":           izinCount,
 			"sakit":          sakitCount,
 			"alpa":           alpaCount,
@@ -1144,6 +1237,8 @@ func getStatusColor(status string) string {
 	switch status {
 	case "hadir":
 		return "bg-green-100 text-green-800"
+	case "terlambat":
+		return "bg-orange-100 text-orange-800"
 	case "izin":
 		return "bg-yellow-100 text-yellow-800"
 	case "sakit":
@@ -1159,6 +1254,8 @@ func getStatusLabel(status string) string {
 	switch status {
 	case "hadir":
 		return "Hadir"
+	case "terlambat":
+		return "Terlambat"
 	case "izin":
 		return "Izin"
 	case "sakit":
@@ -1537,10 +1634,10 @@ func UploadMateri(c *gin.Context) {
 	// INSERT ke tabel tugas dengan type 'materi'
 	query := `
 		INSERT INTO tugas 
-		(course_id, pertemuan, title, description, file_tugas, type, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, 'materi', NOW(), NOW())
+		(course_id, pertemuan, title, description, file_tugas, type, semester_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, 'materi', ?, NOW(), NOW())
 	`
-	result, err := config.DB.Exec(query, courseID, pertemuan, title, desc, filePath)
+	result, err := config.DB.Exec(query, courseID, pertemuan, title, desc, filePath, currentSemesterID())
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal upload materi: "+err.Error())
 		return
@@ -1607,6 +1704,18 @@ func CreateTugas(c *gin.Context) {
 		dueDate = sql.NullTime{Time: time.Now().Add(7 * 24 * time.Hour), Valid: true}
 	}
 
+	// Kebijakan keterlambatan opsional (default: terlambat tetap diterima tanpa potongan)
+	var lateInput latePolicyInput
+	if err := c.ShouldBind(&lateInput); err != nil {
+		utils.ValidationError(c, "Kebijakan keterlambatan tidak valid: "+err.Error())
+		return
+	}
+	policy, err := lateInput.apply(defaultLatePolicy)
+	if err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
 	// File tugas opsional
 	var filePath sql.NullString
 	file, header, err := c.Request.FormFile("file_tugas")
@@ -1634,10 +1743,12 @@ func CreateTugas(c *gin.Context) {
 	// Insert ke tabel tugas dengan type 'tugas'
 	query := `
 		INSERT INTO tugas 
-		(course_id, pertemuan, title, description, file_tugas, due_date, type, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, 'tugas', NOW(), NOW())
+		(course_id, pertemuan, title, description, file_tugas, due_date, type, semester_id,
+		 late_policy, grace_minutes, penalty_per_day, max_penalty, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, 'tugas', ?, ?, ?, ?, ?, NOW(), NOW())
 	`
-	result, err := config.DB.Exec(query, courseID, pertemuan, title, desc, filePath, dueDate)
+	result, err := config.DB.Exec(query, courseID, pertemuan, title, desc, filePath, dueDate, currentSemesterID(),
+		policy.Policy, policy.GraceMinutes, policy.PenaltyPerDay, policy.MaxPenalty)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat tugas: "+err.Error())
 		return
@@ -1652,6 +1763,7 @@ func CreateTugas(c *gin.Context) {
 		"description": desc,
 		"file_tugas":  filePath.String,
 		"due_date":    dueDate.Time.Format("2006-01-02 15:04:05"),
+		"late_policy": policy.toResponse(),
 		"created_at":  time.Now().Format("2006-01-02 15:04:05"),
 	}, "Tugas berhasil dibuat")
 }
@@ -1709,10 +1821,13 @@ func GetTugasSubmissions(c *gin.Context) {
 				s.grade, s.created_at, s.updated_at,
 				m.name as student_name, m.nim as student_nim, 
 				t.title as task_title, t.pertemuan,
-				t.due_date
+				t.due_date, e.due_date, COALESCE(s.submitted_at, s.created_at),
+				s.late_days, s.late_penalty, s.graded_version_id,
+				(SELECT COUNT(*) FROM submission_versions v WHERE v.submission_id = s.id)
 			FROM submissions s
 			JOIN mahasiswa m ON s.student_id = m.id
 			JOIN tugas t ON s.task_id = t.id
+			LEFT JOIN tugas_extensions e ON e.task_id = t.id AND e.student_id = s.student_id
 			WHERE t.course_id = ? AND t.pertemuan = ?
 			ORDER BY s.created_at DESC
 		`
@@ -1724,10 +1839,13 @@ func GetTugasSubmissions(c *gin.Context) {
 				s.grade, s.created_at, s.updated_at,
 				m.name as student_name, m.nim as student_nim, 
 				t.title as task_title, t.pertemuan,
-				t.due_date
+				t.due_date, e.due_date, COALESCE(s.submitted_at, s.created_at),
+				s.late_days, s.late_penalty, s.graded_version_id,
+				(SELECT COUNT(*) FROM submission_versions v WHERE v.submission_id = s.id)
 			FROM submissions s
 			JOIN mahasiswa m ON s.student_id = m.id
 			JOIN tugas t ON s.task_id = t.id
+			LEFT JOIN tugas_extensions e ON e.task_id = t.id AND e.student_id = s.student_id
 			WHERE t.course_id = ?
 			ORDER BY t.pertemuan DESC, s.created_at DESC
 		`
@@ -1741,6 +1859,9 @@ func GetTugasSubmissions(c *gin.Context) {
 	}
 	defer rows.Close()
 
+	// Skor kemiripan dari pemeriksaan terakhir tiap tugas
+	similarity := courseSimilarityHighlights(courseID)
+
 	var submissions []gin.H
 	for rows.Next() {
 		var id, taskID, studentID, pertemuan int
@@ -1748,23 +1869,25 @@ func GetTugasSubmissions(c *gin.Context) {
 		var grade sql.NullFloat64
 		var createdAt, updatedAt time.Time
 		var studentName, studentNIM, taskTitle string
-		var dueDate sql.NullTime
+		var dueDate, extendedDue sql.NullTime
+		var submittedAt time.Time
+		var lateDays int
+		var latePenalty float64
+		var gradedVersionID sql.NullInt64
+		var versionCount int
 
 		err := rows.Scan(&id, &taskID, &studentID, &fileURL, &answerText, &grade, &createdAt,
-			&updatedAt, &studentName, &studentNIM, &taskTitle, &pertemuan, &dueDate)
+			&updatedAt, &studentName, &studentNIM, &taskTitle, &pertemuan, &dueDate, &extendedDue,
+			&submittedAt, &lateDays, &latePenalty, &gradedVersionID, &versionCount)
 		if err != nil {
 			continue
 		}
 
-		// Check if submission is late
-		var isLate bool
-		var lateDays int
-		if dueDate.Valid && createdAt.After(dueDate.Time) {
-			isLate = true
-			lateDays = int(createdAt.Sub(dueDate.Time).Hours() / 24)
-			if lateDays < 1 {
-				lateDays = 1
-			}
+		// Keterlambatan dihitung saat submit terhadap deadline yang berlaku (termasuk perpanjangan)
+		isLate := lateDays > 0
+		extendedDueStr := ""
+		if extendedDue.Valid {
+			extendedDueStr = extendedDue.Time.Format("2006-01-02 15:04:05")
 		}
 
 		submission := gin.H{
@@ -1781,10 +1904,17 @@ func GetTugasSubmissions(c *gin.Context) {
 			"task_title":   taskTitle,
 			"pertemuan":    pertemuan,
 			"due_date":     dueDate.Time.Format("2006-01-02 15:04:05"),
+			"extended_due": extendedDueStr,
+			"submitted_at": submittedAt.Format("2006-01-02 15:04:05"),
 			"is_late":      isLate,
 			"late_days":    lateDays,
+			"late_penalty": latePenalty,
+			"final_grade":  applyLatePenalty(grade.Float64, latePenalty),
 			"graded":       grade.Valid && grade.Float64 > 0,
 		}
+		submission["version_count"] = versionCount
+		submission["graded_version_id"] = gradedVersionID.Int64
+		submission["similarity"] = similarity[id]
 		submissions = append(submissions, submission)
 	}
 
@@ -1794,7 +1924,7 @@ func GetTugasSubmissions(c *gin.Context) {
 		SELECT 
 			COUNT(*) as total,
 			COUNT(CASE WHEN s.grade IS NOT NULL AND s.grade > 0 THEN 1 END) as graded,
-			COUNT(CASE WHEN s.created_at > t.due_date THEN 1 END) as late
+			COUNT(CASE WHEN s.late_days > 0 THEN 1 END) as late
 		FROM submissions s
 		JOIN tugas t ON s.task_id = t.id
 		WHERE t.course_id = ?
@@ -1807,6 +1937,7 @@ func GetTugasSubmissions(c *gin.Context) {
 			"graded":  gradedSubmissions,
 			"late":    lateSubmissions,
 			"pending": totalSubmissions - gradedSubmissions,
+			"similar": len(similarity),
 		},
 	}, "Submissions retrieved successfully")
 }
@@ -1826,8 +1957,9 @@ func GradeSubmission(c *gin.Context) {
 	}
 
 	var input struct {
-		Grade float64 `json:"grade" binding:"required,gte=0,lte=100"`
-		Notes string  `json:"notes"`
+		Grade     float64 `json:"grade" binding:"required,gte=0,lte=100"`
+		Notes     string  `json:"notes"`
+		VersionID int64   `json:"version_id"` // opsional: versi pengumpulan yang dinilai
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -1864,9 +1996,32 @@ func GradeSubmission(c *gin.Context) {
 		return
 	}
 
-	// Update submission with grade
-	query := `UPDATE submissions SET grade = ?, updated_at = NOW() WHERE id = ?`
-	result, err := config.DB.Exec(query, input.Grade, submissionID)
+	if isGradebookLocked(courseID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Nilai mata kuliah sudah dikunci")
+		return
+	}
+
+	versionID, ok := resolveGradedVersion(c, submissionID, input.VersionID)
+	if !ok {
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to grade submission: "+err.Error())
+		return
+	}
+	defer tx.Rollback()
+
+	// Update submission with grade; catatan disimpan sebagai feedback
+	result, err := tx.Exec(submissionGradeQuery, versionID, input.Grade, input.Notes, submissionID)
+	if err == nil {
+		// Nilai langsung menggantikan penilaian rubrik sebelumnya
+		_, err = tx.Exec("DELETE FROM submission_rubric_scores WHERE submission_id = ?", submissionID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to grade submission: "+err.Error())
 		return
@@ -1877,13 +2032,14 @@ func GradeSubmission(c *gin.Context) {
 	// Get submission details
 	var studentName, taskTitle string
 	var studentID int
+	var latePenalty float64
 	config.DB.QueryRow(`
-		SELECT m.name, m.id, t.title
+		SELECT m.name, m.id, t.title, s.late_penalty
 		FROM submissions s
 		JOIN mahasiswa m ON s.student_id = m.id
 		JOIN tugas t ON s.task_id = t.id
 		WHERE s.id = ?
-	`, submissionID).Scan(&studentName, &studentID, &taskTitle)
+	`, submissionID).Scan(&studentName, &studentID, &taskTitle, &latePenalty)
 
 	utils.SuccessResponse(c, gin.H{
 		"submission_id": submissionID,
@@ -1891,6 +2047,9 @@ func GradeSubmission(c *gin.Context) {
 		"student_name":  studentName,
 		"task_title":    taskTitle,
 		"grade":         input.Grade,
+		"version_id":    versionID,
+		"late_penalty":  latePenalty,
+		"final_grade":   applyLatePenalty(input.Grade, latePenalty),
 		"updated_at":    time.Now().Format("2006-01-02 15:04:05"),
 		"rows_affected": rowsAffected,
 	}, "Submission graded successfully")
@@ -2064,14 +2223,24 @@ func DeleteSubmission(c *gin.Context) {
 		return
 	}
 
-	// Hapus file submission jika ada
-	var fileURL sql.NullString
-	err = config.DB.QueryRow("SELECT file_url FROM submissions WHERE id = ?", submissionID).Scan(&fileURL)
-	if err == nil && fileURL.Valid && fileURL.String != "" {
-		fullPath := "." + fileURL.String
-		if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
-			fmt.Printf("Warning: Gagal menghapus file submission: %v\n", err)
+	// Hapus file submission beserta file semua versinya
+	fileRows, err := config.DB.Query(`
+		SELECT file_url FROM submissions WHERE id = ? AND file_url IS NOT NULL AND file_url != ''
+		UNION
+		SELECT file_url FROM submission_versions WHERE submission_id = ? AND file_url IS NOT NULL AND file_url != ''
+	`, submissionID, submissionID)
+	if err == nil {
+		for fileRows.Next() {
+			var fileURL string
+			if fileRows.Scan(&fileURL) != nil {
+				continue
+			}
+			fullPath := "." + fileURL
+			if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+				fmt.Printf("Warning: Gagal menghapus file submission: %v\n", err)
+			}
 		}
+		fileRows.Close()
 	}
 
 	// Hapus submission
@@ -2153,7 +2322,7 @@ func GetPertemuanByMatkul(c *gin.Context) {
 	utils.SuccessResponse(c, list, "Data pertemuan berhasil diambil")
 }
 
-// GetPertemuanDetail - Get detail pertemuan dengan tugas dan materi
+// GetPertemuanDetail - Get detail pertemuan dengan tugas, materi, dan kuis
 func GetPertemuanDetail(c *gin.Context) {
 	courseID := c.Param("course_id")
 	pertemuanStr := c.Param("pertemuan")
@@ -2308,6 +2477,7 @@ func GetPertemuanDetail(c *gin.Context) {
 		"jam_selesai": jamSelesai,
 		"materi":      materi,
 		"tugas":       tugas,
+		"quiz":        pertemuanQuizzes(courseID, pertemuan, userRole, userID),
 		"role":        userRole,
 		"fetched_at":  time.Now().Format("2006-01-02 15:04:05"),
 	}, "Pertemuan detail retrieved successfully")
@@ -0,0 +1,483 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// excuseUploadDir - folder bukti izin/sakit (surat dokter, dll)
+const excuseUploadDir = "./uploads/izin"
+
+// SubmitExcuse - Mahasiswa mengajukan izin/sakit untuk satu pertemuan
+func SubmitExcuse(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := c.Request.ParseMultipartForm(10 << 20); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to parse form data")
+		return
+	}
+
+	courseID := c.Request.FormValue("course_id")
+	jenis := c.Request.FormValue("jenis")
+	alasan := strings.TrimSpace(c.Request.FormValue("alasan"))
+
+	pertemuanKe, err := strconv.Atoi(c.Request.FormValue("pertemuan_ke"))
+	if err != nil || pertemuanKe < 1 || pertemuanKe > 16 {
+		utils.ValidationError(c, "pertemuan_ke harus 1-16")
+		return
+	}
+
+	if courseID == "" || alasan == "" {
+		utils.ValidationError(c, "course_id dan alasan wajib diisi")
+		return
+	}
+
+	if jenis != "izin" && jenis != "sakit" {
+		utils.ValidationError(c, "jenis harus izin atau sakit")
+		return
+	}
+
+	var mahasiswaID int
+	if err := config.DB.QueryRow("SELECT id FROM mahasiswa WHERE user_id = ?", userID).Scan(&mahasiswaID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Mahasiswa not found")
+		return
+	}
+
+	var enrolled bool
+	config.DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM mahasiswa_mata_kuliah
+			WHERE mahasiswa_id = ? AND mata_kuliah_kode = ?
+		)
+	`, mahasiswaID, courseID).Scan(&enrolled)
+	if !enrolled {
+		utils.ErrorResponse(c, http.StatusForbidden, "Anda tidak terdaftar pada mata kuliah ini")
+		return
+	}
+
+	// Hanya satu pengajuan aktif (pending/approved) per pertemuan
+	var duplicate bool
+	config.DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM attendance_excuses
+			WHERE student_id = ? AND course_id = ? AND pertemuan_ke = ?
+				AND status IN ('pending', 'approved')
+		)
+	`, mahasiswaID, courseID, pertemuanKe).Scan(&duplicate)
+	if duplicate {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Anda sudah mengajukan izin untuk pertemuan ke-"+strconv.Itoa(pertemuanKe))
+		return
+	}
+
+	var attended bool
+	config.DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM attendance a
+			JOIN attendance_sessions asess ON a.session_id = asess.id
+			WHERE a.student_id = ? AND asess.course_id = ? AND asess.pertemuan_ke = ?
+				AND a.status IN ('hadir', 'terlambat')
+		)
+	`, mahasiswaID, courseID, pertemuanKe).Scan(&attended)
+	if attended {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Anda sudah tercatat hadir pada pertemuan ke-"+strconv.Itoa(pertemuanKe))
+		return
+	}
+
+	// Bukti (opsional untuk izin, disarankan untuk sakit)
+	var fileURL string
+	file, header, err := c.Request.FormFile("file")
+	if err == nil {
+		defer file.Close()
+
+		allowedTypes := map[string]bool{
+			".pdf": true, ".jpg": true, ".jpeg": true, ".png": true,
+		}
+		ext := strings.ToLower(filepath.Ext(header.Filename))
+		if !allowedTypes[ext] {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid file type")
+			return
+		}
+
+		filename := fmt.Sprintf("izin_%d_%s_p%d_%s%s",
+			mahasiswaID, courseID, pertemuanKe, utils.GenerateRandomString(8), ext)
+		fileURL = "/uploads/izin/" + filename
+
+		if err := os.MkdirAll(excuseUploadDir, 0755); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create directory")
+			return
+		}
+
+		if err := c.SaveUploadedFile(header, excuseUploadDir+"/"+filename); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to save file")
+			return
+		}
+	}
+
+	result, err := config.DB.Exec(`
+		INSERT INTO attendance_excuses
+		(student_id, course_id, pertemuan_ke, jenis, alasan, file_url, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, 'pending', NOW())
+	`, mahasiswaID, courseID, pertemuanKe, jenis, alasan, fileURL)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengajukan izin: "+err.Error())
+		return
+	}
+
+	excuseID, _ := result.LastInsertId()
+
+	utils.SuccessResponse(c, gin.H{
+		"id":           excuseID,
+		"course_id":    courseID,
+		"pertemuan_ke": pertemuanKe,
+		"jenis":        jenis,
+		"file_url":     fileURL,
+		"status":       "pending",
+	}, "Pengajuan "+getStatusLabel(jenis)+" berhasil dikirim, menunggu persetujuan dosen")
+}
+
+// GetMyExcuses - Riwayat pengajuan izin/sakit mahasiswa
+func GetMyExcuses(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var mahasiswaID int
+	if err := config.DB.QueryRow("SELECT id FROM mahasiswa WHERE user_id = ?", userID).Scan(&mahasiswaID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Mahasiswa not found")
+		return
+	}
+
+	excuses, err := listExcuses("e.student_id = ?", mahasiswaID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pengajuan izin: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"excuses": excuses,
+		"total":   len(excuses),
+	}, "Riwayat pengajuan izin berhasil diambil")
+}
+
+// GetExcuseQueue - Antrian pengajuan izin/sakit untuk mata kuliah yang diampu dosen
+func GetExcuseQueue(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var dosenID int
+	if err := config.DB.QueryRow("SELECT id FROM dosen WHERE user_id = ?", userID).Scan(&dosenID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Dosen not found")
+		return
+	}
+
+	status := c.DefaultQuery("status", "pending")
+	where := "mk.dosen_id = ?"
+	args := []interface{}{dosenID}
+
+	if status != "all" {
+		where += " AND e.status = ?"
+		args = append(args, status)
+	}
+
+	if courseID := c.Query("course_id"); courseID != "" {
+		where += " AND e.course_id = ?"
+		args = append(args, courseID)
+	}
+
+	excuses, err := listExcuses(where, args...)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil antrian izin: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"excuses": excuses,
+		"total":   len(excuses),
+		"filters": gin.H{
+			"status":    status,
+			"course_id": c.Query("course_id"),
+		},
+	}, "Antrian pengajuan izin berhasil diambil")
+}
+
+// ReviewExcuse - Dosen menyetujui atau menolak pengajuan izin/sakit
+func ReviewExcuse(c *gin.Context) {
+	excuseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid excuse ID")
+		return
+	}
+
+	var input struct {
+		Status string `json:"status" binding:"required,oneof=approved rejected"`
+		Note   string `json:"note"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var dosenID int
+	if err := config.DB.QueryRow("SELECT id FROM dosen WHERE user_id = ?", userID).Scan(&dosenID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Dosen not found")
+		return
+	}
+
+	var excuse struct {
+		StudentID   int
+		CourseID    string
+		PertemuanKe int
+		Jenis       string
+		Status      string
+		CourseDosen int
+	}
+	err = config.DB.QueryRow(`
+		SELECT e.student_id, e.course_id, e.pertemuan_ke, e.jenis, e.status, mk.dosen_id
+		FROM attendance_excuses e
+		JOIN mata_kuliah mk ON e.course_id = mk.kode
+		WHERE e.id = ?
+	`, excuseID).Scan(&excuse.StudentID, &excuse.CourseID, &excuse.PertemuanKe,
+		&excuse.Jenis, &excuse.Status, &excuse.CourseDosen)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Pengajuan izin tidak ditemukan")
+		return
+	}
+
+	if excuse.CourseDosen != dosenID {
+		utils.ErrorResponse(c, http.StatusForbidden, "Anda tidak mengampu mata kuliah ini")
+		return
+	}
+
+	if excuse.Status != "pending" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Pengajuan izin sudah ditinjau")
+		return
+	}
+
+	// status = 'pending' pada UPDATE: dua peninjauan bersamaan tidak bisa sama-sama berlaku
+	result, err := config.DB.Exec(`
+		UPDATE attendance_excuses
+		SET status = ?, reviewed_by = ?, review_note = ?, reviewed_at = NOW(), updated_at = NOW()
+		WHERE id = ? AND status = 'pending'
+	`, input.Status, dosenID, input.Note, excuseID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal meninjau pengajuan izin: "+err.Error())
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Pengajuan izin sudah ditinjau")
+		return
+	}
+
+	// Jika disetujui dan sesi pertemuan sudah ada, langsung tulis ke attendance.
+	// Jika belum, status diterapkan saat dosen membuat sesi untuk pertemuan tersebut.
+	applied := false
+	var sessionID int
+	if input.Status == "approved" {
+		err = config.DB.QueryRow(`
+			SELECT id FROM attendance_sessions
+			WHERE course_id = ? AND pertemuan_ke = ? AND `+semesterFilter("semester_id")+`
+			ORDER BY created_at DESC
+			LIMIT 1
+		`, append([]interface{}{excuse.CourseID, excuse.PertemuanKe}, semesterArgs(currentSemesterID())...)...).Scan(&sessionID)
+		if err == nil {
+			applied, err = applyExcuse(excuseID, sessionID, excuse.StudentID, excuse.CourseID, excuse.PertemuanKe, excuse.Jenis)
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan status kehadiran: "+err.Error())
+				return
+			}
+		}
+	}
+
+	response := gin.H{
+		"id":           excuseID,
+		"student_id":   excuse.StudentID,
+		"course_id":    excuse.CourseID,
+		"pertemuan_ke": excuse.PertemuanKe,
+		"jenis":        excuse.Jenis,
+		"status":       input.Status,
+		"applied":      applied,
+	}
+	if applied {
+		response["session_id"] = sessionID
+	}
+
+	message := "Pengajuan izin ditolak"
+	if input.Status == "approved" {
+		message = "Pengajuan izin disetujui"
+	}
+
+	utils.SuccessResponse(c, response, message)
+}
+
+// GetChildExcuses - Riwayat pengajuan izin/sakit anak (orangtua)
+func GetChildExcuses(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var childID int
+	if err := config.DB.QueryRow("SELECT child_id FROM ortu WHERE user_id = ?", userID).Scan(&childID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Data anak tidak ditemukan")
+		return
+	}
+
+	excuses, err := listExcuses("e.student_id = ?", childID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pengajuan izin: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"child_id": childID,
+		"excuses":  excuses,
+		"total":    len(excuses),
+	}, "Riwayat izin anak berhasil diambil")
+}
+
+// applyExcuse - Tulis status izin/sakit dari pengajuan yang disetujui ke sesi.
+// Tidak menimpa kehadiran (hadir/terlambat) yang sudah tercatat.
+func applyExcuse(excuseID, sessionID, studentID int, courseID string, pertemuanKe int, jenis string) (bool, error) {
+	var currentStatus string
+	config.DB.QueryRow(`
+		SELECT status FROM attendance WHERE student_id = ? AND session_id = ? LIMIT 1
+	`, studentID, sessionID).Scan(&currentStatus)
+
+	if currentStatus == "hadir" || currentStatus == "terlambat" {
+		return false, nil
+	}
+
+	// Perubahan dicatat atas nama dosen yang menyetujui pengajuan
+	var reviewerUserID int
+	config.DB.QueryRow(`
+		SELECT d.user_id FROM attendance_excuses e JOIN dosen d ON e.reviewed_by = d.id WHERE e.id = ?
+	`, excuseID).Scan(&reviewerUserID)
+
+	change := attendanceChange{
+		Source:    auditSourceExcuse,
+		ChangedBy: reviewerUserID,
+		Reason:    fmt.Sprintf("Pengajuan %s #%d disetujui", jenis, excuseID),
+	}
+	if _, err := saveAttendanceStatus(sessionID, studentID, courseID, pertemuanKe, jenis, change); err != nil {
+		return false, err
+	}
+
+	config.DB.Exec("UPDATE attendance_excuses SET applied_session_id = ? WHERE id = ?", sessionID, excuseID)
+	return true, nil
+}
+
+// applyApprovedExcuses - Terapkan semua pengajuan yang sudah disetujui ke sesi pertemuan yang baru dibuat
+func applyApprovedExcuses(sessionID int, courseID string, pertemuanKe int) {
+	rows, err := config.DB.Query(`
+		SELECT id, student_id, jenis
+		FROM attendance_excuses
+		WHERE course_id = ? AND pertemuan_ke = ? AND status = 'approved'
+	`, courseID, pertemuanKe)
+	if err != nil {
+		return
+	}
+
+	type approvedExcuse struct {
+		ID        int
+		StudentID int
+		Jenis     string
+	}
+
+	var excuses []approvedExcuse
+	for rows.Next() {
+		var e approvedExcuse
+		if err := rows.Scan(&e.ID, &e.StudentID, &e.Jenis); err == nil {
+			excuses = append(excuses, e)
+		}
+	}
+	rows.Close()
+
+	for _, e := range excuses {
+		applyExcuse(e.ID, sessionID, e.StudentID, courseID, pertemuanKe, e.Jenis)
+	}
+}
+
+// listExcuses - Ambil pengajuan izin/sakit dengan filter where
+func listExcuses(where string, args ...interface{}) ([]gin.H, error) {
+	rows, err := config.DB.Query(`
+		SELECT e.id, e.student_id, m.nim, m.name, e.course_id, mk.nama, e.pertemuan_ke,
+		       e.jenis, e.alasan, COALESCE(e.file_url, ''), e.status,
+		       COALESCE(d.name, ''), COALESCE(e.review_note, ''), e.reviewed_at, e.created_at
+		FROM attendance_excuses e
+		JOIN mahasiswa m ON e.student_id = m.id
+		JOIN mata_kuliah mk ON e.course_id = mk.kode
+		LEFT JOIN dosen d ON e.reviewed_by = d.id
+		WHERE `+where+`
+		ORDER BY e.created_at DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	excuses := []gin.H{}
+	for rows.Next() {
+		var id, studentID, pertemuanKe int
+		var nim, name, courseID, courseName, jenis, alasan, fileURL, status, reviewer, note string
+		var reviewedAt sql.NullTime
+		var createdAt time.Time
+
+		err := rows.Scan(&id, &studentID, &nim, &name, &courseID, &courseName, &pertemuanKe,
+			&jenis, &alasan, &fileURL, &status, &reviewer, &note, &reviewedAt, &createdAt)
+		if err != nil {
+			continue
+		}
+
+		excuse := gin.H{
+			"id":           id,
+			"student_id":   studentID,
+			"nim":          nim,
+			"student_name": name,
+			"course_id":    courseID,
+			"course_name":  courseName,
+			"pertemuan_ke": pertemuanKe,
+			"jenis":        jenis,
+			"jenis_label":  getStatusLabel(jenis),
+			"alasan":       alasan,
+			"file_url":     fileURL,
+			"status":       status,
+			"reviewed_by":  reviewer,
+			"review_note":  note,
+			"created_at":   createdAt.Format("2006-01-02 15:04:05"),
+		}
+		if reviewedAt.Valid {
+			excuse["reviewed_at"] = reviewedAt.Time.Format("2006-01-02 15:04:05")
+		}
+		excuses = append(excuses, excuse)
+	}
+
+	return excuses, nil
+}
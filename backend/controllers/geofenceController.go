@@ -0,0 +1,347 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultGeofenceMaxAccuracy - akurasi GPS terburuk (meter) yang masih diterima
+const defaultGeofenceMaxAccuracy = 100.0
+
+// defaultGeofenceTolerance - kelonggaran jarak (meter) di luar radius ruangan, ditetapkan server
+const defaultGeofenceTolerance = 15.0
+
+// geofenceLocation - lokasi perangkat yang dikirim saat scan absensi
+type geofenceLocation struct {
+	Latitude  *float64
+	Longitude *float64
+	Accuracy  *float64
+}
+
+// getGeofenceMaxAccuracy membaca batas akurasi GPS dari env GEOFENCE_MAX_ACCURACY
+func getGeofenceMaxAccuracy() float64 {
+	if v := os.Getenv("GEOFENCE_MAX_ACCURACY"); v != "" {
+		if acc, err := strconv.ParseFloat(v, 64); err == nil && acc > 0 {
+			return acc
+		}
+	}
+	return defaultGeofenceMaxAccuracy
+}
+
+// getGeofenceTolerance membaca kelonggaran jarak dari env GEOFENCE_TOLERANCE
+func getGeofenceTolerance() float64 {
+	if v := os.Getenv("GEOFENCE_TOLERANCE"); v != "" {
+		if t, err := strconv.ParseFloat(v, 64); err == nil && t >= 0 {
+			return t
+		}
+	}
+	return defaultGeofenceTolerance
+}
+
+// getCourseRoom - Kode ruangan mata kuliah dari tabel schedule
+func getCourseRoom(courseID string) string {
+	var ruangan sql.NullString
+	config.DB.QueryRow(`
+		SELECT ruangan FROM schedule
+		WHERE mata_kuliah_kode = ? AND ruangan IS NOT NULL AND ruangan <> ''
+		ORDER BY id
+		LIMIT 1
+	`, courseID).Scan(&ruangan)
+	return ruangan.String
+}
+
+// checkGeofence - Validasi lokasi scan terhadap geofence ruangan.
+// Mengembalikan pesan error kosong jika scan diterima; penolakan dicatat ke attendance_geofence_logs.
+func checkGeofence(sessionID, studentID int, courseID, roomCode string, loc geofenceLocation) string {
+	var enabled bool
+	if err := config.DB.QueryRow("SELECT geofence_enabled FROM mata_kuliah WHERE kode = ?", courseID).Scan(&enabled); err != nil || !enabled {
+		return ""
+	}
+
+	if roomCode == "" {
+		// Geofence aktif tapi ruangan belum diatur: jangan blokir mahasiswa
+		return ""
+	}
+
+	var roomLat, roomLon float64
+	var radius int
+	err := config.DB.QueryRow(`
+		SELECT latitude, longitude, radius_meter FROM ruangan WHERE kode = ?
+	`, roomCode).Scan(&roomLat, &roomLon, &radius)
+	if err != nil {
+		return ""
+	}
+
+	if loc.Latitude == nil || loc.Longitude == nil {
+		reason := "Lokasi perangkat diperlukan untuk absensi mata kuliah ini"
+		logGeofenceRejection(sessionID, studentID, roomCode, loc, nil, reason)
+		return reason
+	}
+
+	accuracy := 0.0
+	if loc.Accuracy != nil {
+		accuracy = *loc.Accuracy
+	}
+
+	if accuracy > getGeofenceMaxAccuracy() {
+		reason := fmt.Sprintf("Akurasi GPS terlalu rendah (%.0f m). Aktifkan lokasi presisi lalu coba lagi", accuracy)
+		logGeofenceRejection(sessionID, studentID, roomCode, loc, nil, reason)
+		return reason
+	}
+
+	distance := utils.HaversineMeter(*loc.Latitude, *loc.Longitude, roomLat, roomLon)
+
+	// Akurasi dari perangkat hanya dipakai untuk menolak fix yang buruk (di atas); kelonggaran jarak
+	// ditentukan server agar klien tidak bisa memperlebar area dengan melaporkan akurasi besar
+	if distance > float64(radius)+getGeofenceTolerance() {
+		reason := fmt.Sprintf("Anda berada di luar area ruangan %s (jarak %.0f m, batas %d m)", roomCode, distance, radius)
+		logGeofenceRejection(sessionID, studentID, roomCode, loc, &distance, reason)
+		return reason
+	}
+
+	return ""
+}
+
+// logGeofenceRejection - Simpan percobaan scan yang ditolak agar bisa ditinjau dosen
+func logGeofenceRejection(sessionID, studentID int, roomCode string, loc geofenceLocation, distance *float64, reason string) {
+	_, err := config.DB.Exec(`
+		INSERT INTO attendance_geofence_logs
+		(session_id, student_id, ruangan_kode, latitude, longitude, accuracy_meter, distance_meter, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW())
+	`, sessionID, studentID, roomCode, loc.Latitude, loc.Longitude, loc.Accuracy, distance, reason)
+	if err != nil {
+		fmt.Printf("Warning: Gagal mencatat penolakan geofence: %v\n", err)
+	}
+}
+
+// SetCourseGeofence - Aktif/nonaktifkan geofence absensi untuk mata kuliah (dosen)
+func SetCourseGeofence(c *gin.Context) {
+	courseID := c.Param("course_id")
+
+	var input struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: enabled required")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var dosenID int
+	if err := config.DB.QueryRow("SELECT id FROM dosen WHERE user_id = ?", userID).Scan(&dosenID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Dosen not found")
+		return
+	}
+
+	result, err := config.DB.Exec(`
+		UPDATE mata_kuliah SET geofence_enabled = ?, updated_at = NOW()
+		WHERE kode = ? AND dosen_id = ?
+	`, *input.Enabled, courseID, dosenID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengubah geofence: "+err.Error())
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		var owns bool
+		config.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM mata_kuliah WHERE kode = ? AND dosen_id = ?)", courseID, dosenID).Scan(&owns)
+		if !owns {
+			utils.ErrorResponse(c, http.StatusForbidden, "Anda tidak mengampu mata kuliah ini")
+			return
+		}
+	}
+
+	roomCode := getCourseRoom(courseID)
+	var roomConfigured bool
+	if roomCode != "" {
+		config.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM ruangan WHERE kode = ?)", roomCode).Scan(&roomConfigured)
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"course_id":       courseID,
+		"enabled":         *input.Enabled,
+		"ruangan":         roomCode,
+		"room_configured": roomConfigured,
+	}, "Pengaturan geofence berhasil disimpan")
+}
+
+// GetGeofenceRejections - Daftar scan yang ditolak geofence untuk ditinjau dosen
+func GetGeofenceRejections(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var dosenID int
+	if err := config.DB.QueryRow("SELECT id FROM dosen WHERE user_id = ?", userID).Scan(&dosenID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Dosen not found")
+		return
+	}
+
+	query := `
+		SELECT
+			gl.id, gl.session_id, asess.course_id, mk.nama, asess.pertemuan_ke,
+			m.id, m.nim, m.name,
+			COALESCE(gl.ruangan_kode, ''), gl.latitude, gl.longitude,
+			gl.accuracy_meter, gl.distance_meter, gl.reason, gl.created_at
+		FROM attendance_geofence_logs gl
+		JOIN attendance_sessions asess ON gl.session_id = asess.id
+		JOIN mata_kuliah mk ON asess.course_id = mk.kode
+		JOIN mahasiswa m ON gl.student_id = m.id
+		WHERE asess.dosen_id = ?
+	`
+	args := []interface{}{dosenID}
+
+	if courseID := c.Query("course_id"); courseID != "" {
+		query += " AND asess.course_id = ?"
+		args = append(args, courseID)
+	}
+
+	if sessionID := c.Query("session_id"); sessionID != "" {
+		query += " AND gl.session_id = ?"
+		args = append(args, sessionID)
+	}
+
+	query += " ORDER BY gl.created_at DESC LIMIT 200"
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil log geofence: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	var logs []gin.H
+	for rows.Next() {
+		var id, sessionID, pertemuanKe, studentID int
+		var courseID, courseName, nim, name, roomCode, reason string
+		var lat, lon, accuracy, distance sql.NullFloat64
+		var createdAt time.Time
+
+		err := rows.Scan(&id, &sessionID, &courseID, &courseName, &pertemuanKe,
+			&studentID, &nim, &name, &roomCode, &lat, &lon, &accuracy, &distance, &reason, &createdAt)
+		if err != nil {
+			continue
+		}
+
+		logs = append(logs, gin.H{
+			"id":             id,
+			"session_id":     sessionID,
+			"course_id":      courseID,
+			"course_name":    courseName,
+			"pertemuan_ke":   pertemuanKe,
+			"student_id":     studentID,
+			"nim":            nim,
+			"name":           name,
+			"ruangan":        roomCode,
+			"latitude":       lat.Float64,
+			"longitude":      lon.Float64,
+			"has_location":   lat.Valid && lon.Valid,
+			"accuracy_meter": accuracy.Float64,
+			"distance_meter": distance.Float64,
+			"reason":         reason,
+			"created_at":     createdAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"logs":  logs,
+		"total": len(logs),
+	}, "Log penolakan geofence berhasil diambil")
+}
+
+// GetRuangan - Daftar ruangan beserta koordinat geofence (admin)
+func GetRuangan(c *gin.Context) {
+	rows, err := config.DB.Query(`
+		SELECT kode, nama, latitude, longitude, radius_meter, updated_at
+		FROM ruangan
+		ORDER BY kode
+	`)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil data ruangan: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	var ruangan []gin.H
+	for rows.Next() {
+		var kode, nama string
+		var lat, lon float64
+		var radius int
+		var updatedAt time.Time
+
+		if err := rows.Scan(&kode, &nama, &lat, &lon, &radius, &updatedAt); err != nil {
+			continue
+		}
+
+		ruangan = append(ruangan, gin.H{
+			"kode":         kode,
+			"nama":         nama,
+			"latitude":     lat,
+			"longitude":    lon,
+			"radius_meter": radius,
+			"updated_at":   updatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	utils.SuccessResponse(c, ruangan, "Data ruangan berhasil diambil")
+}
+
+// SaveRuangan - Tambah atau ubah koordinat dan radius ruangan (admin)
+func SaveRuangan(c *gin.Context) {
+	var input struct {
+		Kode        string   `json:"kode" binding:"required"`
+		Nama        string   `json:"nama" binding:"required"`
+		Latitude    *float64 `json:"latitude" binding:"required,gte=-90,lte=90"`
+		Longitude   *float64 `json:"longitude" binding:"required,gte=-180,lte=180"`
+		RadiusMeter int      `json:"radius_meter" binding:"omitempty,min=5,max=1000"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	if input.RadiusMeter == 0 {
+		input.RadiusMeter = 50
+	}
+
+	_, err := config.DB.Exec(`
+		INSERT INTO ruangan (kode, nama, latitude, longitude, radius_meter, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, NOW(), NOW())
+		ON DUPLICATE KEY UPDATE
+			nama = VALUES(nama),
+			latitude = VALUES(latitude),
+			longitude = VALUES(longitude),
+			radius_meter = VALUES(radius_meter),
+			updated_at = NOW()
+	`, input.Kode, input.Nama, *input.Latitude, *input.Longitude, input.RadiusMeter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan ruangan: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"kode":         input.Kode,
+		"nama":         input.Nama,
+		"latitude":     *input.Latitude,
+		"longitude":    *input.Longitude,
+		"radius_meter": input.RadiusMeter,
+	}, "Ruangan berhasil disimpan")
+}
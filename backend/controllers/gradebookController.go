@@ -0,0 +1,603 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bobot komponen nilai default (persen) jika mata kuliah belum mengatur sendiri
+const (
+	defaultBobotTugas     = 25.0
+	defaultBobotKuis      = 10.0
+	defaultBobotUTS       = 25.0
+	defaultBobotUAS       = 30.0
+	defaultBobotKehadiran = 10.0
+)
+
+// gradeWeights - bobot komponen nilai mata kuliah beserta status kunci
+type gradeWeights struct {
+	Tugas     float64
+	Kuis      float64
+	UTS       float64
+	UAS       float64
+	Kehadiran float64
+	LockedAt  sql.NullTime
+	Source    string // "course" atau "default"
+}
+
+// gradebookRow - nilai per komponen dan nilai akhir satu mahasiswa
+type gradebookRow struct {
+	StudentID    int
+	NIM          string
+	Name         string
+	Tugas        float64
+	Kuis         float64
+	UTS          float64
+	UAS          float64
+	Kehadiran    float64
+	NilaiAkhir   float64
+	Huruf        string
+	TugasDinilai int
+}
+
+// loadGradeWeights - Bobot komponen nilai mata kuliah (default jika belum diatur)
+func loadGradeWeights(courseID string) gradeWeights {
+	w := gradeWeights{Source: "course"}
+	var lockedSemesterID sql.NullInt64
+	err := config.DB.QueryRow(`
+		SELECT bobot_tugas, bobot_kuis, bobot_uts, bobot_uas, bobot_kehadiran, locked_at, locked_semester_id
+		FROM komponen_nilai
+		WHERE course_id = ?
+	`, courseID).Scan(&w.Tugas, &w.Kuis, &w.UTS, &w.UAS, &w.Kehadiran, &w.LockedAt, &lockedSemesterID)
+	if err != nil {
+		return gradeWeights{
+			Tugas:     defaultBobotTugas,
+			Kuis:      defaultBobotKuis,
+			UTS:       defaultBobotUTS,
+			UAS:       defaultBobotUAS,
+			Kehadiran: defaultBobotKehadiran,
+			Source:    "default",
+		}
+	}
+
+	// Kunci berlaku per semester: kunci semester lalu tidak mengunci semester aktif
+	if w.LockedAt.Valid && lockedSemesterID != currentSemesterID() {
+		w.LockedAt = sql.NullTime{}
+	}
+	return w
+}
+
+func (w gradeWeights) locked() bool {
+	return w.LockedAt.Valid
+}
+
+func (w gradeWeights) toResponse() gin.H {
+	response := gin.H{
+		"bobot_tugas":     w.Tugas,
+		"bobot_kuis":      w.Kuis,
+		"bobot_uts":       w.UTS,
+		"bobot_uas":       w.UAS,
+		"bobot_kehadiran": w.Kehadiran,
+		"source":          w.Source,
+		"locked":          w.locked(),
+	}
+	if w.locked() {
+		response["locked_at"] = w.LockedAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return response
+}
+
+// isGradebookLocked - Apakah nilai mata kuliah semester aktif sudah dikunci dosen
+func isGradebookLocked(courseID string) bool {
+	return loadGradeWeights(courseID).locked()
+}
+
+// gradeLetter - Konversi nilai angka ke huruf (A >= 85, B >= 70, C >= 55, D >= 40, selain itu E)
+func gradeLetter(score float64) string {
+	switch {
+	case score >= 85:
+		return "A"
+	case score >= 70:
+		return "B"
+	case score >= 55:
+		return "C"
+	case score >= 40:
+		return "D"
+	default:
+		return "E"
+	}
+}
+
+func roundScore(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// semesterFilter - kondisi SQL agar data gradebook hanya dari semester aktif. Baris lama tanpa
+// semester_id tetap ikut, dan tanpa semester aktif tidak ada penyaringan. Pasangkan dengan semesterArgs.
+func semesterFilter(column string) string {
+	return "(? IS NULL OR " + column + " IS NULL OR " + column + " = ?)"
+}
+
+// semesterArgs - argumen untuk semesterFilter
+func semesterArgs(semesterID sql.NullInt64) []interface{} {
+	v := nullInt(semesterID)
+	return []interface{}{v, v}
+}
+
+// computeGradebook - Hitung nilai komponen dan nilai akhir seluruh mahasiswa terdaftar pada semester aktif.
+// Tugas: rata-rata nilai tertinggi per tugas (tidak mengumpulkan = 0).
+// Kuis/UTS/UAS: rata-rata kuis online yang sudah ditutup per kategori, ditimpa nilai_ujian jika dosen menginput nilai manual.
+// Kehadiran: persentase hadir/terlambat dari sesi yang sudah ditutup.
+func computeGradebook(courseID string, w gradeWeights) ([]gradebookRow, error) {
+	semester := semesterArgs(currentSemesterID())
+
+	studentRows, err := config.DB.Query(`
+		SELECT DISTINCT m.id, m.nim, m.name
+		FROM mahasiswa_mata_kuliah mmk
+		JOIN mahasiswa m ON mmk.mahasiswa_id = m.id
+		WHERE mmk.mata_kuliah_kode = ? AND `+semesterFilter("mmk.semester_id")+`
+		ORDER BY m.nim
+	`, append([]interface{}{courseID}, semester...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []gradebookRow
+	index := map[int]int{}
+	for studentRows.Next() {
+		var r gradebookRow
+		if studentRows.Scan(&r.StudentID, &r.NIM, &r.Name) == nil {
+			index[r.StudentID] = len(rows)
+			rows = append(rows, r)
+		}
+	}
+	studentRows.Close()
+
+	var totalTugas int
+	config.DB.QueryRow(`
+		SELECT COUNT(*) FROM tugas
+		WHERE course_id = ? AND deleted_at IS NULL AND COALESCE(type, 'tugas') = 'tugas'
+			AND `+semesterFilter("semester_id")+`
+	`, append([]interface{}{courseID}, semester...)...).Scan(&totalTugas)
+
+	if totalTugas > 0 {
+		// Nilai terbaik per tugas setelah potongan kebijakan keterlambatan
+		gradeRows, err := config.DB.Query(`
+			SELECT best.student_id, SUM(best.grade), COUNT(*)
+			FROM (
+				SELECT s.student_id, s.task_id, MAX(s.grade * (100 - s.late_penalty) / 100) AS grade
+				FROM submissions s
+				JOIN tugas t ON s.task_id = t.id
+				WHERE t.course_id = ? AND t.deleted_at IS NULL AND COALESCE(t.type, 'tugas') = 'tugas'
+					AND `+semesterFilter("t.semester_id")+`
+					AND s.deleted_at IS NULL AND s.grade IS NOT NULL
+				GROUP BY s.student_id, s.task_id
+			) best
+			GROUP BY best.student_id
+		`, append([]interface{}{courseID}, semester...)...)
+		if err != nil {
+			return nil, err
+		}
+		for gradeRows.Next() {
+			var studentID, graded int
+			var sum float64
+			if gradeRows.Scan(&studentID, &sum, &graded) != nil {
+				continue
+			}
+			if i, ok := index[studentID]; ok {
+				rows[i].Tugas = sum / float64(totalTugas)
+				rows[i].TugasDinilai = graded
+			}
+		}
+		gradeRows.Close()
+	}
+
+	quizScores, err := courseQuizScores(courseID)
+	if err != nil {
+		return nil, err
+	}
+	for studentID, scores := range quizScores {
+		if i, ok := index[studentID]; ok {
+			rows[i].Kuis = scores["kuis"]
+			rows[i].UTS = scores["uts"]
+			rows[i].UAS = scores["uas"]
+		}
+	}
+
+	examRows, err := config.DB.Query(`
+		SELECT student_id, jenis, nilai FROM nilai_ujian WHERE course_id = ? AND `+semesterFilter("semester_id")+`
+	`, append([]interface{}{courseID}, semester...)...)
+	if err != nil {
+		return nil, err
+	}
+	for examRows.Next() {
+		var studentID int
+		var jenis string
+		var nilai float64
+		if examRows.Scan(&studentID, &jenis, &nilai) != nil {
+			continue
+		}
+		i, ok := index[studentID]
+		if !ok {
+			continue
+		}
+		switch jenis {
+		case "kuis":
+			rows[i].Kuis = nilai
+		case "uts":
+			rows[i].UTS = nilai
+		case "uas":
+			rows[i].UAS = nilai
+		}
+	}
+	examRows.Close()
+
+	held, ratios, err := courseAttendanceRatios(courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		r := &rows[i]
+		if held == 0 {
+			// Belum ada pertemuan yang ditutup: komponen kehadiran belum mengurangi nilai
+			r.Kehadiran = 100
+		} else {
+			r.Kehadiran = ratios[r.StudentID]
+		}
+
+		r.Tugas = roundScore(r.Tugas)
+		r.Kehadiran = roundScore(r.Kehadiran)
+		r.NilaiAkhir = roundScore((r.Tugas*w.Tugas + r.Kuis*w.Kuis + r.UTS*w.UTS +
+			r.UAS*w.UAS + r.Kehadiran*w.Kehadiran) / 100)
+		r.Huruf = gradeLetter(r.NilaiAkhir)
+	}
+
+	return rows, nil
+}
+
+// loadLockedGradebook - Nilai akhir semester aktif yang tersimpan saat gradebook dikunci
+func loadLockedGradebook(courseID string) ([]gradebookRow, error) {
+	dbRows, err := config.DB.Query(`
+		SELECT na.student_id, m.nim, m.name, na.nilai_tugas, na.nilai_kuis, na.nilai_uts,
+		       na.nilai_uas, na.nilai_kehadiran, na.nilai_akhir, na.huruf
+		FROM nilai_akhir na
+		JOIN mahasiswa m ON na.student_id = m.id
+		WHERE na.course_id = ? AND `+semesterFilter("na.semester_id")+`
+		ORDER BY m.nim
+	`, append([]interface{}{courseID}, semesterArgs(currentSemesterID())...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer dbRows.Close()
+
+	var rows []gradebookRow
+	for dbRows.Next() {
+		var r gradebookRow
+		err := dbRows.Scan(&r.StudentID, &r.NIM, &r.Name, &r.Tugas, &r.Kuis, &r.UTS,
+			&r.UAS, &r.Kehadiran, &r.NilaiAkhir, &r.Huruf)
+		if err == nil {
+			rows = append(rows, r)
+		}
+	}
+	return rows, nil
+}
+
+func (r gradebookRow) toResponse() gin.H {
+	return gin.H{
+		"student_id":      r.StudentID,
+		"nim":             r.NIM,
+		"name":            r.Name,
+		"nilai_tugas":     r.Tugas,
+		"nilai_kuis":      r.Kuis,
+		"nilai_uts":       r.UTS,
+		"nilai_uas":       r.UAS,
+		"nilai_kehadiran": r.Kehadiran,
+		"nilai_akhir":     r.NilaiAkhir,
+		"huruf":           r.Huruf,
+	}
+}
+
+// GetGradeComponents - Bobot komponen nilai mata kuliah
+func GetGradeComponents(c *gin.Context) {
+	courseID := c.Param("course_id")
+	if _, ok := getDosenCourseAccess(c, courseID); !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, loadGradeWeights(courseID).toResponse(), "Komponen nilai berhasil diambil")
+}
+
+// UpdateGradeComponents - Atur bobot komponen nilai (total harus 100%)
+func UpdateGradeComponents(c *gin.Context) {
+	courseID := c.Param("course_id")
+
+	var input struct {
+		Tugas     float64 `json:"bobot_tugas" binding:"gte=0,lte=100"`
+		Kuis      float64 `json:"bobot_kuis" binding:"gte=0,lte=100"`
+		UTS       float64 `json:"bobot_uts" binding:"gte=0,lte=100"`
+		UAS       float64 `json:"bobot_uas" binding:"gte=0,lte=100"`
+		Kehadiran float64 `json:"bobot_kehadiran" binding:"gte=0,lte=100"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	total := input.Tugas + input.Kuis + input.UTS + input.UAS + input.Kehadiran
+	if math.Abs(total-100) > 0.001 {
+		utils.ValidationError(c, fmt.Sprintf("Total bobot harus 100%% (sekarang %.2f%%)", total))
+		return
+	}
+
+	if _, ok := getDosenCourseAccess(c, courseID); !ok {
+		return
+	}
+
+	if isGradebookLocked(courseID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Nilai mata kuliah sudah dikunci")
+		return
+	}
+
+	_, err := config.DB.Exec(`
+		INSERT INTO komponen_nilai
+		(course_id, bobot_tugas, bobot_kuis, bobot_uts, bobot_uas, bobot_kehadiran, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW(), NOW())
+		ON DUPLICATE KEY UPDATE
+			bobot_tugas = VALUES(bobot_tugas),
+			bobot_kuis = VALUES(bobot_kuis),
+			bobot_uts = VALUES(bobot_uts),
+			bobot_uas = VALUES(bobot_uas),
+			bobot_kehadiran = VALUES(bobot_kehadiran),
+			updated_at = NOW()
+	`, courseID, input.Tugas, input.Kuis, input.UTS, input.UAS, input.Kehadiran)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan komponen nilai: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, loadGradeWeights(courseID).toResponse(), "Komponen nilai berhasil disimpan")
+}
+
+// SaveExamScores - Input nilai kuis, UTS atau UAS untuk beberapa mahasiswa sekaligus
+func SaveExamScores(c *gin.Context) {
+	courseID := c.Param("course_id")
+
+	var input struct {
+		Jenis  string `json:"jenis" binding:"required,oneof=kuis uts uas"`
+		Scores []struct {
+			StudentID  int     `json:"student_id" binding:"required"`
+			Nilai      float64 `json:"nilai" binding:"gte=0,lte=100"`
+			Keterangan string  `json:"keterangan"`
+		} `json:"scores" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	if _, ok := getDosenCourseAccess(c, courseID); !ok {
+		return
+	}
+
+	if isGradebookLocked(courseID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Nilai mata kuliah sudah dikunci")
+		return
+	}
+
+	saved := 0
+	var skipped []int
+	for _, score := range input.Scores {
+		var enrolled bool
+		config.DB.QueryRow(`
+			SELECT EXISTS(
+				SELECT 1 FROM mahasiswa_mata_kuliah
+				WHERE mahasiswa_id = ? AND mata_kuliah_kode = ?
+			)
+		`, score.StudentID, courseID).Scan(&enrolled)
+		if !enrolled {
+			skipped = append(skipped, score.StudentID)
+			continue
+		}
+
+		_, err := config.DB.Exec(`
+			INSERT INTO nilai_ujian (course_id, student_id, jenis, nilai, keterangan, semester_id, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, NOW(), NOW())
+			ON DUPLICATE KEY UPDATE
+				nilai = VALUES(nilai),
+				keterangan = VALUES(keterangan),
+				semester_id = VALUES(semester_id),
+				updated_at = NOW()
+		`, courseID, score.StudentID, input.Jenis, score.Nilai, score.Keterangan, currentSemesterID())
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan nilai: "+err.Error())
+			return
+		}
+		saved++
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"course_id": courseID,
+		"jenis":     input.Jenis,
+		"saved":     saved,
+		"skipped":   skipped,
+	}, fmt.Sprintf("%d nilai %s berhasil disimpan", saved, input.Jenis))
+}
+
+// GetGradebook - Rekap nilai komponen dan nilai akhir mahasiswa pada mata kuliah.
+// Setelah dikunci, yang ditampilkan adalah nilai yang tersimpan saat penguncian.
+func GetGradebook(c *gin.Context) {
+	courseID := c.Param("course_id")
+	if _, ok := getDosenCourseAccess(c, courseID); !ok {
+		return
+	}
+
+	weights := loadGradeWeights(courseID)
+
+	var rows []gradebookRow
+	var err error
+	if weights.locked() {
+		rows, err = loadLockedGradebook(courseID)
+	} else {
+		rows, err = computeGradebook(courseID, weights)
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghitung nilai: "+err.Error())
+		return
+	}
+
+	var courseName string
+	var sks int
+	config.DB.QueryRow("SELECT nama, sks FROM mata_kuliah WHERE kode = ?", courseID).Scan(&courseName, &sks)
+
+	students := []gin.H{}
+	distribution := gin.H{"A": 0, "B": 0, "C": 0, "D": 0, "E": 0}
+	var total float64
+	for _, r := range rows {
+		students = append(students, r.toResponse())
+		distribution[r.Huruf] = distribution[r.Huruf].(int) + 1
+		total += r.NilaiAkhir
+	}
+
+	var average float64
+	if len(rows) > 0 {
+		average = roundScore(total / float64(len(rows)))
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"course_id":    courseID,
+		"course_name":  courseName,
+		"sks":          sks,
+		"komponen":     weights.toResponse(),
+		"students":     students,
+		"total":        len(students),
+		"average":      average,
+		"distribution": distribution,
+		"locked":       weights.locked(),
+	}, "Gradebook berhasil diambil")
+}
+
+// LockGradebook - Kunci nilai: simpan nilai akhir mahasiswa semester aktif ke nilai_akhir dan tolak perubahan berikutnya.
+// Nilai akhir angkatan sebelumnya tidak disentuh.
+func LockGradebook(c *gin.Context) {
+	courseID := c.Param("course_id")
+	if _, ok := getDosenCourseAccess(c, courseID); !ok {
+		return
+	}
+
+	weights := loadGradeWeights(courseID)
+	if weights.locked() {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Nilai mata kuliah sudah dikunci")
+		return
+	}
+
+	if pending := pendingQuizGrading(courseID); pending > 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest,
+			fmt.Sprintf("Masih ada %d percobaan kuis dengan esai yang belum dinilai", pending))
+		return
+	}
+
+	rows, err := computeGradebook(courseID, weights)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghitung nilai: "+err.Error())
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Transaction error")
+		return
+	}
+	defer tx.Rollback()
+
+	semesterID := currentSemesterID()
+	for _, r := range rows {
+		_, err := tx.Exec(`
+			INSERT INTO nilai_akhir
+			(course_id, student_id, semester_id, nilai_tugas, nilai_kuis, nilai_uts, nilai_uas, nilai_kehadiran,
+			 nilai_akhir, huruf, status, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'final', NOW(), NOW())
+			ON DUPLICATE KEY UPDATE
+				nilai_tugas = VALUES(nilai_tugas),
+				nilai_kuis = VALUES(nilai_kuis),
+				nilai_uts = VALUES(nilai_uts),
+				nilai_uas = VALUES(nilai_uas),
+				nilai_kehadiran = VALUES(nilai_kehadiran),
+				nilai_akhir = VALUES(nilai_akhir),
+				huruf = VALUES(huruf),
+				status = 'final',
+				updated_at = NOW()
+		`, courseID, r.StudentID, semesterID, r.Tugas, r.Kuis, r.UTS, r.UAS, r.Kehadiran, r.NilaiAkhir, r.Huruf)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengunci nilai: "+err.Error())
+			return
+		}
+	}
+
+	// Bobot default ikut disimpan agar nilai yang dikunci tetap bisa ditelusuri
+	_, err = tx.Exec(`
+		INSERT INTO komponen_nilai
+		(course_id, bobot_tugas, bobot_kuis, bobot_uts, bobot_uas, bobot_kehadiran,
+		 locked_at, locked_by, locked_semester_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW(), ?, ?, NOW(), NOW())
+		ON DUPLICATE KEY UPDATE locked_at = NOW(), locked_by = VALUES(locked_by),
+			locked_semester_id = VALUES(locked_semester_id), updated_at = NOW()
+	`, courseID, weights.Tugas, weights.Kuis, weights.UTS, weights.UAS, weights.Kehadiran, c.GetInt("user_id"), nullInt(semesterID))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengunci nilai: "+err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Commit failed")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"course_id": courseID,
+		"total":     len(rows),
+		"komponen":  loadGradeWeights(courseID).toResponse(),
+	}, fmt.Sprintf("Nilai %d mahasiswa berhasil dikunci", len(rows)))
+}
+
+// UnlockGradebook - Buka kunci nilai mata kuliah semester aktif (admin) agar dosen bisa merevisi
+func UnlockGradebook(c *gin.Context) {
+	courseID := c.Param("course_id")
+
+	result, err := config.DB.Exec(`
+		UPDATE komponen_nilai SET locked_at = NULL, locked_by = NULL, locked_semester_id = NULL, updated_at = NOW()
+		WHERE course_id = ? AND locked_at IS NOT NULL AND locked_semester_id <=> ?
+	`, courseID, nullInt(currentSemesterID()))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuka kunci nilai: "+err.Error())
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Nilai mata kuliah belum dikunci")
+		return
+	}
+
+	// Nilai akhir tetap tampil di KHS/transkrip dengan status revisi sampai dikunci ulang
+	_, err = config.DB.Exec(`
+		UPDATE nilai_akhir SET status = 'revisi', updated_at = NOW()
+		WHERE course_id = ? AND `+semesterFilter("semester_id")+`
+	`, append([]interface{}{courseID}, semesterArgs(currentSemesterID())...)...)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuka kunci nilai: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"course_id": courseID,
+		"komponen":  loadGradeWeights(courseID).toResponse(),
+	}, "Kunci nilai berhasil dibuka")
+}
@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// khsSemester - kartu hasil studi satu semester
+type khsSemester struct {
+	Semester int
+	Courses  []gin.H
+	SKS      int
+	SKSLulus int
+	Mutu     float64
+	IPS      float64
+	IPK      float64 // IPK kumulatif sampai semester ini
+}
+
+// gradePoint - Bobot mutu huruf (A=4, B=3, C=2, D=1, E=0)
+func gradePoint(huruf string) float64 {
+	switch huruf {
+	case "A":
+		return 4
+	case "B":
+		return 3
+	case "C":
+		return 2
+	case "D":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// gradeIndex - IPS/IPK dari total mutu dan SKS, dibulatkan dua desimal
+func gradeIndex(mutu float64, sks int) float64 {
+	if sks == 0 {
+		return 0
+	}
+	return math.Round(mutu/float64(sks)*100) / 100
+}
+
+// buildKHS - KHS per semester dari nilai akhir yang sudah dikunci dosen.
+// Mata kuliah yang nilainya belum dikunci belum masuk perhitungan IPS/IPK; nilai yang kuncinya
+// sedang dibuka untuk revisi tetap dihitung dengan nilai terakhir yang dikunci. Mata kuliah yang
+// diulang hanya dihitung dengan nilai semester terakhir; nilai sebelumnya ditandai diulang.
+func buildKHS(studentID int) ([]khsSemester, error) {
+	rows, err := config.DB.Query(`
+		SELECT mk.semester, mk.kode, mk.nama, mk.sks, COALESCE(d.name, ''),
+		       na.nilai_akhir, na.huruf, na.status
+		FROM nilai_akhir na
+		JOIN mata_kuliah mk ON na.course_id = mk.kode
+		LEFT JOIN dosen d ON mk.dosen_id = d.id
+		WHERE na.student_id = ?
+		ORDER BY mk.semester, mk.kode, na.semester_id IS NOT NULL, na.semester_id
+	`, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var semesters []khsSemester
+	taken := map[string]gin.H{}
+	for rows.Next() {
+		var semester, sks int
+		var kode, nama, dosen, huruf, status string
+		var nilai float64
+		if rows.Scan(&semester, &kode, &nama, &sks, &dosen, &nilai, &huruf, &status) != nil {
+			continue
+		}
+
+		if len(semesters) == 0 || semesters[len(semesters)-1].Semester != semester {
+			semesters = append(semesters, khsSemester{Semester: semester, Courses: []gin.H{}})
+		}
+		s := &semesters[len(semesters)-1]
+
+		// Pengambilan sebelumnya keluar dari perhitungan, diganti nilai yang lebih baru
+		if prev, ok := taken[kode]; ok {
+			prev["diulang"] = true
+			s.SKS -= sks
+			s.Mutu -= prev["mutu"].(float64)
+			if prev["huruf"] != "E" {
+				s.SKSLulus -= sks
+			}
+		}
+
+		point := gradePoint(huruf)
+		course := gin.H{
+			"kode":        kode,
+			"nama":        nama,
+			"dosen":       dosen,
+			"sks":         sks,
+			"nilai_akhir": nilai,
+			"huruf":       huruf,
+			"bobot":       point,
+			"mutu":        point * float64(sks),
+			"status":      status,
+			"diulang":     false,
+		}
+		taken[kode] = course
+		s.Courses = append(s.Courses, course)
+		s.SKS += sks
+		s.Mutu += point * float64(sks)
+		if huruf != "E" {
+			s.SKSLulus += sks
+		}
+	}
+
+	var totalMutu float64
+	var totalSKS int
+	for i := range semesters {
+		s := &semesters[i]
+		s.IPS = gradeIndex(s.Mutu, s.SKS)
+		totalMutu += s.Mutu
+		totalSKS += s.SKS
+		s.IPK = gradeIndex(totalMutu, totalSKS)
+	}
+
+	return semesters, nil
+}
+
+func (s khsSemester) toResponse() gin.H {
+	return gin.H{
+		"semester":   s.Semester,
+		"courses":    s.Courses,
+		"total_sks":  s.SKS,
+		"sks_lulus":  s.SKSLulus,
+		"total_mutu": s.Mutu,
+		"ips":        s.IPS,
+		"ipk":        s.IPK,
+	}
+}
+
+// academicSummary - Ringkasan KHS semua semester beserta IPK akhir
+func academicSummary(studentID int) (gin.H, error) {
+	semesters, err := buildKHS(studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	khs := []gin.H{}
+	totalSKS, sksLulus := 0, 0
+	var ipk float64
+	for _, s := range semesters {
+		khs = append(khs, s.toResponse())
+		totalSKS += s.SKS
+		sksLulus += s.SKSLulus
+		ipk = s.IPK
+	}
+
+	return gin.H{
+		"khs":       khs,
+		"total_sks": totalSKS,
+		"sks_lulus": sksLulus,
+		"ipk":       ipk,
+	}, nil
+}
+
+// GetMahasiswaKHS - KHS per semester (opsional ?semester=) beserta IPS dan IPK
+func GetMahasiswaKHS(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var mahasiswaID int
+	if err := config.DB.QueryRow("SELECT id FROM mahasiswa WHERE user_id = ?", userID).Scan(&mahasiswaID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Mahasiswa not found")
+		return
+	}
+
+	semesters, err := buildKHS(mahasiswaID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil KHS: "+err.Error())
+		return
+	}
+
+	if v := c.Query("semester"); v != "" {
+		semester, err := strconv.Atoi(v)
+		if err != nil {
+			utils.ValidationError(c, "Semester tidak valid")
+			return
+		}
+		for _, s := range semesters {
+			if s.Semester == semester {
+				utils.SuccessResponse(c, s.toResponse(), "KHS berhasil diambil")
+				return
+			}
+		}
+		utils.ErrorResponse(c, http.StatusNotFound, "Belum ada nilai final untuk semester "+v)
+		return
+	}
+
+	khs := []gin.H{}
+	var ipk float64
+	for _, s := range semesters {
+		khs = append(khs, s.toResponse())
+		ipk = s.IPK
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"khs": khs,
+		"ipk": ipk,
+	}, "KHS berhasil diambil")
+}
+
+// GetMahasiswaTranskrip - Transkrip seluruh mata kuliah yang sudah bernilai final beserta IPK
+func GetMahasiswaTranskrip(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var mahasiswaID int
+	var nim, name string
+	err := config.DB.QueryRow("SELECT id, nim, name FROM mahasiswa WHERE user_id = ?", userID).Scan(&mahasiswaID, &nim, &name)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Mahasiswa not found")
+		return
+	}
+
+	semesters, err := buildKHS(mahasiswaID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil transkrip: "+err.Error())
+		return
+	}
+
+	courses := []gin.H{}
+	totalSKS, sksLulus := 0, 0
+	var totalMutu, ipk float64
+	for _, s := range semesters {
+		for _, course := range s.Courses {
+			course["semester"] = s.Semester
+			courses = append(courses, course)
+		}
+		totalSKS += s.SKS
+		sksLulus += s.SKSLulus
+		totalMutu += s.Mutu
+		ipk = s.IPK
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"student_id": mahasiswaID,
+		"nim":        nim,
+		"name":       name,
+		"courses":    courses,
+		"total_sks":  totalSKS,
+		"sks_lulus":  sksLulus,
+		"total_mutu": totalMutu,
+		"ipk":        ipk,
+	}, "Transkrip berhasil diambil")
+}
@@ -0,0 +1,994 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultKRSMaxSKS - batas SKS mahasiswa yang belum punya IPS (semester pertama)
+const defaultKRSMaxSKS = 20
+
+// krsSKSCap - Batas SKS yang boleh diambil berdasarkan IPS semester terakhir
+func krsSKSCap(ips float64, hasIPS bool) int {
+	switch {
+	case !hasIPS:
+		return defaultKRSMaxSKS
+	case ips >= 3.00:
+		return 24
+	case ips >= 2.50:
+		return 21
+	case ips >= 2.00:
+		return 18
+	case ips >= 1.50:
+		return 15
+	default:
+		return 12
+	}
+}
+
+// studentSKSCap - IPS terakhir dan batas SKS seorang mahasiswa
+func studentSKSCap(studentID int) (float64, bool, int) {
+	semesters, err := buildKHS(studentID)
+	if err != nil || len(semesters) == 0 {
+		return 0, false, krsSKSCap(0, false)
+	}
+	ips := semesters[len(semesters)-1].IPS
+	return ips, true, krsSKSCap(ips, true)
+}
+
+// krsPeriode - periode pengisian KRS
+type krsPeriode struct {
+	ID             int
+	Nama           string
+	TanggalMulai   time.Time
+	TanggalSelesai time.Time
+	Status         string
+	SemesterID     sql.NullInt64
+}
+
+func (p krsPeriode) isOpen(now time.Time) bool {
+	return p.Status == "open" && !now.Before(p.TanggalMulai) && now.Before(p.TanggalSelesai)
+}
+
+func (p krsPeriode) toResponse() gin.H {
+	return gin.H{
+		"id":              p.ID,
+		"nama":            p.Nama,
+		"tanggal_mulai":   p.TanggalMulai.Format("2006-01-02 15:04:05"),
+		"tanggal_selesai": p.TanggalSelesai.Format("2006-01-02 15:04:05"),
+		"status":          p.Status,
+		"is_open":         p.isOpen(time.Now()),
+	}
+}
+
+// currentKRSPeriode - Periode KRS berstatus open yang paling baru
+func currentKRSPeriode() (krsPeriode, error) {
+	var p krsPeriode
+	err := config.DB.QueryRow(`
+		SELECT id, nama, tanggal_mulai, tanggal_selesai, status, semester_id
+		FROM periode_krs
+		WHERE status = 'open'
+		ORDER BY tanggal_mulai DESC
+		LIMIT 1
+	`).Scan(&p.ID, &p.Nama, &p.TanggalMulai, &p.TanggalSelesai, &p.Status, &p.SemesterID)
+	return p, err
+}
+
+// krsOffering - mata kuliah yang ditawarkan pada periode KRS
+type krsOffering struct {
+	Kode       string
+	Nama       string
+	SKS        int
+	Semester   int
+	Dosen      string
+	Hari       string
+	JamMulai   string
+	JamSelesai string
+}
+
+func (o krsOffering) toResponse() gin.H {
+	return gin.H{
+		"kode":        o.Kode,
+		"nama":        o.Nama,
+		"sks":         o.SKS,
+		"semester":    o.Semester,
+		"dosen":       o.Dosen,
+		"hari":        o.Hari,
+		"jam_mulai":   o.JamMulai,
+		"jam_selesai": o.JamSelesai,
+	}
+}
+
+// loadKRSOfferings - Mata kuliah yang ditawarkan pada periode, diindeks per kode
+func loadKRSOfferings(periodeID int) (map[string]krsOffering, []krsOffering, error) {
+	rows, err := config.DB.Query(`
+		SELECT mk.kode, mk.nama, mk.sks, mk.semester, COALESCE(d.name, ''),
+		       COALESCE(mk.hari, ''), COALESCE(mk.jam_mulai, ''), COALESCE(mk.jam_selesai, '')
+		FROM periode_krs_matkul pm
+		JOIN mata_kuliah mk ON pm.mata_kuliah_kode = mk.kode
+		LEFT JOIN dosen d ON mk.dosen_id = d.id
+		WHERE pm.periode_id = ? AND mk.deleted_at IS NULL
+		ORDER BY mk.semester, mk.kode
+	`, periodeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	byKode := map[string]krsOffering{}
+	var list []krsOffering
+	for rows.Next() {
+		var o krsOffering
+		if rows.Scan(&o.Kode, &o.Nama, &o.SKS, &o.Semester, &o.Dosen, &o.Hari, &o.JamMulai, &o.JamSelesai) != nil {
+			continue
+		}
+		byKode[o.Kode] = o
+		list = append(list, o)
+	}
+	return byKode, list, nil
+}
+
+// krsRecord - KRS mahasiswa pada satu periode
+type krsRecord struct {
+	ID          int
+	PeriodeID   int
+	StudentID   int
+	Status      string // draft, diajukan, disetujui, ditolak
+	TotalSKS    int
+	MaxSKS      int
+	Catatan     sql.NullString
+	SubmittedAt sql.NullTime
+	ReviewedAt  sql.NullTime
+}
+
+const krsSelect = `
+	SELECT id, periode_id, mahasiswa_id, status, total_sks, max_sks, catatan, submitted_at, reviewed_at
+	FROM krs
+`
+
+func scanKRS(row *sql.Row) (krsRecord, error) {
+	var k krsRecord
+	err := row.Scan(&k.ID, &k.PeriodeID, &k.StudentID, &k.Status, &k.TotalSKS, &k.MaxSKS,
+		&k.Catatan, &k.SubmittedAt, &k.ReviewedAt)
+	return k, err
+}
+
+// loadKRSDetail - Mata kuliah yang dipilih pada KRS
+func loadKRSDetail(krsID int) ([]gin.H, []string, error) {
+	rows, err := config.DB.Query(`
+		SELECT kd.mata_kuliah_kode, mk.nama, kd.sks, mk.semester, COALESCE(d.name, ''),
+		       COALESCE(mk.hari, ''), COALESCE(mk.jam_mulai, ''), COALESCE(mk.jam_selesai, '')
+		FROM krs_detail kd
+		JOIN mata_kuliah mk ON kd.mata_kuliah_kode = mk.kode
+		LEFT JOIN dosen d ON mk.dosen_id = d.id
+		WHERE kd.krs_id = ?
+		ORDER BY mk.semester, kd.mata_kuliah_kode
+	`, krsID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	courses := []gin.H{}
+	var kodes []string
+	for rows.Next() {
+		var o krsOffering
+		if rows.Scan(&o.Kode, &o.Nama, &o.SKS, &o.Semester, &o.Dosen, &o.Hari, &o.JamMulai, &o.JamSelesai) != nil {
+			continue
+		}
+		courses = append(courses, o.toResponse())
+		kodes = append(kodes, o.Kode)
+	}
+	return courses, kodes, nil
+}
+
+func (k krsRecord) toResponse(courses []gin.H) gin.H {
+	resp := gin.H{
+		"id":           k.ID,
+		"periode_id":   k.PeriodeID,
+		"mahasiswa_id": k.StudentID,
+		"status":       k.Status,
+		"total_sks":    k.TotalSKS,
+		"max_sks":      k.MaxSKS,
+		"catatan":      k.Catatan.String,
+		"courses":      courses,
+		"submitted_at": nil,
+		"reviewed_at":  nil,
+	}
+	if k.SubmittedAt.Valid {
+		resp["submitted_at"] = k.SubmittedAt.Time.Format("2006-01-02 15:04:05")
+	}
+	if k.ReviewedAt.Valid {
+		resp["reviewed_at"] = k.ReviewedAt.Time.Format("2006-01-02 15:04:05")
+	}
+	return resp
+}
+
+// mahasiswaIDFromContext - id mahasiswa milik user yang login; menulis response error jika tidak ada
+func mahasiswaIDFromContext(c *gin.Context) (int, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return 0, false
+	}
+
+	var mahasiswaID int
+	if err := config.DB.QueryRow("SELECT id FROM mahasiswa WHERE user_id = ?", userID).Scan(&mahasiswaID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Mahasiswa not found")
+		return 0, false
+	}
+	return mahasiswaID, true
+}
+
+// ==================== ADMIN ====================
+
+// krsPeriodeInput - Body request membuat periode KRS
+type krsPeriodeInput struct {
+	Nama           string   `json:"nama" binding:"required"`
+	TanggalMulai   string   `json:"tanggal_mulai" binding:"required"`
+	TanggalSelesai string   `json:"tanggal_selesai" binding:"required"`
+	MataKuliah     []string `json:"mata_kuliah"` // kosong = semua mata kuliah aktif ditawarkan
+	SemesterID     int      `json:"semester_id"` // kosong = semester aktif
+}
+
+// parseKRSDate - Terima "2006-01-02 15:04:05", "2006-01-02T15:04" atau "2006-01-02"
+func parseKRSDate(value string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// CreateKRSPeriode - Admin membuka periode KRS beserta daftar mata kuliah yang ditawarkan
+func CreateKRSPeriode(c *gin.Context) {
+	var input krsPeriodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	mulai, ok1 := parseKRSDate(input.TanggalMulai)
+	selesai, ok2 := parseKRSDate(input.TanggalSelesai)
+	if !ok1 || !ok2 {
+		utils.ValidationError(c, "Format tanggal harus YYYY-MM-DD atau YYYY-MM-DD HH:MM:SS")
+		return
+	}
+	if len(input.TanggalSelesai) == len("2006-01-02") {
+		selesai = selesai.AddDate(0, 0, 1) // tanggal selesai inklusif
+	}
+	if !selesai.After(mulai) {
+		utils.ValidationError(c, "Tanggal selesai harus setelah tanggal mulai")
+		return
+	}
+
+	semesterID := currentSemesterID()
+	if input.SemesterID != 0 {
+		if _, err := loadSemester("id = ?", input.SemesterID); err != nil {
+			utils.ValidationError(c, "Semester tidak ditemukan")
+			return
+		}
+		semesterID = sql.NullInt64{Int64: int64(input.SemesterID), Valid: true}
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuka transaksi")
+		return
+	}
+	defer tx.Rollback()
+
+	// Hanya satu periode yang terbuka pada satu waktu
+	if _, err := tx.Exec("UPDATE periode_krs SET status = 'closed' WHERE status = 'open'"); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menutup periode sebelumnya: "+err.Error())
+		return
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO periode_krs (nama, semester_id, tanggal_mulai, tanggal_selesai, status, created_by)
+		VALUES (?, ?, ?, ?, 'open', ?)
+	`, input.Nama, semesterID, mulai, selesai, c.GetInt("user_id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat periode KRS: "+err.Error())
+		return
+	}
+	periodeID, _ := result.LastInsertId()
+
+	if len(input.MataKuliah) == 0 {
+		_, err = tx.Exec(`
+			INSERT INTO periode_krs_matkul (periode_id, mata_kuliah_kode)
+			SELECT ?, kode FROM mata_kuliah WHERE deleted_at IS NULL
+		`, periodeID)
+	} else {
+		for _, kode := range input.MataKuliah {
+			var exists bool
+			tx.QueryRow("SELECT EXISTS(SELECT 1 FROM mata_kuliah WHERE kode = ? AND deleted_at IS NULL)", kode).Scan(&exists)
+			if !exists {
+				utils.ValidationError(c, "Mata kuliah "+kode+" tidak ditemukan")
+				return
+			}
+			if _, err = tx.Exec(`
+				INSERT IGNORE INTO periode_krs_matkul (periode_id, mata_kuliah_kode) VALUES (?, ?)
+			`, periodeID, kode); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan penawaran mata kuliah: "+err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan periode KRS")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"id":              periodeID,
+		"nama":            input.Nama,
+		"tanggal_mulai":   mulai.Format("2006-01-02 15:04:05"),
+		"tanggal_selesai": selesai.Format("2006-01-02 15:04:05"),
+		"semester_id":     nullInt(semesterID),
+		"status":          "open",
+	}, "Periode KRS berhasil dibuka")
+}
+
+// GetKRSPeriodes - Daftar periode KRS beserta rekap status pengisian
+func GetKRSPeriodes(c *gin.Context) {
+	rows, err := config.DB.Query(`
+		SELECT p.id, p.nama, p.tanggal_mulai, p.tanggal_selesai, p.status,
+			(SELECT COUNT(*) FROM periode_krs_matkul pm WHERE pm.periode_id = p.id),
+			(SELECT COUNT(*) FROM krs k WHERE k.periode_id = p.id AND k.status = 'diajukan'),
+			(SELECT COUNT(*) FROM krs k WHERE k.periode_id = p.id AND k.status = 'disetujui')
+		FROM periode_krs p
+		ORDER BY p.tanggal_mulai DESC
+	`)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil periode KRS: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	list := []gin.H{}
+	for rows.Next() {
+		var p krsPeriode
+		var offered, submitted, approved int
+		if rows.Scan(&p.ID, &p.Nama, &p.TanggalMulai, &p.TanggalSelesai, &p.Status, &offered, &submitted, &approved) != nil {
+			continue
+		}
+		item := p.toResponse()
+		item["total_matkul"] = offered
+		item["total_diajukan"] = submitted
+		item["total_disetujui"] = approved
+		list = append(list, item)
+	}
+
+	utils.SuccessResponse(c, list, "Periode KRS berhasil diambil")
+}
+
+// CloseKRSPeriode - Admin menutup periode KRS
+func CloseKRSPeriode(c *gin.Context) {
+	periodeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ValidationError(c, "ID periode tidak valid")
+		return
+	}
+
+	result, err := config.DB.Exec("UPDATE periode_krs SET status = 'closed' WHERE id = ?", periodeID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menutup periode KRS: "+err.Error())
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Periode KRS tidak ditemukan atau sudah ditutup")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"id": periodeID, "status": "closed"}, "Periode KRS ditutup")
+}
+
+// AssignDosenWali - Admin menetapkan dosen wali untuk sejumlah mahasiswa
+func AssignDosenWali(c *gin.Context) {
+	var input struct {
+		DosenID      int   `json:"dosen_id" binding:"required"`
+		MahasiswaIDs []int `json:"mahasiswa_ids" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	var dosenName string
+	if err := config.DB.QueryRow("SELECT name FROM dosen WHERE id = ?", input.DosenID).Scan(&dosenName); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Dosen not found")
+		return
+	}
+
+	assigned := 0
+	for _, mahasiswaID := range input.MahasiswaIDs {
+		_, err := config.DB.Exec(`
+			INSERT INTO dosen_wali (mahasiswa_id, dosen_id)
+			SELECT id, ? FROM mahasiswa WHERE id = ?
+			ON DUPLICATE KEY UPDATE dosen_id = VALUES(dosen_id)
+		`, input.DosenID, mahasiswaID)
+		if err != nil {
+			log.Printf("Gagal menetapkan dosen wali mahasiswa %d: %v", mahasiswaID, err)
+			continue
+		}
+		assigned++
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"dosen_id":   input.DosenID,
+		"dosen_name": dosenName,
+		"assigned":   assigned,
+	}, "Dosen wali berhasil ditetapkan")
+}
+
+// ==================== MAHASISWA ====================
+
+// GetMyKRS - Periode aktif, penawaran mata kuliah, batas SKS dan KRS mahasiswa
+func GetMyKRS(c *gin.Context) {
+	mahasiswaID, ok := mahasiswaIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	periode, err := currentKRSPeriode()
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(c, http.StatusNotFound, "Belum ada periode KRS yang dibuka")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil periode KRS: "+err.Error())
+		return
+	}
+
+	_, offerings, err := loadKRSOfferings(periode.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil penawaran mata kuliah: "+err.Error())
+		return
+	}
+
+	ips, hasIPS, maxSKS := studentSKSCap(mahasiswaID)
+
+	offered := []gin.H{}
+	for _, o := range offerings {
+		offered = append(offered, o.toResponse())
+	}
+
+	var krs interface{}
+	record, err := scanKRS(config.DB.QueryRow(krsSelect+" WHERE periode_id = ? AND mahasiswa_id = ?", periode.ID, mahasiswaID))
+	if err == nil {
+		courses, _, _ := loadKRSDetail(record.ID)
+		krs = record.toResponse(courses)
+	}
+
+	var dosenWali interface{}
+	var waliID int
+	var waliName string
+	if config.DB.QueryRow(`
+		SELECT d.id, d.name FROM dosen_wali dw JOIN dosen d ON dw.dosen_id = d.id WHERE dw.mahasiswa_id = ?
+	`, mahasiswaID).Scan(&waliID, &waliName) == nil {
+		dosenWali = gin.H{"id": waliID, "name": waliName}
+	}
+
+	var lastIPS interface{}
+	if hasIPS {
+		lastIPS = ips
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"periode":      periode.toResponse(),
+		"offerings":    offered,
+		"ips_terakhir": lastIPS,
+		"max_sks":      maxSKS,
+		"dosen_wali":   dosenWali,
+		"krs":          krs,
+	}, "KRS berhasil diambil")
+}
+
+// SaveMyKRS - Simpan pilihan mata kuliah sebagai draft KRS
+func SaveMyKRS(c *gin.Context) {
+	mahasiswaID, ok := mahasiswaIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		MataKuliah []string `json:"mata_kuliah" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	periode, err := currentKRSPeriode()
+	if err != nil || !periode.isOpen(time.Now()) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Periode pengisian KRS sedang tidak dibuka")
+		return
+	}
+
+	offerings, _, err := loadKRSOfferings(periode.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil penawaran mata kuliah: "+err.Error())
+		return
+	}
+
+	// Validasi pilihan: ditawarkan, tidak ganda, belum diambil pada semester periode ini
+	// (mata kuliah semester lalu boleh diulang)
+	seen := map[string]bool{}
+	var selected []krsOffering
+	totalSKS := 0
+	for _, kode := range input.MataKuliah {
+		kode = strings.TrimSpace(kode)
+		if seen[kode] {
+			continue
+		}
+		seen[kode] = true
+
+		o, offered := offerings[kode]
+		if !offered {
+			utils.ValidationError(c, "Mata kuliah "+kode+" tidak ditawarkan pada periode ini")
+			return
+		}
+
+		var enrolled bool
+		config.DB.QueryRow(`
+			SELECT EXISTS(
+				SELECT 1 FROM mahasiswa_mata_kuliah
+				WHERE mahasiswa_id = ? AND mata_kuliah_kode = ? AND semester_id <=> ?
+			)
+		`, mahasiswaID, kode, nullInt(periode.SemesterID)).Scan(&enrolled)
+		if enrolled {
+			utils.ValidationError(c, "Mata kuliah "+kode+" sudah Anda ambil")
+			return
+		}
+
+		selected = append(selected, o)
+		totalSKS += o.SKS
+	}
+
+	_, _, maxSKS := studentSKSCap(mahasiswaID)
+	if totalSKS > maxSKS {
+		utils.ValidationError(c, fmt.Sprintf("Total SKS %d melebihi batas %d SKS", totalSKS, maxSKS))
+		return
+	}
+
+	kodes := make([]string, 0, len(selected))
+	for _, o := range selected {
+		kodes = append(kodes, o.Kode)
+	}
+	conflicts, err := checkStudentSchedule(mahasiswaID, periode.SemesterID, kodes)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa jadwal: "+err.Error())
+		return
+	}
+	if len(conflicts) > 0 {
+		utils.ConflictResponse(c, gin.H{
+			"conflicts": conflictsResponse(conflicts),
+			"total":     len(conflicts),
+		}, "Jadwal mata kuliah yang dipilih bentrok")
+		return
+	}
+
+	existing, err := scanKRS(config.DB.QueryRow(krsSelect+" WHERE periode_id = ? AND mahasiswa_id = ?", periode.ID, mahasiswaID))
+	if err == nil && (existing.Status == "diajukan" || existing.Status == "disetujui") {
+		utils.ErrorResponse(c, http.StatusBadRequest, "KRS sudah "+existing.Status+" dan tidak bisa diubah")
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuka transaksi")
+		return
+	}
+	defer tx.Rollback()
+
+	krsID := existing.ID
+	if krsID == 0 {
+		result, err := tx.Exec(`
+			INSERT INTO krs (periode_id, mahasiswa_id, status, total_sks, max_sks)
+			VALUES (?, ?, 'draft', ?, ?)
+		`, periode.ID, mahasiswaID, totalSKS, maxSKS)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan KRS: "+err.Error())
+			return
+		}
+		id, _ := result.LastInsertId()
+		krsID = int(id)
+	} else {
+		// KRS yang ditolak kembali menjadi draft saat diubah
+		_, err := tx.Exec(`
+			UPDATE krs SET status = 'draft', total_sks = ?, max_sks = ?, updated_at = NOW() WHERE id = ?
+		`, totalSKS, maxSKS, krsID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan KRS: "+err.Error())
+			return
+		}
+		if _, err := tx.Exec("DELETE FROM krs_detail WHERE krs_id = ?", krsID); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan KRS: "+err.Error())
+			return
+		}
+	}
+
+	for _, o := range selected {
+		if _, err := tx.Exec(`
+			INSERT INTO krs_detail (krs_id, mata_kuliah_kode, sks) VALUES (?, ?, ?)
+		`, krsID, o.Kode, o.SKS); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan detail KRS: "+err.Error())
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan KRS")
+		return
+	}
+
+	record, _ := scanKRS(config.DB.QueryRow(krsSelect+" WHERE id = ?", krsID))
+	courses, _, _ := loadKRSDetail(krsID)
+	utils.SuccessResponse(c, record.toResponse(courses), "Draft KRS berhasil disimpan")
+}
+
+// SubmitMyKRS - Ajukan draft KRS ke dosen wali
+func SubmitMyKRS(c *gin.Context) {
+	mahasiswaID, ok := mahasiswaIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	periode, err := currentKRSPeriode()
+	if err != nil || !periode.isOpen(time.Now()) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Periode pengisian KRS sedang tidak dibuka")
+		return
+	}
+
+	record, err := scanKRS(config.DB.QueryRow(krsSelect+" WHERE periode_id = ? AND mahasiswa_id = ?", periode.ID, mahasiswaID))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Anda belum mengisi KRS pada periode ini")
+		return
+	}
+	if record.Status != "draft" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Hanya KRS berstatus draft yang bisa diajukan")
+		return
+	}
+
+	var waliUserID int
+	var mahasiswaName string
+	err = config.DB.QueryRow(`
+		SELECT d.user_id, m.name
+		FROM dosen_wali dw
+		JOIN dosen d ON dw.dosen_id = d.id
+		JOIN mahasiswa m ON dw.mahasiswa_id = m.id
+		WHERE dw.mahasiswa_id = ?
+	`, mahasiswaID).Scan(&waliUserID, &mahasiswaName)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Anda belum memiliki dosen wali, hubungi bagian akademik")
+		return
+	}
+
+	_, err = config.DB.Exec(`
+		UPDATE krs SET status = 'diajukan', submitted_at = NOW(), catatan = NULL, updated_at = NOW() WHERE id = ?
+	`, record.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengajukan KRS: "+err.Error())
+		return
+	}
+
+	createSystemNotification(waliUserID, record.ID,
+		fmt.Sprintf("%s mengajukan KRS %s (%d SKS) untuk disetujui", mahasiswaName, periode.Nama, record.TotalSKS))
+
+	utils.SuccessResponse(c, gin.H{"id": record.ID, "status": "diajukan"}, "KRS berhasil diajukan ke dosen wali")
+}
+
+// ==================== DOSEN WALI ====================
+
+// waliDosenID - id dosen yang login; menulis response error jika tidak ada
+func waliDosenID(c *gin.Context) (int, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return 0, false
+	}
+
+	var dosenID int
+	if err := config.DB.QueryRow("SELECT id FROM dosen WHERE user_id = ?", userID).Scan(&dosenID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Dosen not found")
+		return 0, false
+	}
+	return dosenID, true
+}
+
+// GetAdviseeKRS - Daftar KRS mahasiswa perwalian (?status=, default diajukan)
+func GetAdviseeKRS(c *gin.Context) {
+	dosenID, ok := waliDosenID(c)
+	if !ok {
+		return
+	}
+
+	status := c.DefaultQuery("status", "diajukan")
+	query := `
+		SELECT k.id, k.status, k.total_sks, k.max_sks, k.submitted_at, p.nama, m.id, m.nim, m.name
+		FROM krs k
+		JOIN dosen_wali dw ON dw.mahasiswa_id = k.mahasiswa_id
+		JOIN periode_krs p ON k.periode_id = p.id
+		JOIN mahasiswa m ON k.mahasiswa_id = m.id
+		WHERE dw.dosen_id = ?`
+	args := []interface{}{dosenID}
+	if status != "all" {
+		query += " AND k.status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY k.submitted_at, m.nim"
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil KRS: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	list := []gin.H{}
+	for rows.Next() {
+		var krsID, totalSKS, maxSKS, mahasiswaID int
+		var krsStatus, periodeNama, nim, name string
+		var submittedAt sql.NullTime
+		if rows.Scan(&krsID, &krsStatus, &totalSKS, &maxSKS, &submittedAt, &periodeNama, &mahasiswaID, &nim, &name) != nil {
+			continue
+		}
+
+		item := gin.H{
+			"id":             krsID,
+			"status":         krsStatus,
+			"total_sks":      totalSKS,
+			"max_sks":        maxSKS,
+			"periode":        periodeNama,
+			"mahasiswa_id":   mahasiswaID,
+			"nim":            nim,
+			"mahasiswa_name": name,
+			"submitted_at":   nil,
+		}
+		if submittedAt.Valid {
+			item["submitted_at"] = submittedAt.Time.Format("2006-01-02 15:04:05")
+		}
+		list = append(list, item)
+	}
+
+	utils.SuccessResponse(c, list, "KRS mahasiswa perwalian berhasil diambil")
+}
+
+// loadAdviseeKRS - KRS dengan id tertentu yang mahasiswanya berada di bawah perwalian dosen
+func loadAdviseeKRS(c *gin.Context, dosenID int) (krsRecord, bool) {
+	krsID, err := strconv.Atoi(c.Param("krs_id"))
+	if err != nil {
+		utils.ValidationError(c, "ID KRS tidak valid")
+		return krsRecord{}, false
+	}
+
+	record, err := scanKRS(config.DB.QueryRow(krsSelect+" WHERE id = ?", krsID))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "KRS tidak ditemukan")
+		return record, false
+	}
+
+	var isWali bool
+	config.DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM dosen_wali WHERE mahasiswa_id = ? AND dosen_id = ?)
+	`, record.StudentID, dosenID).Scan(&isWali)
+	if !isWali {
+		utils.ErrorResponse(c, http.StatusForbidden, "Mahasiswa ini bukan mahasiswa perwalian Anda")
+		return record, false
+	}
+	return record, true
+}
+
+// GetAdviseeKRSDetail - Detail KRS mahasiswa perwalian beserta IPS terakhir
+func GetAdviseeKRSDetail(c *gin.Context) {
+	dosenID, ok := waliDosenID(c)
+	if !ok {
+		return
+	}
+
+	record, ok := loadAdviseeKRS(c, dosenID)
+	if !ok {
+		return
+	}
+
+	courses, _, err := loadKRSDetail(record.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil detail KRS: "+err.Error())
+		return
+	}
+
+	var nim, name string
+	config.DB.QueryRow("SELECT nim, name FROM mahasiswa WHERE id = ?", record.StudentID).Scan(&nim, &name)
+
+	resp := record.toResponse(courses)
+	resp["nim"] = nim
+	resp["mahasiswa_name"] = name
+	if ips, hasIPS, _ := studentSKSCap(record.StudentID); hasIPS {
+		resp["ips_terakhir"] = ips
+	} else {
+		resp["ips_terakhir"] = nil
+	}
+
+	utils.SuccessResponse(c, resp, "Detail KRS berhasil diambil")
+}
+
+// ReviewAdviseeKRS - Dosen wali menyetujui atau menolak KRS.
+// KRS yang disetujui ditulis ke mahasiswa_mata_kuliah dan mahasiswa masuk grup chat mata kuliah.
+func ReviewAdviseeKRS(c *gin.Context) {
+	dosenID, ok := waliDosenID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Action  string `json:"action" binding:"required,oneof=approve reject"`
+		Catatan string `json:"catatan"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	record, ok := loadAdviseeKRS(c, dosenID)
+	if !ok {
+		return
+	}
+	if record.Status != "diajukan" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "KRS tidak sedang menunggu persetujuan")
+		return
+	}
+
+	var studentUserID int
+	config.DB.QueryRow("SELECT user_id FROM mahasiswa WHERE id = ?", record.StudentID).Scan(&studentUserID)
+
+	if input.Action == "reject" {
+		if strings.TrimSpace(input.Catatan) == "" {
+			utils.ValidationError(c, "Catatan wajib diisi saat menolak KRS")
+			return
+		}
+		result, err := config.DB.Exec(`
+			UPDATE krs SET status = 'ditolak', catatan = ?, reviewed_by = ?, reviewed_at = NOW(), updated_at = NOW()
+			WHERE id = ? AND status = 'diajukan'
+		`, input.Catatan, dosenID, record.ID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menolak KRS: "+err.Error())
+			return
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "KRS sudah ditinjau")
+			return
+		}
+		createSystemNotification(studentUserID, record.ID, "KRS Anda ditolak dosen wali: "+input.Catatan)
+		utils.SuccessResponse(c, gin.H{"id": record.ID, "status": "ditolak"}, "KRS ditolak")
+		return
+	}
+
+	_, kodes, err := loadKRSDetail(record.ID)
+	if err != nil || len(kodes) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "KRS tidak berisi mata kuliah")
+		return
+	}
+
+	var semesterID sql.NullInt64
+	config.DB.QueryRow("SELECT semester_id FROM periode_krs WHERE id = ?", record.PeriodeID).Scan(&semesterID)
+
+	// Jadwal atau data perkuliahan bisa berubah sejak KRS diajukan
+	conflicts, err := checkStudentSchedule(record.StudentID, semesterID, kodes)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa jadwal: "+err.Error())
+		return
+	}
+	if len(conflicts) > 0 {
+		utils.ConflictResponse(c, gin.H{
+			"conflicts": conflictsResponse(conflicts),
+			"total":     len(conflicts),
+		}, "Jadwal pada KRS bentrok, tolak KRS agar mahasiswa memperbaikinya")
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuka transaksi")
+		return
+	}
+	defer tx.Rollback()
+
+	// Hanya satu peninjau yang berhasil mengubah status; peninjau lain tidak ikut mendaftarkan mata kuliah
+	result, err := tx.Exec(`
+		UPDATE krs SET status = 'disetujui', catatan = ?, reviewed_by = ?, reviewed_at = NOW(), updated_at = NOW()
+		WHERE id = ? AND status = 'diajukan'
+	`, input.Catatan, dosenID, record.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyetujui KRS: "+err.Error())
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "KRS sudah ditinjau")
+		return
+	}
+
+	for _, kode := range kodes {
+		_, err := tx.Exec(`
+			INSERT INTO mahasiswa_mata_kuliah (mahasiswa_id, mata_kuliah_kode, semester_id)
+			SELECT ?, ?, ? FROM DUAL
+			WHERE NOT EXISTS (
+				SELECT 1 FROM mahasiswa_mata_kuliah
+				WHERE mahasiswa_id = ? AND mata_kuliah_kode = ? AND semester_id <=> ?
+			)
+		`, record.StudentID, kode, semesterID, record.StudentID, kode, semesterID)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menulis data perkuliahan: "+err.Error())
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyetujui KRS")
+		return
+	}
+
+	joined := joinCourseChatGroups(studentUserID, kodes)
+	createSystemNotification(studentUserID, record.ID,
+		fmt.Sprintf("KRS Anda disetujui dosen wali (%d mata kuliah, %d SKS)", len(kodes), record.TotalSKS))
+
+	utils.SuccessResponse(c, gin.H{
+		"id":        record.ID,
+		"status":    "disetujui",
+		"enrolled":  kodes,
+		"chat_grup": joined,
+	}, "KRS disetujui")
+}
+
+// joinCourseChatGroups - Masukkan mahasiswa ke grup chat mata kuliah yang sudah dibuat dosen.
+// Mata kuliah tanpa grup dilewati; mahasiswa ikut otomatis saat grup dibuat nanti.
+func joinCourseChatGroups(userID int, kodes []string) int {
+	if userID == 0 {
+		return 0
+	}
+
+	joined := 0
+	for _, kode := range kodes {
+		var conversationID int
+		err := config.DB.QueryRow(`
+			SELECT g.conversation_id
+			FROM mata_kuliah_chat_groups g
+			JOIN mata_kuliah mk ON g.mata_kuliah_id = mk.id
+			WHERE mk.kode = ? AND g.deleted_at IS NULL
+		`, kode).Scan(&conversationID)
+		if err != nil {
+			continue
+		}
+
+		result, err := config.DB.Exec(`
+			INSERT INTO conversation_participants (conversation_id, user_id, role)
+			SELECT ?, ?, 'member' FROM DUAL
+			WHERE NOT EXISTS (
+				SELECT 1 FROM conversation_participants WHERE conversation_id = ? AND user_id = ?
+			)
+		`, conversationID, userID, conversationID, userID)
+		if err != nil {
+			log.Printf("Gagal menambahkan user %d ke grup chat %s: %v", userID, kode, err)
+			continue
+		}
+		if affected, _ := result.RowsAffected(); affected > 0 {
+			joined++
+		}
+	}
+	return joined
+}
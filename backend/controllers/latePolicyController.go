@@ -0,0 +1,438 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kebijakan keterlambatan tugas:
+//
+//	none       - pengumpulan terlambat tetap diterima tanpa potongan (perilaku lama)
+//	hard_close - pengumpulan ditolak setelah deadline
+//	grace      - diterima tanpa potongan sampai masa tenggang habis, setelah itu ditolak
+//	penalty    - diterima, nilai dipotong penalty_per_day persen per hari (setelah masa tenggang), maksimal max_penalty
+var latePolicies = map[string]bool{"none": true, "hard_close": true, "grace": true, "penalty": true}
+
+const maxGraceMinutes = 7 * 24 * 60
+
+// latePolicy - kebijakan keterlambatan satu tugas
+type latePolicy struct {
+	Policy        string
+	GraceMinutes  int
+	PenaltyPerDay float64
+	MaxPenalty    float64
+}
+
+var defaultLatePolicy = latePolicy{Policy: "none", MaxPenalty: 100}
+
+func (p latePolicy) toResponse() gin.H {
+	return gin.H{
+		"late_policy":     p.Policy,
+		"grace_minutes":   p.GraceMinutes,
+		"penalty_per_day": p.PenaltyPerDay,
+		"max_penalty":     p.MaxPenalty,
+	}
+}
+
+// lateOutcome - hasil evaluasi pengumpulan terhadap deadline
+type lateOutcome struct {
+	Accepted bool
+	LateDays int
+	Penalty  float64 // persen potongan nilai
+	Message  string
+}
+
+// evaluate menilai pengumpulan pada waktu at terhadap deadline yang berlaku
+func (p latePolicy) evaluate(deadline sql.NullTime, at time.Time) lateOutcome {
+	if !deadline.Valid || !at.After(deadline.Time) {
+		return lateOutcome{Accepted: true}
+	}
+
+	late := at.Sub(deadline.Time)
+	grace := time.Duration(p.GraceMinutes) * time.Minute
+	out := lateOutcome{Accepted: true, LateDays: int(math.Ceil(late.Hours() / 24))}
+
+	switch p.Policy {
+	case "hard_close":
+		out.Accepted = false
+		out.Message = "Batas waktu pengumpulan tugas sudah lewat"
+	case "grace":
+		if late > grace {
+			out.Accepted = false
+			out.Message = "Masa tenggang pengumpulan tugas sudah habis"
+		}
+	case "penalty":
+		if late > grace {
+			days := math.Ceil((late - grace).Hours() / 24)
+			out.Penalty = math.Min(days*p.PenaltyPerDay, p.MaxPenalty)
+		}
+	}
+	return out
+}
+
+// applyLatePenalty - nilai akhir setelah potongan keterlambatan
+func applyLatePenalty(grade, penalty float64) float64 {
+	return math.Round(grade*(100-penalty)) / 100
+}
+
+// latePolicyInput - field kebijakan keterlambatan (JSON maupun form multipart)
+type latePolicyInput struct {
+	LatePolicy    string   `json:"late_policy" form:"late_policy"`
+	GraceMinutes  *int     `json:"grace_minutes" form:"grace_minutes"`
+	PenaltyPerDay *float64 `json:"penalty_per_day" form:"penalty_per_day"`
+	MaxPenalty    *float64 `json:"max_penalty" form:"max_penalty"`
+}
+
+// apply menimpa base dengan field yang diisi lalu memvalidasi hasilnya
+func (in latePolicyInput) apply(base latePolicy) (latePolicy, error) {
+	p := base
+	if in.LatePolicy != "" {
+		p.Policy = in.LatePolicy
+	}
+	if in.GraceMinutes != nil {
+		p.GraceMinutes = *in.GraceMinutes
+	}
+	if in.PenaltyPerDay != nil {
+		p.PenaltyPerDay = *in.PenaltyPerDay
+	}
+	if in.MaxPenalty != nil {
+		p.MaxPenalty = *in.MaxPenalty
+	}
+
+	switch {
+	case !latePolicies[p.Policy]:
+		return p, fmt.Errorf("late_policy harus none, hard_close, grace, atau penalty")
+	case p.GraceMinutes < 0 || p.GraceMinutes > maxGraceMinutes:
+		return p, fmt.Errorf("grace_minutes harus 0-%d", maxGraceMinutes)
+	case p.PenaltyPerDay < 0 || p.PenaltyPerDay > 100:
+		return p, fmt.Errorf("penalty_per_day harus 0-100")
+	case p.MaxPenalty < 0 || p.MaxPenalty > 100:
+		return p, fmt.Errorf("max_penalty harus 0-100")
+	case p.Policy == "penalty" && p.PenaltyPerDay == 0:
+		return p, fmt.Errorf("penalty_per_day wajib diisi untuk kebijakan penalty")
+	case p.Policy == "grace" && p.GraceMinutes == 0:
+		return p, fmt.Errorf("grace_minutes wajib diisi untuk kebijakan grace")
+	}
+	return p, nil
+}
+
+// taskDeadline - deadline tugas yang berlaku untuk satu mahasiswa
+type taskDeadline struct {
+	CourseID string
+	Title    string
+	DueDate  sql.NullTime
+	Extended sql.NullTime
+	Policy   latePolicy
+}
+
+// effective - deadline perpanjangan jika ada, selain itu deadline tugas
+func (d taskDeadline) effective() sql.NullTime {
+	if d.Extended.Valid {
+		return d.Extended
+	}
+	return d.DueDate
+}
+
+// loadTaskDeadline memuat deadline, kebijakan, dan perpanjangan tugas untuk mahasiswa
+func loadTaskDeadline(taskID, studentID int) (taskDeadline, error) {
+	var d taskDeadline
+	err := config.DB.QueryRow(`
+		SELECT t.course_id, t.title, t.due_date, e.due_date,
+			COALESCE(t.late_policy, 'none'), COALESCE(t.grace_minutes, 0),
+			COALESCE(t.penalty_per_day, 0), COALESCE(t.max_penalty, 100)
+		FROM tugas t
+		LEFT JOIN tugas_extensions e ON e.task_id = t.id AND e.student_id = ?
+		WHERE t.id = ? AND t.deleted_at IS NULL AND COALESCE(t.type, 'tugas') = 'tugas'
+	`, studentID, taskID).Scan(&d.CourseID, &d.Title, &d.DueDate, &d.Extended,
+		&d.Policy.Policy, &d.Policy.GraceMinutes, &d.Policy.PenaltyPerDay, &d.Policy.MaxPenalty)
+	return d, err
+}
+
+// dbNow - waktu sekarang menurut database; due_date dan submitted_at disimpan dalam jam yang sama
+func dbNow() time.Time {
+	var now time.Time
+	if err := config.DB.QueryRow("SELECT NOW()").Scan(&now); err != nil {
+		return time.Now()
+	}
+	return now
+}
+
+// recomputeLatePenalties menghitung ulang keterlambatan setiap versi submission tugas (studentID 0 = semua mahasiswa),
+// lalu menyalin hasil versi yang dinilai ke submissions. Dipanggil setelah kebijakan atau perpanjangan berubah;
+// submission yang sudah masuk tidak pernah ditolak ulang.
+func recomputeLatePenalties(taskID, studentID int) int {
+	query := `
+		SELECT v.id, v.submission_id, v.student_id, v.submitted_at
+		FROM submission_versions v
+		JOIN submissions s ON s.id = v.submission_id
+		WHERE v.task_id = ? AND s.deleted_at IS NULL`
+	args := []interface{}{taskID}
+	if studentID > 0 {
+		query += " AND v.student_id = ?"
+		args = append(args, studentID)
+	}
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Gagal memuat submission tugas %d: %v", taskID, err)
+		return 0
+	}
+	type pending struct {
+		id                      int64
+		submissionID, studentID int
+		at                      time.Time
+	}
+	var versions []pending
+	for rows.Next() {
+		var v pending
+		if err := rows.Scan(&v.id, &v.submissionID, &v.studentID, &v.at); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	rows.Close()
+
+	deadlines := map[int]taskDeadline{}
+	submissions := map[int]bool{}
+	for _, v := range versions {
+		d, ok := deadlines[v.studentID]
+		if !ok {
+			if d, err = loadTaskDeadline(taskID, v.studentID); err != nil {
+				continue
+			}
+			deadlines[v.studentID] = d
+		}
+		out := d.Policy.evaluate(d.effective(), v.at)
+		config.DB.Exec("UPDATE submission_versions SET late_days = ?, late_penalty = ? WHERE id = ?", out.LateDays, out.Penalty, v.id)
+		submissions[v.submissionID] = true
+	}
+
+	updated := 0
+	for submissionID := range submissions {
+		versionID, err := activeVersionID(submissionID)
+		if err != nil || versionID == 0 {
+			continue
+		}
+		if _, err := config.DB.Exec(`
+			UPDATE submissions s JOIN submission_versions v ON v.id = ?
+			SET s.late_days = v.late_days, s.late_penalty = v.late_penalty
+			WHERE s.id = ?
+		`, versionID, submissionID); err == nil {
+			updated++
+		}
+	}
+	return updated
+}
+
+// loadCourseTask - validasi dosen pengampu dan tugas :task_id milik mata kuliah :course_id
+func loadCourseTask(c *gin.Context) (int, int, bool) {
+	courseID := c.Param("course_id")
+	dosenID, ok := getDosenCourseAccess(c, courseID)
+	if !ok {
+		return 0, 0, false
+	}
+
+	taskID, err := strconv.Atoi(c.Param("task_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid task ID")
+		return 0, 0, false
+	}
+
+	var exists bool
+	config.DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM tugas WHERE id = ? AND course_id = ? AND deleted_at IS NULL AND COALESCE(type, 'tugas') = 'tugas')
+	`, taskID, courseID).Scan(&exists)
+	if !exists {
+		utils.ErrorResponse(c, http.StatusNotFound, "Tugas tidak ditemukan")
+		return 0, 0, false
+	}
+
+	return dosenID, taskID, true
+}
+
+// UpdateTugasLatePolicy - Ubah kebijakan keterlambatan tugas dan hitung ulang potongan
+func UpdateTugasLatePolicy(c *gin.Context) {
+	_, taskID, ok := loadCourseTask(c)
+	if !ok {
+		return
+	}
+
+	var input latePolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	current, err := loadTaskDeadline(taskID, 0)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Tugas tidak ditemukan")
+		return
+	}
+
+	policy, err := input.apply(current.Policy)
+	if err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	_, err = config.DB.Exec(`
+		UPDATE tugas SET late_policy = ?, grace_minutes = ?, penalty_per_day = ?, max_penalty = ?, updated_at = NOW()
+		WHERE id = ?
+	`, policy.Policy, policy.GraceMinutes, policy.PenaltyPerDay, policy.MaxPenalty, taskID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan kebijakan keterlambatan: "+err.Error())
+		return
+	}
+
+	response := policy.toResponse()
+	response["task_id"] = taskID
+	response["submissions_updated"] = recomputeLatePenalties(taskID, 0)
+	utils.SuccessResponse(c, response, "Kebijakan keterlambatan berhasil disimpan")
+}
+
+// GetTugasExtensions - Daftar perpanjangan deadline per mahasiswa untuk satu tugas
+func GetTugasExtensions(c *gin.Context) {
+	_, taskID, ok := loadCourseTask(c)
+	if !ok {
+		return
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT e.student_id, m.name, m.nim, e.due_date, COALESCE(e.reason, ''), COALESCE(d.name, ''), e.created_at
+		FROM tugas_extensions e
+		JOIN mahasiswa m ON m.id = e.student_id
+		LEFT JOIN dosen d ON d.id = e.granted_by
+		WHERE e.task_id = ?
+		ORDER BY m.name
+	`, taskID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil perpanjangan: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	extensions := []gin.H{}
+	for rows.Next() {
+		var studentID int
+		var name, nim, reason, grantedBy string
+		var dueDate, createdAt time.Time
+		if err := rows.Scan(&studentID, &name, &nim, &dueDate, &reason, &grantedBy, &createdAt); err != nil {
+			continue
+		}
+		extensions = append(extensions, gin.H{
+			"student_id":   studentID,
+			"student_name": name,
+			"student_nim":  nim,
+			"due_date":     dueDate.Format("2006-01-02 15:04:05"),
+			"reason":       reason,
+			"granted_by":   grantedBy,
+			"created_at":   createdAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	utils.SuccessResponse(c, extensions, "Perpanjangan deadline berhasil diambil")
+}
+
+// GrantTugasExtension - Beri (atau ubah) perpanjangan deadline untuk satu mahasiswa
+func GrantTugasExtension(c *gin.Context) {
+	dosenID, taskID, ok := loadCourseTask(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		StudentID int    `json:"student_id" binding:"required"`
+		DueDate   string `json:"due_date" binding:"required"`
+		Reason    string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	dueDate, err := time.Parse("2006-01-02T15:04", input.DueDate)
+	if err != nil {
+		if dueDate, err = time.Parse("2006-01-02 15:04:05", input.DueDate); err != nil {
+			utils.ValidationError(c, "Format due_date salah (gunakan datetime-local)")
+			return
+		}
+	}
+
+	task, err := loadTaskDeadline(taskID, input.StudentID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Tugas tidak ditemukan")
+		return
+	}
+	if task.DueDate.Valid && !dueDate.After(task.DueDate.Time) {
+		utils.ValidationError(c, "Deadline perpanjangan harus setelah deadline tugas")
+		return
+	}
+
+	var studentUserID int
+	err = config.DB.QueryRow(`
+		SELECT m.user_id FROM mahasiswa m
+		JOIN mahasiswa_mata_kuliah mmk ON mmk.mahasiswa_id = m.id
+		WHERE m.id = ? AND mmk.mata_kuliah_kode = ?
+	`, input.StudentID, task.CourseID).Scan(&studentUserID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Mahasiswa tidak terdaftar di mata kuliah ini")
+		return
+	}
+
+	_, err = config.DB.Exec(`
+		INSERT INTO tugas_extensions (task_id, student_id, due_date, reason, granted_by, created_at)
+		VALUES (?, ?, ?, ?, ?, NOW())
+		ON DUPLICATE KEY UPDATE due_date = VALUES(due_date), reason = VALUES(reason), granted_by = VALUES(granted_by)
+	`, taskID, input.StudentID, dueDate, input.Reason, dosenID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan perpanjangan: "+err.Error())
+		return
+	}
+
+	recomputeLatePenalties(taskID, input.StudentID)
+	createSystemNotification(studentUserID, taskID, fmt.Sprintf("Deadline tugas \"%s\" diperpanjang sampai %s",
+		task.Title, dueDate.Format("02-01-2006 15:04")))
+
+	utils.SuccessResponse(c, gin.H{
+		"task_id":    taskID,
+		"student_id": input.StudentID,
+		"due_date":   dueDate.Format("2006-01-02 15:04:05"),
+		"reason":     input.Reason,
+	}, "Perpanjangan deadline berhasil disimpan")
+}
+
+// RevokeTugasExtension - Cabut perpanjangan deadline mahasiswa
+func RevokeTugasExtension(c *gin.Context) {
+	_, taskID, ok := loadCourseTask(c)
+	if !ok {
+		return
+	}
+
+	studentID, err := strconv.Atoi(c.Param("student_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid student ID")
+		return
+	}
+
+	result, err := config.DB.Exec("DELETE FROM tugas_extensions WHERE task_id = ? AND student_id = ?", taskID, studentID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mencabut perpanjangan: "+err.Error())
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Perpanjangan tidak ditemukan")
+		return
+	}
+
+	recomputeLatePenalties(taskID, studentID)
+	utils.SuccessResponse(c, nil, "Perpanjangan deadline berhasil dicabut")
+}
@@ -288,14 +288,16 @@ func GetMahasiswaCourses(c *gin.Context) {
 	config.DB.QueryRow("SELECT COUNT(*) FROM mahasiswa_mata_kuliah WHERE mahasiswa_id = ?", mahasiswaID).Scan(&totalCourses)
 	fmt.Printf("Debug: Total courses for mahasiswa_id %d: %d\n", mahasiswaID, totalCourses)
 
+	// Opsional ?semester_id= untuk membatasi ke satu semester
+	scope, scopeArgs := semesterScope(c, "mmk.semester_id")
 	rows, err := config.DB.Query(`
 		SELECT mk.kode, mk.nama, d.name as dosen, mk.sks, mk.hari, mk.jam_mulai, mk.jam_selesai
 		FROM mata_kuliah mk
 		JOIN dosen d ON mk.dosen_id = d.id
 		JOIN mahasiswa_mata_kuliah mmk ON mk.kode = mmk.mata_kuliah_kode
-		WHERE mmk.mahasiswa_id = ? AND mk.deleted_at IS NULL
+		WHERE mmk.mahasiswa_id = ? AND mk.deleted_at IS NULL`+scope+`
 		ORDER BY mk.nama
-	`, mahasiswaID)
+	`, append([]interface{}{mahasiswaID}, scopeArgs...)...)
 	
 	if err != nil {
 		fmt.Printf("Debug: Error querying courses: %v\n", err)
@@ -443,6 +445,13 @@ func GetMahasiswaJadwalHariIni(c *gin.Context) {
 	}
 	defer rows.Close()
 
+	// Pertemuan pengganti hari ini menggantikan jadwal reguler mata kuliah yang sama
+	pengganti := listPenggantiToday(mahasiswaID)
+	penggantiCourses := map[string]bool{}
+	for _, p := range pengganti {
+		penggantiCourses[p.CourseID] = true
+	}
+
 	var jadwal []gin.H
 	for rows.Next() {
 		var kode, nama, dosen, hari, jamMulai, jamSelesai, statusAbsen, waktuAbsen, sessionCode string
@@ -454,23 +463,16 @@ func GetMahasiswaJadwalHariIni(c *gin.Context) {
 			continue
 		}
 
+		if penggantiCourses[kode] {
+			continue
+		}
+
 		// Check if course can be scanned (belum absen dan sesuai waktu)
 		canScan := false
 		isActiveSession := false
 		if statusAbsen == "belum_absen" && sessionCode != "" {
-			// Check if current time is within course time
-			currentTime := time.Now()
-			courseTimeStart, _ := time.Parse("15:04", jamMulai)
-			courseTimeEnd, _ := time.Parse("15:04", jamSelesai)
-
-			// Create today's datetime
-			startTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(),
-				courseTimeStart.Hour(), courseTimeStart.Minute(), 0, 0, currentTime.Location())
-			endTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(),
-				courseTimeEnd.Hour(), courseTimeEnd.Minute(), 0, 0, currentTime.Location())
-
-			// Allow scan 15 minutes before and 60 minutes after start time
-			if currentTime.After(startTime.Add(-15*time.Minute)) && currentTime.Before(endTime.Add(60*time.Minute)) {
+			// Check if current time is within the course's scan window
+			if loadAttendancePolicy(kode).canScanAt(time.Now(), jamMulai, jamSelesai) {
 				canScan = true
 				isActiveSession = true
 			}
@@ -490,6 +492,30 @@ func GetMahasiswaJadwalHariIni(c *gin.Context) {
 			"session_code":      sessionCode,
 			"can_scan":          canScan,
 			"is_active_session": isActiveSession,
+			"is_pengganti":      false,
+		})
+	}
+
+	for _, p := range pengganti {
+		canScan := p.StatusAbsen == "belum_absen" && p.SessionCode != "" &&
+			loadAttendancePolicy(p.CourseID).canScanAt(time.Now(), p.JamMulai, p.JamSelesai)
+
+		jadwal = append(jadwal, gin.H{
+			"kode":              p.CourseID,
+			"nama":              p.CourseName,
+			"dosen":             p.Dosen,
+			"sks":               p.SKS,
+			"hari":              hariIni,
+			"jam_mulai":         p.JamMulai,
+			"jam_selesai":       p.JamSelesai,
+			"status_absen":      p.StatusAbsen,
+			"waktu_absen":       p.WaktuAbsen,
+			"pertemuan_ke":      p.PertemuanKe,
+			"session_code":      p.SessionCode,
+			"can_scan":          canScan,
+			"is_active_session": canScan,
+			"is_pengganti":      true,
+			"ruangan":           p.Ruangan,
 		})
 	}
 
@@ -513,8 +539,13 @@ func ScanAttendance(c *gin.Context) {
 	}
 
 	var input struct {
-		SessionToken string `json:"session_token" binding:"required"`
-		CourseID     string `json:"course_id" binding:"required"`
+		QRPayload  string   `json:"qr_payload" binding:"required"`
+		CourseID   string   `json:"course_id" binding:"required"`
+		Latitude   *float64 `json:"latitude"`
+		Longitude  *float64 `json:"longitude"`
+		Accuracy   *float64 `json:"accuracy"`
+		DeviceID   string   `json:"device_id" binding:"required"`
+		DeviceName string   `json:"device_name"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -522,48 +553,65 @@ func ScanAttendance(c *gin.Context) {
 		return
 	}
 
+	// Verifikasi tanda tangan dan masa berlaku QR tanpa menyentuh database:
+	// QR palsu atau kadaluarsa langsung ditolak
+	payload, err := utils.VerifyQRPayload(input.QRPayload, time.Now())
+	if err == utils.ErrQRPayloadExpired {
+		utils.ErrorResponse(c, http.StatusBadRequest, "QR Code sudah kadaluarsa. Silakan scan ulang QR terbaru di kelas")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "QR Code tidak valid")
+		return
+	}
+
+	// Validasi: Mata kuliah harus sesuai dengan yang dipilih mahasiswa
+	if payload.CourseID != input.CourseID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "QR Code tidak sesuai dengan mata kuliah yang dipilih")
+		return
+	}
+
 	// Get mahasiswa ID
 	var mahasiswaID int
-	err := config.DB.QueryRow("SELECT id FROM mahasiswa WHERE user_id = ?", userID).Scan(&mahasiswaID)
+	err = config.DB.QueryRow("SELECT id FROM mahasiswa WHERE user_id = ?", userID).Scan(&mahasiswaID)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Mahasiswa not found")
 		return
 	}
 
-	// Cek session token dan dapatkan informasi sesi dengan pertemuan
+	// Sesi dari payload harus masih aktif di database
 	var session struct {
 		ID          int
 		CourseID    string
 		DosenID     int
 		PertemuanKe int
 		ExpiresAt   time.Time
-		CourseDay   string
-		CourseStart string
-		CourseEnd   string
 		Status      string
+		QRSecret    string
 	}
 
 	err = config.DB.QueryRow(`
 		SELECT asess.id, asess.course_id, asess.dosen_id, asess.pertemuan_ke, 
-		       asess.expires_at, mk.hari, mk.jam_mulai, mk.jam_selesai, asess.status
+		       asess.expires_at, asess.status,
+		       COALESCE(asess.qr_secret, asess.qr_token, '')
 		FROM attendance_sessions asess
 		JOIN mata_kuliah mk ON asess.course_id = mk.kode
-		WHERE asess.session_token = ? 
+		WHERE asess.id = ? 
 			AND asess.status = 'active' 
 			AND asess.expires_at > NOW()
 			AND asess.course_id = ?
-	`, input.SessionToken, input.CourseID).Scan(
+	`, payload.SessionID, payload.CourseID).Scan(
 		&session.ID, &session.CourseID, &session.DosenID, &session.PertemuanKe,
-		&session.ExpiresAt, &session.CourseDay, &session.CourseStart, &session.CourseEnd, &session.Status)
+		&session.ExpiresAt, &session.Status, &session.QRSecret)
 
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "QR Code tidak valid: Sesi sudah kadaluarsa atau tidak aktif")
 		return
 	}
 
-	// Validasi: Mata kuliah harus sesuai dengan yang dipilih mahasiswa
-	if session.CourseID != input.CourseID {
-		utils.ErrorResponse(c, http.StatusBadRequest, "QR Code tidak sesuai dengan mata kuliah yang dipilih")
+	// Kode QR berotasi: hanya window saat ini dan satu window sebelumnya yang diterima
+	if !utils.ValidateQRToken(session.QRSecret, payload.Code, time.Now(), utils.GetQRTokenPeriod()) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "QR Code sudah kadaluarsa. Silakan scan ulang QR terbaru di kelas")
 		return
 	}
 
@@ -581,49 +629,32 @@ func ScanAttendance(c *gin.Context) {
 		return
 	}
 
-	// Cek hari sesuai jadwal
-	today := time.Now().Weekday()
-	dayMap := map[string]time.Weekday{
-		"Senin":  time.Monday,
-		"Selasa": time.Tuesday,
-		"Rabu":   time.Wednesday,
-		"Kamis":  time.Thursday,
-		"Jumat":  time.Friday,
-		"Sabtu":  time.Saturday,
-		"Minggu": time.Sunday,
+	// Perangkat harus terdaftar atas nama mahasiswa ini (maksimal MAX_DEVICES_PER_MAHASISWA)
+	deviceFingerprint := hashDeviceFingerprint(input.DeviceID)
+	if reason := bindMahasiswaDevice(mahasiswaID, deviceFingerprint, input.DeviceName); reason != "" {
+		utils.ErrorResponse(c, http.StatusForbidden, reason)
+		return
 	}
 
-	courseDay, ok := dayMap[session.CourseDay]
-	if !ok || courseDay != today {
-		utils.ErrorResponse(c, http.StatusBadRequest,
-			"Hari ini bukan jadwal mata kuliah ini. Jadwal: "+session.CourseDay)
+	// Jadwal pertemuan ini: reguler atau pertemuan pengganti (tanggal, jam dan ruangan sendiri)
+	schedule, err := resolveSchedule(session.CourseID, session.PertemuanKe)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Jadwal mata kuliah tidak ditemukan")
 		return
 	}
 
-	// Cek waktu absensi (15 menit sebelum - 60 menit setelah jam mulai)
-	currentTime := time.Now()
-	courseStart, _ := time.Parse("15:04", session.CourseStart)
-	courseEnd, _ := time.Parse("15:04", session.CourseEnd)
-
-	// Create today's datetime for course times
-	startTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(),
-		courseStart.Hour(), courseStart.Minute(), 0, 0, currentTime.Location())
-	endTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(),
-		courseEnd.Hour(), courseEnd.Minute(), 0, 0, currentTime.Location())
-
-	// Validasi waktu
-	scanStart := startTime.Add(-15 * time.Minute)
-	scanEnd := endTime.Add(60 * time.Minute)
-
-	if currentTime.Before(scanStart) {
-		utils.ErrorResponse(c, http.StatusBadRequest,
-			"Belum waktunya absen. Bisa scan 15 menit sebelum kelas dimulai.")
+	// Cek lokasi perangkat terhadap geofence ruangan (jika diaktifkan untuk mata kuliah ini)
+	location := geofenceLocation{Latitude: input.Latitude, Longitude: input.Longitude, Accuracy: input.Accuracy}
+	if reason := checkGeofence(session.ID, mahasiswaID, session.CourseID, schedule.Ruangan, location); reason != "" {
+		utils.ErrorResponse(c, http.StatusForbidden, reason)
 		return
 	}
 
-	if currentTime.After(scanEnd) {
-		utils.ErrorResponse(c, http.StatusBadRequest,
-			"Waktu absen sudah berakhir. Maksimal 60 menit setelah kelas selesai.")
+	// Cek hari dan jendela waktu scan sesuai kebijakan absensi mata kuliah
+	policy := loadAttendancePolicy(session.CourseID)
+	scanStatus, reason := policy.evaluateScan(time.Now(), schedule)
+	if reason != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, reason)
 		return
 	}
 
@@ -663,33 +694,26 @@ func ScanAttendance(c *gin.Context) {
 		studentCode = fmt.Sprintf("MHS-%d", mahasiswaID)
 	}
 
-	// Insert attendance dengan status hadir dan pertemuan_ke
-	_, err = config.DB.Exec(`
-		INSERT INTO attendance (student_id, session_id, student_code, status, pertemuan_ke, created_at)
-		VALUES (?, ?, ?, 'hadir', ?, NOW())
-	`, mahasiswaID, session.ID, studentCode, session.PertemuanKe)
+	// Insert attendance dengan status dari kebijakan, pertemuan_ke dan perangkat yang dipakai
+	result, err := config.DB.Exec(`
+		INSERT INTO attendance (student_id, session_id, student_code, status, pertemuan_ke, device_fingerprint, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW())
+	`, mahasiswaID, session.ID, studentCode, scanStatus, session.PertemuanKe, deviceFingerprint)
 
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to record attendance: "+err.Error())
 		return
 	}
 
+	attendanceID, _ := result.LastInsertId()
+	recordAttendanceAudit(int(attendanceID), session.ID, mahasiswaID, "", scanStatus,
+		attendanceChange{Source: auditSourceScan, ChangedBy: c.GetInt("user_id")})
+
 	// Update attendance_summary
-	_, err = config.DB.Exec(`
-		INSERT INTO attendance_summary 
-		(student_id, nim, student_name, session_id, course_id, course_name, status, 
-		 attendance_date, attendance_time, dosen_name, hari, jam_mulai, jam_selesai)
-		SELECT 
-			m.id, m.nim, m.name, ?, mk.kode, mk.nama, 'hadir',
-			CURDATE(), NOW(), d.name, mk.hari, mk.jam_mulai, mk.jam_selesai
-		FROM mahasiswa m
-		JOIN mata_kuliah mk ON mk.kode = ?
-		JOIN dosen d ON mk.dosen_id = d.id
-		WHERE m.id = ?
-		ON DUPLICATE KEY UPDATE
-			status = 'hadir',
-			attendance_time = NOW()
-	`, session.ID, session.CourseID, mahasiswaID)
+	syncAttendanceSummary(session.ID, mahasiswaID, session.CourseID, scanStatus)
+
+	// Push ke tampilan realtime dosen dan perangkat mahasiswa
+	publishAttendanceEvent(session.ID, mahasiswaID, scanStatus, "scan")
 
 	// Get course info untuk response
 	var courseName, dosenName string
@@ -708,9 +732,10 @@ func ScanAttendance(c *gin.Context) {
 		"pertemuan_ke": session.PertemuanKe,
 		"time":         time.Now().Format("15:04"),
 		"date":         time.Now().Format("2006-01-02"),
-		"status":       "hadir",
+		"status":       scanStatus,
+		"status_label": getStatusLabel(scanStatus),
 		"session_code": fmt.Sprintf("ABS-%s-P%d", session.CourseID, session.PertemuanKe),
-	}, "Absensi berhasil! Pertemuan ke-"+strconv.Itoa(session.PertemuanKe)+" - Status: Hadir")
+	}, "Absensi berhasil! Pertemuan ke-"+strconv.Itoa(session.PertemuanKe)+" - Status: "+getStatusLabel(scanStatus))
 }
 
 // GetAttendanceHistoryByCourse - Get riwayat absensi per mata kuliah
@@ -787,22 +812,23 @@ func GetAttendanceHistoryByCourse(c *gin.Context) {
 	}
 
 	// Get summary untuk mata kuliah ini
-	var totalSessions, hadirCount, izinCount, sakitCount, alpaCount int
+	var totalSessions, hadirCount, terlambatCount, izinCount, sakitCount, alpaCount int
 	err = config.DB.QueryRow(`
 		SELECT 
 			COUNT(DISTINCT a.id) as total,
 			SUM(CASE WHEN a.status = 'hadir' THEN 1 ELSE 0 END) as hadir,
+			SUM(CASE WHEN a.status = 'terlambat' THEN 1 ELSE 0 END) as terlambat,
 			SUM(CASE WHEN a.status = 'izin' THEN 1 ELSE 0 END) as izin,
 			SUM(CASE WHEN a.status = 'sakit' THEN 1 ELSE 0 END) as sakit,
 			SUM(CASE WHEN a.status = 'alpa' THEN 1 ELSE 0 END) as alpa
 		FROM attendance a
 		JOIN attendance_sessions asess ON a.session_id = asess.id
-		WHERE a.student_id = ? AND asess.course_id = ?
-	`, mahasiswaID, courseID).Scan(&totalSessions, &hadirCount, &izinCount, &sakitCount, &alpaCount)
+		WHERE a.student_id = ? AND asess.course_id = ? AND `+countedAttendance("a")+`
+	`, mahasiswaID, courseID).Scan(&totalSessions, &hadirCount, &terlambatCount, &izinCount, &sakitCount, &alpaCount)
 
 	if err != nil {
 		// Jika error, set ke 0
-		totalSessions, hadirCount, izinCount, sakitCount, alpaCount = 0, 0, 0, 0, 0
+		totalSessions, hadirCount, terlambatCount, izinCount, sakitCount, alpaCount = 0, 0, 0, 0, 0, 0
 	}
 
 	// Get course info
@@ -827,12 +853,14 @@ func GetAttendanceHistoryByCourse(c *gin.Context) {
 			"jam_selesai": jamSelesai,
 			"total":       totalSessions,
 			"hadir":       hadirCount,
+			"terlambat":   terlambatCount,
 			"izin":        izinCount,
 			"sakit":       sakitCount,
 			"alpa":        alpaCount,
 			"kehadiran_percent": func() float64 {
 				if totalSessions > 0 {
-					return float64(hadirCount) / float64(totalSessions) * 100
+					// Terlambat tetap dihitung hadir secara persentase
+					return float64(hadirCount+terlambatCount) / float64(totalSessions) * 100
 				}
 				return 0
 			}(),
@@ -929,28 +957,29 @@ func GetAttendanceHistory(c *gin.Context) {
 	}
 
 	// Get summary statistics
-	var totalSessions, hadirCount, izinCount, sakitCount, alpaCount int
+	var totalSessions, hadirCount, terlambatCount, izinCount, sakitCount, alpaCount int
 	summaryQuery := `
 		SELECT 
 			COUNT(DISTINCT a.id) as total,
 			SUM(CASE WHEN a.status = 'hadir' THEN 1 ELSE 0 END) as hadir,
+			SUM(CASE WHEN a.status = 'terlambat' THEN 1 ELSE 0 END) as terlambat,
 			SUM(CASE WHEN a.status = 'izin' THEN 1 ELSE 0 END) as izin,
 			SUM(CASE WHEN a.status = 'sakit' THEN 1 ELSE 0 END) as sakit,
 			SUM(CASE WHEN a.status = 'alpa' THEN 1 ELSE 0 END) as alpa
 		FROM attendance a
-		WHERE a.student_id = ?
+		WHERE a.student_id = ? AND `+countedAttendance("a")+`
 	`
 	
 	if status != "all" {
 		summaryQuery += " AND a.status = ?"
-		err = config.DB.QueryRow(summaryQuery, mahasiswaID, status).Scan(&totalSessions, &hadirCount, &izinCount, &sakitCount, &alpaCount)
+		err = config.DB.QueryRow(summaryQuery, mahasiswaID, status).Scan(&totalSessions, &hadirCount, &terlambatCount, &izinCount, &sakitCount, &alpaCount)
 	} else {
-		err = config.DB.QueryRow(summaryQuery, mahasiswaID).Scan(&totalSessions, &hadirCount, &izinCount, &sakitCount, &alpaCount)
+		err = config.DB.QueryRow(summaryQuery, mahasiswaID).Scan(&totalSessions, &hadirCount, &terlambatCount, &izinCount, &sakitCount, &alpaCount)
 	}
 
 	if err != nil {
 		// Jika error, set default values
-		totalSessions, hadirCount, izinCount, sakitCount, alpaCount = 0, 0, 0, 0, 0
+		totalSessions, hadirCount, terlambatCount, izinCount, sakitCount, alpaCount = 0, 0, 0, 0, 0, 0
 	}
 
 	utils.SuccessResponse(c, gin.H{
@@ -958,12 +987,14 @@ func GetAttendanceHistory(c *gin.Context) {
 		"summary": gin.H{
 			"total":              totalSessions,
 			"hadir":              hadirCount,
+			"terlambat":          terlambatCount,
 			"izin":               izinCount,
 			"sakit":              sakitCount,
 			"alpa":               alpaCount,
 			"kehadiran_percent": func() float64 {
 				if totalSessions > 0 {
-					return float64(hadirCount) / float64(totalSessions) * 100
+					// Terlambat tetap dihitung hadir secara persentase
+					return float64(hadirCount+terlambatCount) / float64(totalSessions) * 100
 				}
 				return 0
 			}(),
@@ -999,6 +1030,7 @@ func GetAttendanceSummary(c *gin.Context) {
         COUNT(DISTINCT asess.id) AS total_sessions,
         COUNT(DISTINCT a.id) AS attended_sessions,
         SUM(CASE WHEN a.status = 'hadir' THEN 1 ELSE 0 END) AS hadir_count,
+        SUM(CASE WHEN a.status = 'terlambat' THEN 1 ELSE 0 END) AS terlambat_count,
         SUM(CASE WHEN a.status = 'izin' THEN 1 ELSE 0 END) AS izin_count,
         SUM(CASE WHEN a.status = 'sakit' THEN 1 ELSE 0 END) AS sakit_count,
         SUM(CASE WHEN a.status = 'alpa' THEN 1 ELSE 0 END) AS alpa_count
@@ -1010,6 +1042,7 @@ func GetAttendanceSummary(c *gin.Context) {
     LEFT JOIN attendance a 
         ON asess.id = a.session_id 
         AND a.student_id = ?
+        AND `+countedAttendance("a")+`
     WHERE mmk.mahasiswa_id = ?
     GROUP BY mk.kode, mk.nama, d.name
     ORDER BY mk.nama
@@ -1025,17 +1058,18 @@ func GetAttendanceSummary(c *gin.Context) {
 	var summary []gin.H
 	for rows.Next() {
 		var kode, mataKuliah, dosen string
-		var totalSessions, attendedSessions, hadirCount, izinCount, sakitCount, alpaCount int
+		var totalSessions, attendedSessions, hadirCount, terlambatCount, izinCount, sakitCount, alpaCount int
 
 		err := rows.Scan(&kode, &mataKuliah, &dosen, &totalSessions, &attendedSessions,
-			&hadirCount, &izinCount, &sakitCount, &alpaCount)
+			&hadirCount, &terlambatCount, &izinCount, &sakitCount, &alpaCount)
 		if err != nil {
 			continue
 		}
 
+		// Persentase kehadiran dihitung dari hadir + terlambat, bukan semua baris absensi
 		var attendancePercentage float64
 		if totalSessions > 0 {
-			attendancePercentage = float64(attendedSessions) / float64(totalSessions) * 100
+			attendancePercentage = float64(hadirCount+terlambatCount) / float64(totalSessions) * 100
 		}
 
 		summary = append(summary, gin.H{
@@ -1046,6 +1080,7 @@ func GetAttendanceSummary(c *gin.Context) {
 			"attended_sessions":  attendedSessions,
 			"attendance_percent": attendancePercentage,
 			"hadir_count":        hadirCount,
+			"terlambat_count":    terlambatCount,
 			"izin_count":         izinCount,
 			"sakit_count":        sakitCount,
 			"alpa_count":         alpaCount,
@@ -1223,12 +1258,13 @@ func GetUKTInvoices(c *gin.Context) {
 		return
 	}
 
+	scope, scopeArgs := semesterScope(c, "semester_id")
 	rows, err := config.DB.Query(`
 		SELECT id, amount, uuid, status, created_at
 		FROM ukt_invoices
-		WHERE student_id = ?
+		WHERE student_id = ?`+scope+`
 		ORDER BY created_at DESC
-	`, mahasiswaID)
+	`, append([]interface{}{mahasiswaID}, scopeArgs...)...)
 
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch invoices")
@@ -1341,6 +1377,19 @@ func SubmitTugas(c *gin.Context) {
 		return
 	}
 
+	// Terapkan deadline (termasuk perpanjangan) dan kebijakan keterlambatan tugas
+	deadline, err := loadTaskDeadline(taskID, mahasiswaID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Tugas tidak ditemukan")
+		return
+	}
+	submittedAt := dbNow()
+	outcome := deadline.Policy.evaluate(deadline.effective(), submittedAt)
+	if !outcome.Accepted {
+		utils.ErrorResponse(c, http.StatusForbidden, outcome.Message)
+		return
+	}
+
 	// Handle file upload
 	var fileURL string
 	file, header, err := c.Request.FormFile("file")
@@ -1378,26 +1427,62 @@ func SubmitTugas(c *gin.Context) {
 	// Periksa apakah submission sudah ada
 	var existingSubmissionID int
 	var existingFileURL string
-	checkQuery := `SELECT id, file_url FROM submissions WHERE task_id = ? AND student_id = ?`
-	err = config.DB.QueryRow(checkQuery, taskID, mahasiswaID).Scan(&existingSubmissionID, &existingFileURL)
+	checkQuery := `SELECT id, COALESCE(file_url, '') FROM submissions WHERE task_id = ? AND student_id = ?`
+	isNew := config.DB.QueryRow(checkQuery, taskID, mahasiswaID).Scan(&existingSubmissionID, &existingFileURL) != nil
 
-	// Insert atau update submission
+	// Jika ada file baru, gunakan yang baru, jika tidak pertahankan yang lama
+	if fileURL == "" && !isNew {
+		fileURL = existingFileURL
+	}
+
+	contentHash, err := submissionContentHash(answerText, fileURL)
 	if err != nil {
-		// Insert baru
-		query := `INSERT INTO submissions (task_id, student_id, file_url, answer_text, created_at) 
-				  VALUES (?, ?, ?, ?, NOW())`
-		_, err = config.DB.Exec(query, taskID, mahasiswaID, fileURL, answerText)
-	} else {
-		// Update existing
-		// Jika ada file baru, gunakan yang baru, jika tidak pertahankan yang lama
-		finalFileURL := fileURL
-		if finalFileURL == "" {
-			finalFileURL = existingFileURL
-		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to hash submission: "+err.Error())
+		return
+	}
 
-		query := `UPDATE submissions SET file_url = ?, answer_text = ?, updated_at = NOW() 
+	tx, err := config.DB.Begin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to submit tugas: "+err.Error())
+		return
+	}
+	defer tx.Rollback()
+
+	// Insert atau update ringkasan submission, lalu simpan versi baru (versi lama tidak pernah ditimpa)
+	submissionID := existingSubmissionID
+	if isNew {
+		query := `INSERT INTO submissions (task_id, student_id, file_url, answer_text, submitted_at, late_days, late_penalty, created_at) 
+				  VALUES (?, ?, ?, ?, ?, ?, ?, NOW())`
+		result, execErr := tx.Exec(query, taskID, mahasiswaID, fileURL, answerText, submittedAt, outcome.LateDays, outcome.Penalty)
+		if err = execErr; err == nil {
+			id, _ := result.LastInsertId()
+			submissionID = int(id)
+		}
+	} else {
+		// Keterlambatan mengikuti versi yang dinilai jika dosen sudah memilih versi
+		query := `UPDATE submissions SET file_url = ?, answer_text = ?, submitted_at = ?,
+				  late_days = IF(graded_version_id IS NULL, ?, late_days), late_penalty = IF(graded_version_id IS NULL, ?, late_penalty),
+				  updated_at = NOW() 
 				  WHERE id = ?`
-		_, err = config.DB.Exec(query, finalFileURL, answerText, existingSubmissionID)
+		_, err = tx.Exec(query, fileURL, answerText, submittedAt, outcome.LateDays, outcome.Penalty, existingSubmissionID)
+	}
+
+	version := submissionVersion{
+		SubmissionID: submissionID,
+		TaskID:       taskID,
+		StudentID:    mahasiswaID,
+		FileURL:      fileURL,
+		AnswerText:   answerText,
+		ContentHash:  contentHash,
+		SubmittedAt:  submittedAt,
+		LateDays:     outcome.LateDays,
+		LatePenalty:  outcome.Penalty,
+	}
+	if err == nil {
+		err = recordSubmissionVersion(tx, &version, deadline.Title, c.GetInt("user_id"))
+	}
+	if err == nil {
+		err = tx.Commit()
 	}
 
 	if err != nil {
@@ -1405,7 +1490,20 @@ func SubmitTugas(c *gin.Context) {
 		return
 	}
 
-	utils.SuccessResponse(c, nil, "Tugas submitted successfully")
+	effectiveDue := ""
+	if due := deadline.effective(); due.Valid {
+		effectiveDue = due.Time.Format("2006-01-02 15:04:05")
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"submitted_at": submittedAt.Format("2006-01-02 15:04:05"),
+		"due_date":     effectiveDue,
+		"extended":     deadline.Extended.Valid,
+		"late_days":    outcome.LateDays,
+		"late_penalty": outcome.Penalty,
+		"version_id":   version.ID,
+		"receipt":      version.receiptResponse(),
+	}, "Tugas submitted successfully")
 }
 
 // GetSubmissionStatus - Get submission status for a task
@@ -1431,14 +1529,21 @@ func GetSubmissionStatus(c *gin.Context) {
 		FileURL    string  `json:"file_url"`
 		AnswerText string  `json:"answer_text"`
 		Grade      float64 `json:"grade"`
+		LateDays   int     `json:"late_days"`
+		Penalty    float64 `json:"late_penalty"`
+		FinalGrade float64 `json:"final_grade"`
+		Feedback   string  `json:"feedback"`
+		Rubric     gin.H   `json:"rubric"`
 		CreatedAt  string  `json:"created_at"`
 	}
 
 	err = config.DB.QueryRow(`
-		SELECT id, COALESCE(file_url, ''), COALESCE(answer_text, ''), COALESCE(grade, 0), created_at
+		SELECT id, COALESCE(file_url, ''), COALESCE(answer_text, ''), COALESCE(grade, 0), late_days, late_penalty,
+			COALESCE(feedback, ''), created_at
 		FROM submissions 
 		WHERE task_id = ? AND student_id = ?
-	`, taskID, mahasiswaID).Scan(&submission.ID, &submission.FileURL, &submission.AnswerText, &submission.Grade, &submission.CreatedAt)
+	`, taskID, mahasiswaID).Scan(&submission.ID, &submission.FileURL, &submission.AnswerText, &submission.Grade,
+		&submission.LateDays, &submission.Penalty, &submission.Feedback, &submission.CreatedAt)
 
 	if err != nil {
 		// Tidak ada submission
@@ -1446,5 +1551,7 @@ func GetSubmissionStatus(c *gin.Context) {
 		return
 	}
 
+	submission.FinalGrade = applyLatePenalty(submission.Grade, submission.Penalty)
+	submission.Rubric = submissionRubricBreakdown(submission.ID)
 	utils.SuccessResponse(c, submission, "Submission found")
 }
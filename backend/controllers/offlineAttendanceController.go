@@ -0,0 +1,475 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultOfflineSyncMaxAge - batas umur struk offline yang masih boleh disinkronkan (jam).
+// Dibuat pendek karena waktu scan di struk tidak bisa dibuktikan (lihat syncOfflineReceipt).
+const defaultOfflineSyncMaxAge = 12
+
+// maxOfflineReceiptsPerSync - jumlah struk maksimal dalam satu permintaan sinkronisasi
+const maxOfflineReceiptsPerSync = 50
+
+// offlineClockSkew - toleransi jam perangkat yang lebih cepat dari server
+const offlineClockSkew = 2 * time.Minute
+
+// getOfflineSyncMaxAge membaca batas umur struk dari env OFFLINE_SYNC_MAX_AGE_HOURS
+func getOfflineSyncMaxAge() time.Duration {
+	if v := os.Getenv("OFFLINE_SYNC_MAX_AGE_HOURS"); v != "" {
+		if hours, err := strconv.Atoi(v); err == nil && hours > 0 {
+			return time.Duration(hours) * time.Hour
+		}
+	}
+	return defaultOfflineSyncMaxAge * time.Hour
+}
+
+// offlineReceipt - struk scan yang disimpan perangkat saat tidak ada koneksi
+type offlineReceipt struct {
+	QRPayload  string   `json:"qr_payload" binding:"required"`
+	CapturedAt int64    `json:"captured_at" binding:"required"`
+	Signature  string   `json:"signature" binding:"required"`
+	Latitude   *float64 `json:"latitude"`
+	Longitude  *float64 `json:"longitude"`
+	Accuracy   *float64 `json:"accuracy"`
+}
+
+// GetOfflineReceiptKey - Kunci penandatangan struk offline untuk perangkat mahasiswa.
+// Perangkat harus terdaftar; kunci hanya berlaku untuk pasangan mahasiswa dan perangkat ini.
+// Karena kunci dipegang perangkat, struk hanya membuktikan asal perangkat, bukan waktu scan.
+func GetOfflineReceiptKey(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	deviceID := c.Query("device_id")
+	if deviceID == "" {
+		utils.ValidationError(c, "device_id diperlukan")
+		return
+	}
+
+	var mahasiswaID int
+	err := config.DB.QueryRow("SELECT id FROM mahasiswa WHERE user_id = ?", userID).Scan(&mahasiswaID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Mahasiswa not found")
+		return
+	}
+
+	fingerprint := hashDeviceFingerprint(deviceID)
+	if reason := bindMahasiswaDevice(mahasiswaID, fingerprint, c.Query("device_name")); reason != "" {
+		utils.ErrorResponse(c, http.StatusForbidden, reason)
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"student_id":    mahasiswaID,
+		"receipt_key":   utils.OfflineReceiptKey(mahasiswaID, fingerprint),
+		"algorithm":     "HMAC-SHA256",
+		"message":       "qr_payload|student_id|captured_at",
+		"max_age_hours": int(getOfflineSyncMaxAge().Hours()),
+	}, "Kunci struk offline berhasil diambil")
+}
+
+// SyncOfflineAttendance - Sinkronkan struk scan offline. Setiap struk divalidasi terpisah:
+// tanda tangan struk, tanda tangan QR pada waktu scan, jendela sesi dan kebijakan absensi.
+// Absensi yang diterima ditandai offline_synced dan belum dihitung sampai ditinjau dosen.
+func SyncOfflineAttendance(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		DeviceID   string           `json:"device_id" binding:"required"`
+		DeviceName string           `json:"device_name"`
+		Receipts   []offlineReceipt `json:"receipts" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	if len(input.Receipts) > maxOfflineReceiptsPerSync {
+		utils.ValidationError(c, fmt.Sprintf("Maksimal %d struk per sinkronisasi", maxOfflineReceiptsPerSync))
+		return
+	}
+
+	var mahasiswaID int
+	err := config.DB.QueryRow("SELECT id FROM mahasiswa WHERE user_id = ?", userID).Scan(&mahasiswaID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Mahasiswa not found")
+		return
+	}
+
+	fingerprint := hashDeviceFingerprint(input.DeviceID)
+	if reason := bindMahasiswaDevice(mahasiswaID, fingerprint, input.DeviceName); reason != "" {
+		utils.ErrorResponse(c, http.StatusForbidden, reason)
+		return
+	}
+
+	key := utils.OfflineReceiptKey(mahasiswaID, fingerprint)
+	now := time.Now()
+
+	var results []gin.H
+	accepted := 0
+	for i, receipt := range input.Receipts {
+		result := syncOfflineReceipt(c.GetInt("user_id"), mahasiswaID, fingerprint, key, receipt, now)
+		result["index"] = i
+		if result["accepted"].(bool) {
+			accepted++
+		}
+		results = append(results, result)
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"results":  results,
+		"total":    len(results),
+		"accepted": accepted,
+		"rejected": len(results) - accepted,
+	}, fmt.Sprintf("%d dari %d struk offline berhasil disinkronkan", accepted, len(results)))
+}
+
+// syncOfflineReceipt - Validasi dan catat satu struk offline.
+// captured_at dipilih perangkat: kunci struk ada di perangkat, jadi mahasiswa bisa menandatangani
+// waktu berapa pun. Tanda tangan hanya membuktikan struk dibuat perangkat terdaftar; pengecekan
+// QR, jendela sesi dan kebijakan di bawah sekadar membatasi klaim waktu tersebut. Karena itu
+// absensi disimpan dengan offline_review 'pending' dan tidak masuk attendance_summary, persentase,
+// alert maupun nilai sampai diterima lewat ReviewOfflineAttendance.
+func syncOfflineReceipt(userID, mahasiswaID int, fingerprint, key string, receipt offlineReceipt, now time.Time) gin.H {
+	reject := func(reason string) gin.H {
+		return gin.H{"accepted": false, "reason": reason}
+	}
+
+	capturedAt := time.Unix(receipt.CapturedAt, 0)
+	if capturedAt.After(now.Add(offlineClockSkew)) {
+		return reject("Waktu scan berada di masa depan")
+	}
+	if now.Sub(capturedAt) > getOfflineSyncMaxAge() {
+		return reject(fmt.Sprintf("Struk sudah lebih dari %d jam dan tidak dapat disinkronkan", int(getOfflineSyncMaxAge().Hours())))
+	}
+
+	if !utils.VerifyOfflineReceipt(key, receipt.QRPayload, mahasiswaID, receipt.CapturedAt, receipt.Signature) {
+		return reject("Tanda tangan struk tidak valid")
+	}
+
+	// QR harus sah dan masih berlaku pada saat scan dilakukan
+	payload, err := utils.VerifyQRPayload(receipt.QRPayload, capturedAt)
+	if err == utils.ErrQRPayloadExpired {
+		return reject("QR Code sudah kadaluarsa saat discan")
+	}
+	if err != nil {
+		return reject("QR Code tidak valid")
+	}
+
+	var createdAt, expiresAt time.Time
+	err = config.DB.QueryRow(`
+		SELECT created_at, expires_at
+		FROM attendance_sessions
+		WHERE id = ? AND course_id = ? AND pertemuan_ke = ?
+	`, payload.SessionID, payload.CourseID, payload.PertemuanKe).Scan(&createdAt, &expiresAt)
+	if err != nil {
+		return reject("Sesi absensi tidak ditemukan")
+	}
+
+	if capturedAt.Before(createdAt) || !capturedAt.Before(expiresAt) {
+		return reject("Waktu scan di luar jendela sesi absensi")
+	}
+
+	var enrolled bool
+	config.DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM mahasiswa_mata_kuliah
+			WHERE mahasiswa_id = ? AND mata_kuliah_kode = ?
+		)
+	`, mahasiswaID, payload.CourseID).Scan(&enrolled)
+	if !enrolled {
+		return reject("Anda tidak terdaftar pada mata kuliah ini")
+	}
+
+	schedule, err := resolveSchedule(payload.CourseID, payload.PertemuanKe)
+	if err != nil {
+		return reject("Jadwal mata kuliah tidak ditemukan")
+	}
+
+	location := geofenceLocation{Latitude: receipt.Latitude, Longitude: receipt.Longitude, Accuracy: receipt.Accuracy}
+	if reason := checkGeofence(payload.SessionID, mahasiswaID, payload.CourseID, schedule.Ruangan, location); reason != "" {
+		return reject(reason)
+	}
+
+	// Status ditentukan dari waktu scan, bukan waktu sinkronisasi
+	policy := loadAttendancePolicy(payload.CourseID)
+	status, reason := policy.evaluateScan(capturedAt, schedule)
+	if reason != "" {
+		return reject(reason)
+	}
+
+	result := gin.H{
+		"session_id":   payload.SessionID,
+		"course_id":    payload.CourseID,
+		"pertemuan_ke": payload.PertemuanKe,
+		"captured_at":  capturedAt.Format("2006-01-02 15:04:05"),
+	}
+
+	// Alpa dari penutupan sesi boleh ditimpa; status lain berarti sudah tercatat
+	var existingID int
+	var existingStatus string
+	config.DB.QueryRow(`
+		SELECT a.id, a.status
+		FROM attendance a
+		JOIN attendance_sessions asess ON a.session_id = asess.id
+		WHERE a.student_id = ? AND asess.course_id = ? AND asess.pertemuan_ke = ?
+		ORDER BY a.status = 'alpa', a.id
+		LIMIT 1
+	`, mahasiswaID, payload.CourseID, payload.PertemuanKe).Scan(&existingID, &existingStatus)
+
+	if existingID > 0 && existingStatus != "alpa" {
+		result["accepted"] = false
+		result["duplicate"] = true
+		result["status"] = existingStatus
+		result["reason"] = "Sudah tercatat dengan status: " + getStatusLabel(existingStatus)
+		return result
+	}
+
+	// Alpa yang ditetapkan dosen secara manual tidak ditimpa sinkronisasi
+	if existingID > 0 {
+		var lastSource string
+		config.DB.QueryRow(`
+			SELECT source FROM attendance_audit WHERE attendance_id = ? ORDER BY id DESC LIMIT 1
+		`, existingID).Scan(&lastSource)
+		if lastSource == auditSourceManual {
+			return reject("Status Alpa sudah ditetapkan dosen. Hubungi dosen pengampu")
+		}
+	}
+
+	if err := saveOfflineAttendance(payload.SessionID, mahasiswaID, payload.PertemuanKe, status, fingerprint, capturedAt, userID); err != nil {
+		return reject("Gagal mencatat absensi: " + err.Error())
+	}
+
+	publishAttendanceEvent(payload.SessionID, mahasiswaID, status, "offline_sync")
+
+	result["accepted"] = true
+	result["review"] = "pending"
+	result["status"] = status
+	result["status_label"] = getStatusLabel(status)
+	return result
+}
+
+// saveOfflineAttendance - Insert absensi hasil sinkronisasi atau timpa alpa pada sesi yang sama
+func saveOfflineAttendance(sessionID, studentID, pertemuanKe int, status, fingerprint string, capturedAt time.Time, userID int) error {
+	var attendanceID int
+	var oldStatus string
+	config.DB.QueryRow(`
+		SELECT id, status FROM attendance WHERE student_id = ? AND session_id = ? LIMIT 1
+	`, studentID, sessionID).Scan(&attendanceID, &oldStatus)
+
+	if attendanceID > 0 {
+		_, err := config.DB.Exec(`
+			UPDATE attendance
+			SET status = ?, device_fingerprint = ?, offline_synced = 1,
+				offline_captured_at = ?, offline_review = 'pending', updated_at = NOW()
+			WHERE id = ?
+		`, status, fingerprint, capturedAt, attendanceID)
+		if err != nil {
+			return err
+		}
+	} else {
+		var studentCode string
+		config.DB.QueryRow("SELECT nim FROM mahasiswa WHERE id = ?", studentID).Scan(&studentCode)
+
+		result, err := config.DB.Exec(`
+			INSERT INTO attendance
+			(student_id, session_id, student_code, status, pertemuan_ke, device_fingerprint,
+			 offline_synced, offline_captured_at, offline_review, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, 1, ?, 'pending', NOW())
+		`, studentID, sessionID, studentCode, status, pertemuanKe, fingerprint, capturedAt)
+		if err != nil {
+			return err
+		}
+		insertedID, _ := result.LastInsertId()
+		attendanceID = int(insertedID)
+	}
+
+	recordAttendanceAudit(attendanceID, sessionID, studentID, oldStatus, status, attendanceChange{
+		Source:    auditSourceOfflineSync,
+		ChangedBy: userID,
+		Reason:    "Scan offline pada " + capturedAt.Format("2006-01-02 15:04:05"),
+	})
+	return nil
+}
+
+// GetOfflineSyncedAttendance - Daftar absensi hasil sinkronisasi offline pada sesi (dosen)
+func GetOfflineSyncedAttendance(c *gin.Context) {
+	sessionID, err := strconv.Atoi(c.Param("session_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid session ID")
+		return
+	}
+
+	if !verifyDosenSession(c, sessionID) {
+		return
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT a.student_id, m.nim, m.name, a.status, COALESCE(a.offline_review, 'pending'),
+		       COALESCE(DATE_FORMAT(a.offline_captured_at, '%Y-%m-%d %H:%i:%s'), ''),
+		       DATE_FORMAT(COALESCE(a.updated_at, a.created_at), '%Y-%m-%d %H:%i:%s'),
+		       COALESCE(a.device_fingerprint, '')
+		FROM attendance a
+		JOIN mahasiswa m ON a.student_id = m.id
+		WHERE a.session_id = ? AND a.offline_synced = 1
+		ORDER BY a.offline_captured_at
+	`, sessionID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil absensi offline: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	var records []gin.H
+	pending := 0
+	for rows.Next() {
+		var studentID int
+		var nim, name, status, review, capturedAt, syncedAt, fingerprint string
+		if err := rows.Scan(&studentID, &nim, &name, &status, &review, &capturedAt, &syncedAt, &fingerprint); err != nil {
+			continue
+		}
+		if review == "pending" {
+			pending++
+		}
+		records = append(records, gin.H{
+			"student_id":         studentID,
+			"nim":                nim,
+			"name":               name,
+			"status":             status,
+			"status_label":       getStatusLabel(status),
+			"review":             review,
+			"captured_at":        capturedAt,
+			"synced_at":          syncedAt,
+			"device_fingerprint": fingerprint,
+		})
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"session_id": sessionID,
+		"records":    records,
+		"total":      len(records),
+		"pending":    pending,
+	}, "Absensi offline berhasil diambil")
+}
+
+// ReviewOfflineAttendance - Terima atau tolak absensi hasil sinkronisasi offline.
+// Penerimaan baru memasukkan absensi ke attendance_summary dan perhitungan kehadiran;
+// penolakan mengubah status menjadi alpa. Tanpa student_ids, semua yang pending ditinjau.
+func ReviewOfflineAttendance(c *gin.Context) {
+	sessionID, err := strconv.Atoi(c.Param("session_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid session ID")
+		return
+	}
+
+	var input struct {
+		StudentIDs []int  `json:"student_ids"`
+		Action     string `json:"action" binding:"required,oneof=accept reject"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	if !verifyDosenSession(c, sessionID) {
+		return
+	}
+
+	var courseID string
+	var pertemuanKe int
+	err = config.DB.QueryRow(`
+		SELECT course_id, pertemuan_ke FROM attendance_sessions WHERE id = ?
+	`, sessionID).Scan(&courseID, &pertemuanKe)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Sesi tidak ditemukan")
+		return
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT student_id, status FROM attendance
+		WHERE session_id = ? AND offline_synced = 1 AND COALESCE(offline_review, 'pending') = 'pending'
+	`, sessionID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil absensi offline: "+err.Error())
+		return
+	}
+
+	pending := map[int]string{}
+	for rows.Next() {
+		var studentID int
+		var status string
+		if rows.Scan(&studentID, &status) == nil {
+			pending[studentID] = status
+		}
+	}
+	rows.Close()
+
+	targets := input.StudentIDs
+	if len(targets) == 0 {
+		for studentID := range pending {
+			targets = append(targets, studentID)
+		}
+	}
+
+	review := "accepted"
+	if input.Action == "reject" {
+		review = "rejected"
+	}
+
+	var reviewed []int
+	for _, studentID := range targets {
+		status, ok := pending[studentID]
+		if !ok {
+			continue
+		}
+		if input.Action == "reject" {
+			if _, err := saveAttendanceStatus(sessionID, studentID, courseID, pertemuanKe, "alpa", attendanceChange{
+				Source:    auditSourceManual,
+				ChangedBy: c.GetInt("user_id"),
+				Reason:    "Absensi offline ditolak dosen",
+			}); err != nil {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menolak absensi: "+err.Error())
+				return
+			}
+			publishAttendanceEvent(sessionID, studentID, "alpa", "manual")
+		}
+		config.DB.Exec(`
+			UPDATE attendance SET offline_review = ? WHERE session_id = ? AND student_id = ?
+		`, review, sessionID, studentID)
+		if input.Action == "accept" {
+			syncAttendanceSummary(sessionID, studentID, courseID, status)
+		}
+		reviewed = append(reviewed, studentID)
+	}
+
+	if len(reviewed) > 0 {
+		checkAttendanceThresholds(courseID)
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"session_id":   sessionID,
+		"pertemuan_ke": pertemuanKe,
+		"action":       input.Action,
+		"reviewed":     reviewed,
+		"total":        len(reviewed),
+	}, fmt.Sprintf("%d absensi offline ditinjau", len(reviewed)))
+}
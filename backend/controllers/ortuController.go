@@ -97,6 +97,7 @@ func GetChildAttendance(c *gin.Context) {
 			DATE_FORMAT(a.created_at, '%H:%i') as jam,
 			CASE 
 				WHEN a.status = 'hadir' THEN 'Hadir'
+				WHEN a.status = 'terlambat' THEN 'Terlambat'
 				WHEN a.status = 'izin' THEN 'Izin'
 				WHEN a.status = 'sakit' THEN 'Sakit'
 				WHEN a.status = 'alpa' THEN 'Alpa'
@@ -109,7 +110,7 @@ func GetChildAttendance(c *gin.Context) {
 		JOIN attendance_sessions ases ON a.session_id = ases.id
 		JOIN mata_kuliah mk ON ases.course_id = mk.kode
 		JOIN dosen d ON mk.dosen_id = d.id
-		WHERE a.student_id = ?
+		WHERE a.student_id = ? AND ` + countedAttendance("a") + `
 		ORDER BY a.created_at DESC
 		LIMIT 100
 	`
@@ -125,6 +126,7 @@ func GetChildAttendance(c *gin.Context) {
 	var summary struct {
 		Total       int `json:"total"`
 		Hadir       int `json:"hadir"`
+		Terlambat   int `json:"terlambat"`
 		Izin        int `json:"izin"`
 		Sakit       int `json:"sakit"`
 		Alpa        int `json:"alpa"`
@@ -145,6 +147,8 @@ func GetChildAttendance(c *gin.Context) {
 		switch status {
 		case "hadir":
 			summary.Hadir++
+		case "terlambat":
+			summary.Terlambat++
 		case "izin":
 			summary.Izin++
 		case "sakit":
@@ -169,7 +173,7 @@ func GetChildAttendance(c *gin.Context) {
 
 	// Hitung persentase kehadiran
 	if summary.Total > 0 {
-		summary.Persentase = float64(summary.Hadir) / float64(summary.Total) * 100
+		summary.Persentase = float64(summary.Hadir+summary.Terlambat) / float64(summary.Total) * 100
 	}
 
 	// Get child info
@@ -255,6 +259,13 @@ func GetChildAttendanceToday(c *gin.Context) {
 	}
 	defer rows.Close()
 
+	// Pertemuan pengganti hari ini menggantikan jadwal reguler mata kuliah yang sama
+	pengganti := listPenggantiToday(childID)
+	penggantiCourses := map[string]bool{}
+	for _, p := range pengganti {
+		penggantiCourses[p.CourseID] = true
+	}
+
 	var todaySchedule []gin.H
 	for rows.Next() {
 		var kode, mataKuliah, hari, jamMulai, jamSelesai, dosen, statusAbsen, waktuAbsen string
@@ -264,6 +275,10 @@ func GetChildAttendanceToday(c *gin.Context) {
 			continue
 		}
 
+		if penggantiCourses[kode] {
+			continue
+		}
+
 		todaySchedule = append(todaySchedule, gin.H{
 			"kode":         kode,
 			"mata_kuliah":  mataKuliah,
@@ -273,6 +288,23 @@ func GetChildAttendanceToday(c *gin.Context) {
 			"dosen":        dosen,
 			"status_absen": statusAbsen,
 			"waktu_absen":  waktuAbsen,
+			"is_pengganti": false,
+		})
+	}
+
+	for _, p := range pengganti {
+		todaySchedule = append(todaySchedule, gin.H{
+			"kode":         p.CourseID,
+			"mata_kuliah":  p.CourseName,
+			"hari":         hariIni,
+			"jam_mulai":    p.JamMulai,
+			"jam_selesai":  p.JamSelesai,
+			"dosen":        p.Dosen,
+			"status_absen": p.StatusAbsen,
+			"waktu_absen":  p.WaktuAbsen,
+			"is_pengganti": true,
+			"pertemuan_ke": p.PertemuanKe,
+			"ruangan":      p.Ruangan,
 		})
 	}
 
@@ -403,7 +435,18 @@ func GetChildAcademicInfo(c *gin.Context) {
 		})
 	}
 
+	// KHS, IPS dan IPK anak (hanya nilai yang sudah dikunci dosen)
+	academic, err := academicSummary(childID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch academic info")
+		return
+	}
+
 	utils.SuccessResponse(c, gin.H{
-		"courses": courses,
+		"courses":   courses,
+		"khs":       academic["khs"],
+		"total_sks": academic["total_sks"],
+		"sks_lulus": academic["sks_lulus"],
+		"ipk":       academic["ipk"],
 	}, "Informasi akademik anak retrieved")
 }
\ No newline at end of file
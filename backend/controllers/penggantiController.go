@@ -0,0 +1,353 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// courseSchedule - Jadwal yang berlaku untuk satu pertemuan: jadwal reguler mata kuliah,
+// atau pertemuan pengganti jika pertemuan tersebut dijadwal ulang
+type courseSchedule struct {
+	CourseID    string
+	Hari        string
+	JamMulai    string
+	JamSelesai  string
+	Ruangan     string
+	Tanggal     time.Time
+	PenggantiID int
+}
+
+// isPengganti - Apakah jadwal berasal dari pertemuan pengganti
+func (s courseSchedule) isPengganti() bool {
+	return s.PenggantiID > 0
+}
+
+// fallsOn - Apakah jadwal jatuh pada hari day
+func (s courseSchedule) fallsOn(day time.Time) bool {
+	if s.isPengganti() {
+		y1, m1, d1 := s.Tanggal.Date()
+		y2, m2, d2 := day.Date()
+		return y1 == y2 && m1 == m2 && d1 == d2
+	}
+
+	courseDay, ok := hariToWeekday[s.Hari]
+	return ok && courseDay == day.Weekday()
+}
+
+// resolveSchedule - Jadwal yang berlaku untuk pertemuan ke-n sebuah mata kuliah pada semester aktif
+func resolveSchedule(courseID string, pertemuanKe int) (courseSchedule, error) {
+	s := courseSchedule{CourseID: courseID}
+
+	var ruangan sql.NullString
+	err := config.DB.QueryRow(`
+		SELECT id, tanggal, jam_mulai, jam_selesai, ruangan
+		FROM pertemuan_pengganti
+		WHERE course_id = ? AND pertemuan_ke = ? AND `+semesterFilter("semester_id")+`
+	`, append([]interface{}{courseID, pertemuanKe}, semesterArgs(currentSemesterID())...)...).
+		Scan(&s.PenggantiID, &s.Tanggal, &s.JamMulai, &s.JamSelesai, &ruangan)
+	if err == nil {
+		s.Hari = hariIndonesia(s.Tanggal)
+		s.Ruangan = ruangan.String
+		if s.Ruangan == "" {
+			s.Ruangan = getCourseRoom(courseID)
+		}
+		return s, nil
+	}
+
+	err = config.DB.QueryRow(`
+		SELECT hari, jam_mulai, jam_selesai FROM mata_kuliah WHERE kode = ?
+	`, courseID).Scan(&s.Hari, &s.JamMulai, &s.JamSelesai)
+	if err != nil {
+		return s, err
+	}
+
+	s.Ruangan = getCourseRoom(courseID)
+	return s, nil
+}
+
+// hariIndonesia - Nama hari dalam bahasa Indonesia untuk t
+func hariIndonesia(t time.Time) string {
+	for hari, weekday := range hariToWeekday {
+		if weekday == t.Weekday() {
+			return hari
+		}
+	}
+	return ""
+}
+
+// penggantiToday - Pertemuan pengganti hari ini untuk seorang mahasiswa
+type penggantiToday struct {
+	ID          int
+	CourseID    string
+	CourseName  string
+	Dosen       string
+	SKS         int
+	PertemuanKe int
+	JamMulai    string
+	JamSelesai  string
+	Ruangan     string
+	StatusAbsen string
+	WaktuAbsen  string
+	SessionCode string
+}
+
+// listPenggantiToday - Pertemuan pengganti yang dijadwalkan hari ini pada mata kuliah yang diambil mahasiswa
+func listPenggantiToday(studentID int) []penggantiToday {
+	rows, err := config.DB.Query(`
+		SELECT DISTINCT pp.id, pp.course_id, mk.nama, d.name, mk.sks, pp.pertemuan_ke,
+		       pp.jam_mulai, pp.jam_selesai, COALESCE(pp.ruangan, '')
+		FROM pertemuan_pengganti pp
+		JOIN mata_kuliah mk ON pp.course_id = mk.kode
+		JOIN dosen d ON mk.dosen_id = d.id
+		JOIN mahasiswa_mata_kuliah mmk ON mmk.mata_kuliah_kode = pp.course_id
+		WHERE mmk.mahasiswa_id = ? AND pp.tanggal = CURDATE()
+		ORDER BY pp.jam_mulai
+	`, studentID)
+	if err != nil {
+		return nil
+	}
+
+	var list []penggantiToday
+	for rows.Next() {
+		var p penggantiToday
+		if rows.Scan(&p.ID, &p.CourseID, &p.CourseName, &p.Dosen, &p.SKS, &p.PertemuanKe,
+			&p.JamMulai, &p.JamSelesai, &p.Ruangan) == nil {
+			list = append(list, p)
+		}
+	}
+	rows.Close()
+
+	for i := range list {
+		p := &list[i]
+		p.StatusAbsen = "belum_absen"
+		config.DB.QueryRow(`
+			SELECT a.status, COALESCE(TIME_FORMAT(a.created_at, '%H:%i'), '')
+			FROM attendance a
+			JOIN attendance_sessions asess ON a.session_id = asess.id
+			WHERE a.student_id = ? AND asess.course_id = ? AND asess.pertemuan_ke = ?
+			ORDER BY a.created_at DESC
+			LIMIT 1
+		`, studentID, p.CourseID, p.PertemuanKe).Scan(&p.StatusAbsen, &p.WaktuAbsen)
+
+		config.DB.QueryRow(`
+			SELECT session_code FROM attendance_sessions
+			WHERE course_id = ? AND pertemuan_ke = ? AND status = 'active' AND expires_at > NOW()
+			ORDER BY created_at DESC
+			LIMIT 1
+		`, p.CourseID, p.PertemuanKe).Scan(&p.SessionCode)
+	}
+
+	return list
+}
+
+// penggantiInput - Body request membuat/mengubah pertemuan pengganti
+type penggantiInput struct {
+	PertemuanKe int    `json:"pertemuan_ke" binding:"required,min=1,max=16"`
+	Tanggal     string `json:"tanggal" binding:"required"`
+	JamMulai    string `json:"jam_mulai" binding:"required"`
+	JamSelesai  string `json:"jam_selesai" binding:"required"`
+	Ruangan     string `json:"ruangan"`
+	Alasan      string `json:"alasan"`
+}
+
+// validate - Cek format tanggal dan jam pertemuan pengganti
+func (in penggantiInput) validate() string {
+	if _, err := time.Parse("2006-01-02", in.Tanggal); err != nil {
+		return "Format tanggal harus YYYY-MM-DD"
+	}
+
+	start, ok1 := parseClock(in.JamMulai)
+	end, ok2 := parseClock(in.JamSelesai)
+	if !ok1 || !ok2 {
+		return "Format jam harus HH:MM"
+	}
+	if !end.After(start) {
+		return "Jam selesai harus setelah jam mulai"
+	}
+	return ""
+}
+
+// GetPertemuanPengganti - Daftar pertemuan pengganti sebuah mata kuliah pada semester aktif
+func GetPertemuanPengganti(c *gin.Context) {
+	courseID := c.Param("course_id")
+	if _, ok := getDosenCourseAccess(c, courseID); !ok {
+		return
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT id, pertemuan_ke, tanggal, jam_mulai, jam_selesai, COALESCE(ruangan, ''), COALESCE(alasan, '')
+		FROM pertemuan_pengganti
+		WHERE course_id = ? AND `+semesterFilter("semester_id")+`
+		ORDER BY tanggal, jam_mulai
+	`, append([]interface{}{courseID}, semesterArgs(currentSemesterID())...)...)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil pertemuan pengganti: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	list := []gin.H{}
+	for rows.Next() {
+		var id, pertemuanKe int
+		var tanggal time.Time
+		var jamMulai, jamSelesai, ruangan, alasan string
+
+		if err := rows.Scan(&id, &pertemuanKe, &tanggal, &jamMulai, &jamSelesai, &ruangan, &alasan); err != nil {
+			continue
+		}
+
+		list = append(list, gin.H{
+			"id":           id,
+			"course_id":    courseID,
+			"pertemuan_ke": pertemuanKe,
+			"tanggal":      tanggal.Format("2006-01-02"),
+			"hari":         hariIndonesia(tanggal),
+			"jam_mulai":    jamMulai,
+			"jam_selesai":  jamSelesai,
+			"ruangan":      ruangan,
+			"alasan":       alasan,
+		})
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"course_id": courseID,
+		"pengganti": list,
+		"total":     len(list),
+	}, "Pertemuan pengganti berhasil diambil")
+}
+
+// CreatePertemuanPengganti - Jadwalkan ulang satu pertemuan (kelas pengganti)
+func CreatePertemuanPengganti(c *gin.Context) {
+	courseID := c.Param("course_id")
+
+	var input penggantiInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+	if msg := input.validate(); msg != "" {
+		utils.ValidationError(c, msg)
+		return
+	}
+
+	dosenID, ok := getDosenCourseAccess(c, courseID)
+	if !ok {
+		return
+	}
+
+	result, err := config.DB.Exec(`
+		INSERT INTO pertemuan_pengganti
+		(course_id, semester_id, pertemuan_ke, tanggal, jam_mulai, jam_selesai, ruangan, alasan, dosen_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, NOW())
+	`, courseID, nullInt(currentSemesterID()), input.PertemuanKe, input.Tanggal, input.JamMulai, input.JamSelesai,
+		input.Ruangan, input.Alasan, dosenID)
+	if config.IsDuplicateKeyError(err) {
+		utils.ErrorResponse(c, http.StatusBadRequest,
+			"Pertemuan ke-"+strconv.Itoa(input.PertemuanKe)+" sudah memiliki jadwal pengganti")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan pertemuan pengganti: "+err.Error())
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	utils.SuccessResponse(c, gin.H{
+		"id":           id,
+		"course_id":    courseID,
+		"pertemuan_ke": input.PertemuanKe,
+		"tanggal":      input.Tanggal,
+		"jam_mulai":    input.JamMulai,
+		"jam_selesai":  input.JamSelesai,
+		"ruangan":      input.Ruangan,
+	}, "Pertemuan pengganti berhasil dijadwalkan")
+}
+
+// UpdatePertemuanPengganti - Ubah jadwal pertemuan pengganti
+func UpdatePertemuanPengganti(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid ID")
+		return
+	}
+
+	var input penggantiInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+	if msg := input.validate(); msg != "" {
+		utils.ValidationError(c, msg)
+		return
+	}
+
+	var courseID string
+	if err := config.DB.QueryRow("SELECT course_id FROM pertemuan_pengganti WHERE id = ?", id).Scan(&courseID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Pertemuan pengganti tidak ditemukan")
+		return
+	}
+
+	if _, ok := getDosenCourseAccess(c, courseID); !ok {
+		return
+	}
+
+	_, err = config.DB.Exec(`
+		UPDATE pertemuan_pengganti
+		SET pertemuan_ke = ?, tanggal = ?, jam_mulai = ?, jam_selesai = ?,
+		    ruangan = NULLIF(?, ''), alasan = ?, updated_at = NOW()
+		WHERE id = ?
+	`, input.PertemuanKe, input.Tanggal, input.JamMulai, input.JamSelesai, input.Ruangan, input.Alasan, id)
+	if config.IsDuplicateKeyError(err) {
+		utils.ErrorResponse(c, http.StatusBadRequest,
+			"Pertemuan ke-"+strconv.Itoa(input.PertemuanKe)+" sudah memiliki jadwal pengganti")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan pertemuan pengganti: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"id":           id,
+		"course_id":    courseID,
+		"pertemuan_ke": input.PertemuanKe,
+		"tanggal":      input.Tanggal,
+		"jam_mulai":    input.JamMulai,
+		"jam_selesai":  input.JamSelesai,
+		"ruangan":      input.Ruangan,
+	}, "Pertemuan pengganti berhasil diubah")
+}
+
+// DeletePertemuanPengganti - Batalkan pertemuan pengganti (kembali ke jadwal reguler)
+func DeletePertemuanPengganti(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid ID")
+		return
+	}
+
+	var courseID string
+	if err := config.DB.QueryRow("SELECT course_id FROM pertemuan_pengganti WHERE id = ?", id).Scan(&courseID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Pertemuan pengganti tidak ditemukan")
+		return
+	}
+
+	if _, ok := getDosenCourseAccess(c, courseID); !ok {
+		return
+	}
+
+	if _, err := config.DB.Exec("DELETE FROM pertemuan_pengganti WHERE id = ?", id); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus pertemuan pengganti: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Pertemuan pengganti berhasil dihapus")
+}
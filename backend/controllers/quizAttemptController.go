@@ -0,0 +1,979 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// quizSubmitGrace - toleransi jaringan setelah batas waktu; jawaban yang masuk setelahnya diabaikan
+const quizSubmitGrace = 60 * time.Second
+
+// quizAttempt - satu percobaan mahasiswa. Urutan soal/opsi diacak sekali saat mulai dan disimpan
+// agar tampilan tetap sama ketika halaman dimuat ulang.
+type quizAttempt struct {
+	ID            int
+	QuizID        int
+	StudentID     int
+	AttemptNo     int
+	StartedAt     time.Time
+	DeadlineAt    sql.NullTime
+	SubmittedAt   sql.NullTime
+	Status        string // in_progress, submitted (menunggu penilaian esai), graded
+	Score         float64
+	MaxScore      float64
+	Percentage    float64
+	QuestionOrder []int
+	OptionOrder   map[int][]int
+}
+
+// quizAnswer - jawaban satu soal pada percobaan
+type quizAnswer struct {
+	QuestionID    int
+	OptionID      sql.NullInt64
+	AnswerText    string
+	IsCorrect     sql.NullBool
+	PointsAwarded sql.NullFloat64
+	Feedback      string
+}
+
+func (a quizAttempt) expired(now time.Time) bool {
+	return a.DeadlineAt.Valid && now.After(a.DeadlineAt.Time.Add(quizSubmitGrace))
+}
+
+func (a quizAttempt) toResponse() gin.H {
+	response := gin.H{
+		"attempt_id":   a.ID,
+		"attempt_no":   a.AttemptNo,
+		"status":       a.Status,
+		"started_at":   a.StartedAt.Format("2006-01-02 15:04:05"),
+		"deadline_at":  formatNullTime(a.DeadlineAt),
+		"submitted_at": formatNullTime(a.SubmittedAt),
+	}
+	if a.Status != "in_progress" {
+		response["score"] = a.Score
+		response["max_score"] = a.MaxScore
+		response["percentage"] = a.Percentage
+	}
+	return response
+}
+
+const quizAttemptSelect = `
+	SELECT id, quiz_id, student_id, attempt_no, started_at, deadline_at, submitted_at, status,
+		COALESCE(score, 0), COALESCE(max_score, 0), COALESCE(percentage, 0), question_order, option_order
+	FROM quiz_attempts`
+
+// loadQuizAttempts - percobaan sesuai kondisi, urut nomor percobaan
+func loadQuizAttempts(where string, args ...interface{}) ([]quizAttempt, error) {
+	rows, err := config.DB.Query(quizAttemptSelect+" WHERE "+where+" ORDER BY student_id, attempt_no", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []quizAttempt
+	for rows.Next() {
+		var a quizAttempt
+		var questionOrder, optionOrder string
+		if err := rows.Scan(&a.ID, &a.QuizID, &a.StudentID, &a.AttemptNo, &a.StartedAt, &a.DeadlineAt, &a.SubmittedAt,
+			&a.Status, &a.Score, &a.MaxScore, &a.Percentage, &questionOrder, &optionOrder); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(questionOrder), &a.QuestionOrder)
+		json.Unmarshal([]byte(optionOrder), &a.OptionOrder)
+		attempts = append(attempts, a)
+	}
+	return attempts, nil
+}
+
+// loadQuizAnswers - jawaban percobaan per question_id
+func loadQuizAnswers(attemptID int) (map[int]quizAnswer, error) {
+	rows, err := config.DB.Query(`
+		SELECT question_id, option_id, COALESCE(answer_text, ''), is_correct, points_awarded, COALESCE(feedback, '')
+		FROM quiz_answers WHERE attempt_id = ?
+	`, attemptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	answers := map[int]quizAnswer{}
+	for rows.Next() {
+		var a quizAnswer
+		if err := rows.Scan(&a.QuestionID, &a.OptionID, &a.AnswerText, &a.IsCorrect, &a.PointsAwarded, &a.Feedback); err != nil {
+			return nil, err
+		}
+		answers[a.QuestionID] = a
+	}
+	return answers, nil
+}
+
+// gradeQuizAttempt menilai otomatis soal objektif dan menutup percobaan.
+// Percobaan dengan jawaban esai tetap berstatus submitted sampai dosen menilainya.
+func gradeQuizAttempt(q *quiz, attemptID int, submittedAt time.Time) error {
+	answers, err := loadQuizAnswers(attemptID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, question := range q.Questions {
+		answer := answers[question.ID]
+		// Esai kosong langsung bernilai 0 sehingga tidak perlu menunggu penilaian dosen
+		if !question.autoGraded() && strings.TrimSpace(answer.AnswerText) != "" {
+			continue
+		}
+		correct := false
+		switch question.Type {
+		case questionShortAnswer:
+			given := normalizeShortAnswer(answer.AnswerText)
+			for _, accepted := range question.Accepted {
+				if given != "" && given == normalizeShortAnswer(accepted) {
+					correct = true
+				}
+			}
+		default:
+			for _, o := range question.Options {
+				if answer.OptionID.Valid && int64(o.ID) == answer.OptionID.Int64 && o.IsCorrect {
+					correct = true
+				}
+			}
+		}
+		points := 0.0
+		if correct {
+			points = question.Points
+		}
+		// Soal yang tidak dijawab tetap dicatat dengan nilai 0
+		if _, err := tx.Exec(`
+			INSERT INTO quiz_answers (attempt_id, question_id, is_correct, points_awarded, updated_at)
+			VALUES (?, ?, ?, ?, NOW())
+			ON DUPLICATE KEY UPDATE is_correct = VALUES(is_correct), points_awarded = VALUES(points_awarded)
+		`, attemptID, question.ID, correct, points); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE quiz_attempts SET submitted_at = ?, status = 'submitted' WHERE id = ? AND status = 'in_progress'
+	`, submittedAt, attemptID); err != nil {
+		return err
+	}
+	if err := refreshAttemptScore(tx, q, attemptID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// refreshAttemptScore menghitung ulang skor percobaan; status menjadi graded jika semua soal sudah bernilai
+func refreshAttemptScore(tx *sql.Tx, q *quiz, attemptID int) error {
+	var score float64
+	var scored int
+	if err := tx.QueryRow(`
+		SELECT COALESCE(SUM(a.points_awarded), 0), COUNT(a.points_awarded)
+		FROM quiz_answers a
+		JOIN quiz_questions qq ON qq.id = a.question_id
+		WHERE a.attempt_id = ? AND qq.quiz_id = ?
+	`, attemptID, q.ID).Scan(&score, &scored); err != nil {
+		return err
+	}
+
+	maxScore := q.maxScore()
+	percentage := 0.0
+	if maxScore > 0 {
+		percentage = roundScore(score / maxScore * 100)
+	}
+	status := "submitted"
+	if scored >= len(q.Questions) {
+		status = "graded"
+	}
+	_, err := tx.Exec(`
+		UPDATE quiz_attempts SET score = ?, max_score = ?, percentage = ?, status = ?
+		WHERE id = ? AND status <> 'in_progress'
+	`, score, maxScore, percentage, status, attemptID)
+	return err
+}
+
+// finalizeExpiredAttempts menutup percobaan yang melewati batas waktu tanpa dikumpulkan.
+// Dipanggil saat kuis diakses sehingga tidak butuh job terjadwal.
+func finalizeExpiredAttempts(q *quiz) {
+	attempts, err := loadQuizAttempts("quiz_id = ? AND status = 'in_progress'", q.ID)
+	if err != nil {
+		return
+	}
+	now := dbNow()
+	for _, a := range attempts {
+		if a.expired(now) {
+			gradeQuizAttempt(q, a.ID, a.DeadlineAt.Time)
+		}
+	}
+}
+
+// quizFinalAttempt - percobaan yang dihitung sesuai aturan scoring kuis (highest/latest)
+func quizFinalAttempt(scoring string, attempts []quizAttempt) *quizAttempt {
+	var final *quizAttempt
+	for i := range attempts {
+		a := &attempts[i]
+		if a.Status == "in_progress" {
+			continue
+		}
+		if final == nil || (scoring == "latest" && a.AttemptNo > final.AttemptNo) ||
+			(scoring != "latest" && a.Percentage > final.Percentage) {
+			final = a
+		}
+	}
+	return final
+}
+
+// studentQuizSummary - ringkasan percobaan mahasiswa untuk daftar kuis
+func studentQuizSummary(q *quiz, studentID int) gin.H {
+	attempts, _ := loadQuizAttempts("quiz_id = ? AND student_id = ?", q.ID, studentID)
+
+	summary := gin.H{
+		"attempts_used":       len(attempts),
+		"attempts_remaining":  nil,
+		"in_progress_attempt": nil,
+		"final_score":         nil,
+		"final_status":        nil,
+	}
+	if q.MaxAttempts > 0 {
+		remaining := q.MaxAttempts - len(attempts)
+		if remaining < 0 {
+			remaining = 0
+		}
+		summary["attempts_remaining"] = remaining
+	}
+	for _, a := range attempts {
+		if a.Status == "in_progress" {
+			summary["in_progress_attempt"] = a.ID
+		}
+	}
+	if final := quizFinalAttempt(q.Scoring, attempts); final != nil {
+		summary["final_score"] = final.Percentage
+		summary["final_status"] = final.Status
+	}
+	return summary
+}
+
+// studentQuiz - kuis :quiz_id yang dipublikasi untuk mata kuliah yang diambil mahasiswa login
+func studentQuiz(c *gin.Context) (*quiz, int, bool) {
+	studentID, ok := mahasiswaIDFromContext(c)
+	if !ok {
+		return nil, 0, false
+	}
+
+	quizID, err := strconv.Atoi(c.Param("quiz_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid quiz ID")
+		return nil, 0, false
+	}
+
+	q, err := loadQuiz(quizID)
+	if err != nil || !q.Published {
+		utils.ErrorResponse(c, http.StatusNotFound, "Kuis tidak ditemukan")
+		return nil, 0, false
+	}
+
+	var enrolled bool
+	config.DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM mahasiswa_mata_kuliah WHERE mata_kuliah_kode = ? AND mahasiswa_id = ?)
+	`, q.CourseID, studentID).Scan(&enrolled)
+	if !enrolled {
+		utils.ErrorResponse(c, http.StatusForbidden, "Anda tidak mengambil mata kuliah ini")
+		return nil, 0, false
+	}
+
+	finalizeExpiredAttempts(q)
+	return q, studentID, true
+}
+
+// inProgressAttempt - percobaan yang sedang berjalan milik mahasiswa, nil jika tidak ada
+func inProgressAttempt(quizID, studentID int) *quizAttempt {
+	attempts, err := loadQuizAttempts("quiz_id = ? AND student_id = ? AND status = 'in_progress'", quizID, studentID)
+	if err != nil || len(attempts) == 0 {
+		return nil
+	}
+	return &attempts[0]
+}
+
+// attemptPaper - lembar soal sesuai urutan acak percobaan, tanpa kunci jawaban, beserta jawaban tersimpan
+func attemptPaper(q *quiz, a *quizAttempt, now time.Time) gin.H {
+	answers, _ := loadQuizAnswers(a.ID)
+
+	byID := map[int]quizQuestion{}
+	for _, question := range q.Questions {
+		byID[question.ID] = question
+	}
+
+	questions := []gin.H{}
+	for i, questionID := range a.QuestionOrder {
+		question, ok := byID[questionID]
+		if !ok {
+			continue
+		}
+		optionByID := map[int]quizOption{}
+		for _, o := range question.Options {
+			optionByID[o.ID] = o
+		}
+		var options []quizOption
+		for _, optionID := range a.OptionOrder[questionID] {
+			if o, ok := optionByID[optionID]; ok {
+				options = append(options, o)
+			}
+		}
+
+		item := question.questionResponse(options, false)
+		item["number"] = i + 1
+		if answer, ok := answers[questionID]; ok {
+			if answer.OptionID.Valid {
+				item["selected_option_id"] = answer.OptionID.Int64
+			}
+			item["answer_text"] = answer.AnswerText
+		}
+		questions = append(questions, item)
+	}
+
+	response := a.toResponse()
+	response["quiz"] = q.settingsResponse()
+	response["questions"] = questions
+	if a.DeadlineAt.Valid {
+		remaining := int(a.DeadlineAt.Time.Sub(now).Seconds())
+		if remaining < 0 {
+			remaining = 0
+		}
+		response["remaining_seconds"] = remaining
+	}
+	return response
+}
+
+// GetMahasiswaQuiz - Info kuis dan ringkasan percobaan mahasiswa (tanpa soal)
+func GetMahasiswaQuiz(c *gin.Context) {
+	q, studentID, ok := studentQuiz(c)
+	if !ok {
+		return
+	}
+
+	response := q.settingsResponse()
+	response["is_open"] = q.isOpen(dbNow())
+	for k, v := range studentQuizSummary(q, studentID) {
+		response[k] = v
+	}
+	utils.SuccessResponse(c, response, "Detail kuis berhasil diambil")
+}
+
+// StartQuiz - Mulai percobaan baru, atau lanjutkan percobaan yang masih berjalan
+func StartQuiz(c *gin.Context) {
+	q, studentID, ok := studentQuiz(c)
+	if !ok {
+		return
+	}
+
+	now := dbNow()
+	if a := inProgressAttempt(q.ID, studentID); a != nil {
+		utils.SuccessResponse(c, attemptPaper(q, a, now), "Melanjutkan percobaan kuis")
+		return
+	}
+
+	if !q.isOpen(now) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Kuis belum dibuka atau sudah ditutup")
+		return
+	}
+
+	var used int
+	config.DB.QueryRow("SELECT COUNT(*) FROM quiz_attempts WHERE quiz_id = ? AND student_id = ?", q.ID, studentID).Scan(&used)
+	if q.MaxAttempts > 0 && used >= q.MaxAttempts {
+		utils.ErrorResponse(c, http.StatusForbidden, fmt.Sprintf("Batas %d kali percobaan sudah tercapai", q.MaxAttempts))
+		return
+	}
+
+	// Batas waktu: durasi kuis, dipotong oleh waktu tutup kuis
+	var deadline sql.NullTime
+	if q.TimeLimitMinutes > 0 {
+		deadline = sql.NullTime{Time: now.Add(time.Duration(q.TimeLimitMinutes) * time.Minute), Valid: true}
+	}
+	if q.ClosesAt.Valid && (!deadline.Valid || q.ClosesAt.Time.Before(deadline.Time)) {
+		deadline = q.ClosesAt
+	}
+
+	questionOrder := make([]int, len(q.Questions))
+	optionOrder := map[int][]int{}
+	for i, question := range q.Questions {
+		questionOrder[i] = question.ID
+		var options []int
+		for _, o := range question.Options {
+			options = append(options, o.ID)
+		}
+		// Opsi benar/salah tidak diacak agar urutannya tetap wajar
+		if q.ShuffleOptions && question.Type == questionMultipleChoice {
+			rand.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+		}
+		optionOrder[question.ID] = options
+	}
+	if q.ShuffleQuestions {
+		rand.Shuffle(len(questionOrder), func(i, j int) { questionOrder[i], questionOrder[j] = questionOrder[j], questionOrder[i] })
+	}
+	questionJSON, _ := json.Marshal(questionOrder)
+	optionJSON, _ := json.Marshal(optionOrder)
+
+	result, err := config.DB.Exec(`
+		INSERT INTO quiz_attempts (quiz_id, student_id, attempt_no, started_at, deadline_at, status, question_order, option_order)
+		VALUES (?, ?, ?, ?, ?, 'in_progress', ?, ?)
+	`, q.ID, studentID, used+1, now, deadline, string(questionJSON), string(optionJSON))
+	if config.IsDuplicateKeyError(err) {
+		// UNIQUE (quiz_id, student_id, attempt_no): permintaan mulai ganda
+		utils.ErrorResponse(c, http.StatusConflict, "Percobaan kuis sudah dimulai")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memulai kuis: "+err.Error())
+		return
+	}
+	attemptID, _ := result.LastInsertId()
+
+	a := &quizAttempt{
+		ID:            int(attemptID),
+		QuizID:        q.ID,
+		StudentID:     studentID,
+		AttemptNo:     used + 1,
+		StartedAt:     now,
+		DeadlineAt:    deadline,
+		Status:        "in_progress",
+		QuestionOrder: questionOrder,
+		OptionOrder:   optionOrder,
+	}
+	utils.SuccessResponse(c, attemptPaper(q, a, now), "Percobaan kuis dimulai")
+}
+
+// quizAnswersInput - jawaban yang dikirim mahasiswa (autosave maupun saat submit)
+type quizAnswersInput struct {
+	Answers []struct {
+		QuestionID int    `json:"question_id" binding:"required"`
+		OptionID   *int   `json:"option_id"`
+		AnswerText string `json:"answer_text"`
+	} `json:"answers" binding:"dive"`
+}
+
+// saveQuizAnswers menyimpan jawaban setelah memastikan soal dan opsi milik kuis
+func saveQuizAnswers(q *quiz, attemptID int, input quizAnswersInput) error {
+	byID := map[int]quizQuestion{}
+	for _, question := range q.Questions {
+		byID[question.ID] = question
+	}
+
+	for _, answer := range input.Answers {
+		question, ok := byID[answer.QuestionID]
+		if !ok {
+			return fmt.Errorf("soal %d tidak ada pada kuis ini", answer.QuestionID)
+		}
+
+		var optionID interface{}
+		answerText := answer.AnswerText
+		switch question.Type {
+		case questionMultipleChoice, questionTrueFalse:
+			answerText = ""
+			if answer.OptionID != nil {
+				valid := false
+				for _, o := range question.Options {
+					valid = valid || o.ID == *answer.OptionID
+				}
+				if !valid {
+					return fmt.Errorf("opsi %d bukan milik soal %d", *answer.OptionID, answer.QuestionID)
+				}
+				optionID = *answer.OptionID
+			}
+		}
+
+		if _, err := config.DB.Exec(`
+			INSERT INTO quiz_answers (attempt_id, question_id, option_id, answer_text, updated_at)
+			VALUES (?, ?, ?, ?, NOW())
+			ON DUPLICATE KEY UPDATE option_id = VALUES(option_id), answer_text = VALUES(answer_text), updated_at = NOW()
+		`, attemptID, answer.QuestionID, optionID, answerText); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// activeAttemptForAnswers - percobaan berjalan yang masih menerima jawaban
+func activeAttemptForAnswers(c *gin.Context, q *quiz, studentID int) (*quizAttempt, bool) {
+	a := inProgressAttempt(q.ID, studentID)
+	if a == nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Tidak ada percobaan kuis yang sedang berjalan")
+		return nil, false
+	}
+	return a, true
+}
+
+// SaveQuizAnswers - Simpan jawaban sementara (autosave) selama percobaan berjalan
+func SaveQuizAnswers(c *gin.Context) {
+	q, studentID, ok := studentQuiz(c)
+	if !ok {
+		return
+	}
+
+	var input quizAnswersInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	a, ok := activeAttemptForAnswers(c, q, studentID)
+	if !ok {
+		return
+	}
+
+	if err := saveQuizAnswers(q, a.ID, input); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	response := gin.H{"attempt_id": a.ID, "saved": len(input.Answers)}
+	if a.DeadlineAt.Valid {
+		response["remaining_seconds"] = int(a.DeadlineAt.Time.Sub(dbNow()).Seconds())
+	}
+	utils.SuccessResponse(c, response, "Jawaban tersimpan")
+}
+
+// SubmitQuiz - Kumpulkan percobaan; soal objektif langsung dinilai otomatis
+func SubmitQuiz(c *gin.Context) {
+	q, studentID, ok := studentQuiz(c)
+	if !ok {
+		return
+	}
+
+	var input quizAnswersInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			utils.ValidationError(c, "Invalid input: "+err.Error())
+			return
+		}
+	}
+
+	a, ok := activeAttemptForAnswers(c, q, studentID)
+	if !ok {
+		return
+	}
+
+	if err := saveQuizAnswers(q, a.ID, input); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	submittedAt := dbNow()
+	if a.DeadlineAt.Valid && submittedAt.After(a.DeadlineAt.Time) {
+		submittedAt = a.DeadlineAt.Time
+	}
+	if err := gradeQuizAttempt(q, a.ID, submittedAt); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengumpulkan kuis: "+err.Error())
+		return
+	}
+
+	attempts, _ := loadQuizAttempts("id = ?", a.ID)
+	message := "Kuis berhasil dikumpulkan"
+	if len(attempts) > 0 && attempts[0].Status == "submitted" {
+		message = "Kuis berhasil dikumpulkan; nilai akhir menunggu penilaian esai oleh dosen"
+	}
+	var response gin.H
+	if len(attempts) > 0 {
+		response = attempts[0].toResponse()
+	}
+	utils.SuccessResponse(c, response, message)
+}
+
+// attemptReview - rincian jawaban per soal. withKey menampilkan kunci jawaban dan nilai per soal.
+func attemptReview(q *quiz, a quizAttempt, withKey bool) []gin.H {
+	answers, _ := loadQuizAnswers(a.ID)
+
+	review := []gin.H{}
+	for _, question := range q.Questions {
+		item := question.questionResponse(question.Options, withKey)
+		answer, answered := answers[question.ID]
+		item["answer_text"] = answer.AnswerText
+		item["selected_option_id"] = nil
+		if answer.OptionID.Valid {
+			item["selected_option_id"] = answer.OptionID.Int64
+		}
+		item["answered"] = answered && (answer.OptionID.Valid || answer.AnswerText != "")
+		if withKey {
+			item["is_correct"] = nil
+			if answer.IsCorrect.Valid {
+				item["is_correct"] = answer.IsCorrect.Bool
+			}
+			item["points_awarded"] = nil
+			if answer.PointsAwarded.Valid {
+				item["points_awarded"] = answer.PointsAwarded.Float64
+			}
+			item["feedback"] = answer.Feedback
+		}
+		review = append(review, item)
+	}
+	return review
+}
+
+// GetQuizResult - Hasil semua percobaan mahasiswa. Kunci jawaban baru ditampilkan setelah kuis ditutup.
+func GetQuizResult(c *gin.Context) {
+	q, studentID, ok := studentQuiz(c)
+	if !ok {
+		return
+	}
+
+	attempts, err := loadQuizAttempts("quiz_id = ? AND student_id = ?", q.ID, studentID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil hasil kuis: "+err.Error())
+		return
+	}
+
+	revealed := q.isClosed(dbNow())
+	result := []gin.H{}
+	for _, a := range attempts {
+		item := a.toResponse()
+		if revealed && a.Status != "in_progress" {
+			item["answers"] = attemptReview(q, a, true)
+		}
+		result = append(result, item)
+	}
+
+	response := q.settingsResponse()
+	for k, v := range studentQuizSummary(q, studentID) {
+		response[k] = v
+	}
+	response["answer_key_revealed"] = revealed
+	response["attempts"] = result
+	utils.SuccessResponse(c, response, "Hasil kuis berhasil diambil")
+}
+
+// ==================== DOSEN ====================
+
+// GetQuizAttempts - Daftar percobaan seluruh mahasiswa pada satu kuis
+func GetQuizAttempts(c *gin.Context) {
+	q, ok := dosenQuiz(c)
+	if !ok {
+		return
+	}
+	finalizeExpiredAttempts(q)
+
+	attempts, err := loadQuizAttempts("quiz_id = ?", q.ID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil percobaan kuis: "+err.Error())
+		return
+	}
+
+	byStudent := map[int][]quizAttempt{}
+	for _, a := range attempts {
+		byStudent[a.StudentID] = append(byStudent[a.StudentID], a)
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT DISTINCT m.id, m.nim, m.name
+		FROM mahasiswa_mata_kuliah mmk
+		JOIN mahasiswa m ON mmk.mahasiswa_id = m.id
+		WHERE mmk.mata_kuliah_kode = ?
+		ORDER BY m.nim
+	`, q.CourseID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil peserta: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	students := []gin.H{}
+	var attempted, needsGrading int
+	for rows.Next() {
+		var studentID int
+		var nim, name string
+		if rows.Scan(&studentID, &nim, &name) != nil {
+			continue
+		}
+		list := []gin.H{}
+		for _, a := range byStudent[studentID] {
+			list = append(list, a.toResponse())
+			if a.Status == "submitted" {
+				needsGrading++
+			}
+		}
+		if len(list) > 0 {
+			attempted++
+		}
+		item := gin.H{
+			"student_id":   studentID,
+			"student_nim":  nim,
+			"student_name": name,
+			"attempts":     list,
+			"final_score":  nil,
+		}
+		if final := quizFinalAttempt(q.Scoring, byStudent[studentID]); final != nil {
+			item["final_score"] = final.Percentage
+		}
+		students = append(students, item)
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"quiz":     q.settingsResponse(),
+		"students": students,
+		"statistics": gin.H{
+			"total_students": len(students),
+			"attempted":      attempted,
+			"needs_grading":  needsGrading,
+		},
+	}, "Percobaan kuis berhasil diambil")
+}
+
+// dosenQuizAttempt - percobaan :attempt_id pada kuis milik dosen
+func dosenQuizAttempt(c *gin.Context) (*quiz, *quizAttempt, bool) {
+	q, ok := dosenQuiz(c)
+	if !ok {
+		return nil, nil, false
+	}
+	finalizeExpiredAttempts(q)
+
+	attemptID, err := strconv.Atoi(c.Param("attempt_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid attempt ID")
+		return nil, nil, false
+	}
+
+	attempts, err := loadQuizAttempts("id = ? AND quiz_id = ?", attemptID, q.ID)
+	if err != nil || len(attempts) == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Percobaan kuis tidak ditemukan")
+		return nil, nil, false
+	}
+	return q, &attempts[0], true
+}
+
+// GetQuizAttemptDetail - Jawaban satu percobaan beserta kunci dan nilai per soal
+func GetQuizAttemptDetail(c *gin.Context) {
+	q, a, ok := dosenQuizAttempt(c)
+	if !ok {
+		return
+	}
+
+	var nim, name string
+	config.DB.QueryRow("SELECT nim, name FROM mahasiswa WHERE id = ?", a.StudentID).Scan(&nim, &name)
+
+	response := a.toResponse()
+	response["student_id"] = a.StudentID
+	response["student_nim"] = nim
+	response["student_name"] = name
+	response["answers"] = attemptReview(q, *a, true)
+	utils.SuccessResponse(c, response, "Detail percobaan berhasil diambil")
+}
+
+// GradeQuizAttempt - Nilai manual soal esai (atau koreksi nilai soal lain) pada satu percobaan
+func GradeQuizAttempt(c *gin.Context) {
+	q, a, ok := dosenQuizAttempt(c)
+	if !ok {
+		return
+	}
+
+	if a.Status == "in_progress" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Percobaan belum dikumpulkan")
+		return
+	}
+	if isGradebookLocked(q.CourseID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Nilai mata kuliah sudah dikunci")
+		return
+	}
+
+	var input struct {
+		Scores []struct {
+			QuestionID int      `json:"question_id" binding:"required"`
+			Points     *float64 `json:"points" binding:"required"`
+			Feedback   string   `json:"feedback"`
+		} `json:"scores" binding:"required,min=1,dive"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	byID := map[int]quizQuestion{}
+	for _, question := range q.Questions {
+		byID[question.ID] = question
+	}
+	for _, s := range input.Scores {
+		question, ok := byID[s.QuestionID]
+		if !ok {
+			utils.ValidationError(c, fmt.Sprintf("Soal %d tidak ada pada kuis ini", s.QuestionID))
+			return
+		}
+		if *s.Points < 0 || *s.Points > question.Points {
+			utils.ValidationError(c, fmt.Sprintf("Nilai soal %d harus 0-%g", s.QuestionID, question.Points))
+			return
+		}
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan nilai: "+err.Error())
+		return
+	}
+	defer tx.Rollback()
+
+	for _, s := range input.Scores {
+		question := byID[s.QuestionID]
+		if _, err = tx.Exec(`
+			INSERT INTO quiz_answers (attempt_id, question_id, is_correct, points_awarded, feedback, updated_at)
+			VALUES (?, ?, ?, ?, ?, NOW())
+			ON DUPLICATE KEY UPDATE is_correct = VALUES(is_correct), points_awarded = VALUES(points_awarded),
+				feedback = VALUES(feedback), updated_at = NOW()
+		`, a.ID, s.QuestionID, *s.Points == question.Points, *s.Points, s.Feedback); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = refreshAttemptScore(tx, q, a.ID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan nilai: "+err.Error())
+		return
+	}
+
+	attempts, err := loadQuizAttempts("id = ?", a.ID)
+	if err != nil || len(attempts) == 0 {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memuat percobaan kuis")
+		return
+	}
+	updated := attempts[0]
+	if updated.Status == "graded" && a.Status != "graded" {
+		var userID int
+		config.DB.QueryRow("SELECT user_id FROM mahasiswa WHERE id = ?", a.StudentID).Scan(&userID)
+		createSystemNotification(userID, q.ID, fmt.Sprintf("Kuis \"%s\" sudah dinilai: %.2f", q.Title, updated.Percentage))
+	}
+
+	response := updated.toResponse()
+	response["answers"] = attemptReview(q, updated, true)
+	utils.SuccessResponse(c, response, "Nilai percobaan berhasil disimpan")
+}
+
+// quizGradebookFilter - kuis yang dihitung ke gradebook: dipublikasi, semester aktif, dan sudah ditutup
+// (kuis tanpa waktu tutup langsung dihitung). Argumen: semesterArgs.
+var quizGradebookFilter = `q.is_published = 1 AND q.deleted_at IS NULL AND (q.closes_at IS NULL OR q.closes_at <= NOW())
+	AND ` + semesterFilter("q.semester_id")
+
+// finalizeCourseQuizAttempts - Nilai otomatis percobaan yang waktunya habis pada kuis yang dihitung ke
+// gradebook, agar percobaan yang tidak sempat dikumpulkan ikut masuk nilai dan pengecekan esai
+func finalizeCourseQuizAttempts(courseID string) {
+	rows, err := config.DB.Query(`
+		SELECT DISTINCT q.id FROM quizzes q
+		JOIN quiz_attempts a ON a.quiz_id = q.id
+		WHERE q.course_id = ? AND `+quizGradebookFilter+` AND a.status = 'in_progress'
+	`, append([]interface{}{courseID}, semesterArgs(currentSemesterID())...)...)
+	if err != nil {
+		return
+	}
+	var quizIDs []int
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			quizIDs = append(quizIDs, id)
+		}
+	}
+	rows.Close()
+
+	for _, id := range quizIDs {
+		if q, err := loadQuiz(id); err == nil {
+			finalizeExpiredAttempts(q)
+		}
+	}
+}
+
+// courseQuizScores - nilai kuis online per mahasiswa per kategori (kuis/uts/uas) untuk gradebook:
+// rata-rata nilai akhir (highest/latest) kuis yang dihitung pada kategori itu; tidak mengerjakan = 0.
+// Percobaan yang esainya belum dinilai tidak dihitung.
+func courseQuizScores(courseID string) (map[int]map[string]float64, error) {
+	finalizeCourseQuizAttempts(courseID)
+
+	args := append([]interface{}{courseID}, semesterArgs(currentSemesterID())...)
+
+	quizCount := map[string]int{}
+	countRows, err := config.DB.Query(`
+		SELECT q.kategori, COUNT(*) FROM quizzes q
+		WHERE q.course_id = ? AND `+quizGradebookFilter+`
+		GROUP BY q.kategori
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for countRows.Next() {
+		var kategori string
+		var n int
+		if countRows.Scan(&kategori, &n) == nil {
+			quizCount[kategori] = n
+		}
+	}
+	countRows.Close()
+	if len(quizCount) == 0 {
+		return nil, nil
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT q.id, q.kategori, q.scoring, a.student_id, a.attempt_no, COALESCE(a.percentage, 0)
+		FROM quiz_attempts a
+		JOIN quizzes q ON q.id = a.quiz_id
+		WHERE q.course_id = ? AND `+quizGradebookFilter+` AND a.status = 'graded'
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type key struct{ quizID, studentID int }
+	type best struct {
+		kategori   string
+		attemptNo  int
+		percentage float64
+	}
+	final := map[key]*best{}
+	for rows.Next() {
+		var quizID, studentID, attemptNo int
+		var kategori, scoring string
+		var percentage float64
+		if rows.Scan(&quizID, &kategori, &scoring, &studentID, &attemptNo, &percentage) != nil {
+			continue
+		}
+		k := key{quizID, studentID}
+		b, ok := final[k]
+		if !ok || (scoring == "latest" && attemptNo > b.attemptNo) || (scoring != "latest" && percentage > b.percentage) {
+			final[k] = &best{kategori: kategori, attemptNo: attemptNo, percentage: percentage}
+		}
+	}
+
+	scores := map[int]map[string]float64{}
+	for k, b := range final {
+		if scores[k.studentID] == nil {
+			scores[k.studentID] = map[string]float64{}
+		}
+		scores[k.studentID][b.kategori] += b.percentage / float64(quizCount[b.kategori])
+	}
+	return scores, nil
+}
+
+// pendingQuizGrading - jumlah percobaan kuis yang esainya belum dinilai pada mata kuliah
+func pendingQuizGrading(courseID string) int {
+	finalizeCourseQuizAttempts(courseID)
+
+	var pending int
+	config.DB.QueryRow(`
+		SELECT COUNT(*) FROM quiz_attempts a
+		JOIN quizzes q ON q.id = a.quiz_id
+		WHERE q.course_id = ? AND `+quizGradebookFilter+` AND a.status = 'submitted'
+	`, append([]interface{}{courseID}, semesterArgs(currentSemesterID())...)...).Scan(&pending)
+	return pending
+}
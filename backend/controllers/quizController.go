@@ -0,0 +1,684 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tipe soal kuis. Soal benar/salah disimpan sebagai dua opsi ("Benar"/"Salah") sehingga dinilai
+// sama seperti pilihan ganda; jawaban singkat dinilai otomatis dari daftar jawaban yang diterima;
+// esai dinilai manual oleh dosen.
+const (
+	questionMultipleChoice = "multiple_choice"
+	questionTrueFalse      = "true_false"
+	questionShortAnswer    = "short_answer"
+	questionEssay          = "essay"
+)
+
+// quizOption - satu opsi jawaban
+type quizOption struct {
+	ID        int
+	Text      string
+	IsCorrect bool
+}
+
+// quizQuestion - satu soal beserta kunci jawaban
+type quizQuestion struct {
+	ID       int
+	Type     string
+	Question string
+	Points   float64
+	Accepted []string // jawaban singkat yang diterima
+	Options  []quizOption
+}
+
+// autoGraded - soal dinilai otomatis saat dikumpulkan
+func (q quizQuestion) autoGraded() bool {
+	return q.Type != questionEssay
+}
+
+// quiz - pengaturan kuis
+type quiz struct {
+	ID               int
+	CourseID         string
+	Pertemuan        int
+	Title            string
+	Description      string
+	Kategori         string // kuis, uts, uas: komponen nilai yang diisi
+	TimeLimitMinutes int
+	MaxAttempts      int // 0 = tidak dibatasi
+	ShuffleQuestions bool
+	ShuffleOptions   bool
+	OpensAt          sql.NullTime
+	ClosesAt         sql.NullTime
+	Scoring          string // highest atau latest
+	Published        bool
+	Questions        []quizQuestion
+}
+
+func (q quiz) maxScore() float64 {
+	total := 0.0
+	for _, question := range q.Questions {
+		total += question.Points
+	}
+	return total
+}
+
+// isOpen - kuis sudah dipublikasi dan berada dalam jendela waktu pengerjaan
+func (q quiz) isOpen(now time.Time) bool {
+	if !q.Published {
+		return false
+	}
+	if q.OpensAt.Valid && now.Before(q.OpensAt.Time) {
+		return false
+	}
+	return !q.ClosesAt.Valid || now.Before(q.ClosesAt.Time)
+}
+
+// isClosed - jendela pengerjaan sudah berakhir (kunci jawaban boleh ditampilkan)
+func (q quiz) isClosed(now time.Time) bool {
+	return q.ClosesAt.Valid && !now.Before(q.ClosesAt.Time)
+}
+
+func formatNullTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Format("2006-01-02 15:04:05")
+}
+
+// settingsResponse - pengaturan kuis tanpa soal
+func (q quiz) settingsResponse() gin.H {
+	return gin.H{
+		"id":                 q.ID,
+		"course_id":          q.CourseID,
+		"pertemuan":          q.Pertemuan,
+		"title":              q.Title,
+		"description":        q.Description,
+		"kategori":           q.Kategori,
+		"time_limit_minutes": q.TimeLimitMinutes,
+		"max_attempts":       q.MaxAttempts,
+		"shuffle_questions":  q.ShuffleQuestions,
+		"shuffle_options":    q.ShuffleOptions,
+		"opens_at":           formatNullTime(q.OpensAt),
+		"closes_at":          formatNullTime(q.ClosesAt),
+		"scoring":            q.Scoring,
+		"is_published":       q.Published,
+		"question_count":     len(q.Questions),
+		"max_score":          q.maxScore(),
+	}
+}
+
+// questionResponse - soal untuk ditampilkan; withKey menyertakan kunci jawaban (hanya untuk dosen)
+func (question quizQuestion) questionResponse(options []quizOption, withKey bool) gin.H {
+	opts := []gin.H{}
+	for _, o := range options {
+		opt := gin.H{"id": o.ID, "text": o.Text}
+		if withKey {
+			opt["is_correct"] = o.IsCorrect
+		}
+		opts = append(opts, opt)
+	}
+	item := gin.H{
+		"id":       question.ID,
+		"type":     question.Type,
+		"question": question.Question,
+		"points":   question.Points,
+		"options":  opts,
+	}
+	if withKey && question.Type == questionShortAnswer {
+		item["accepted_answers"] = question.Accepted
+	}
+	return item
+}
+
+// detailResponse - pengaturan kuis beserta soal dan kunci jawaban (untuk dosen)
+func (q quiz) detailResponse() gin.H {
+	response := q.settingsResponse()
+	questions := []gin.H{}
+	for _, question := range q.Questions {
+		questions = append(questions, question.questionResponse(question.Options, true))
+	}
+	response["questions"] = questions
+	return response
+}
+
+const quizSelect = `
+	SELECT id, course_id, pertemuan, title, COALESCE(description, ''), kategori, time_limit_minutes,
+		max_attempts, shuffle_questions, shuffle_options, opens_at, closes_at, scoring, is_published
+	FROM quizzes`
+
+func scanQuiz(row interface{ Scan(...interface{}) error }) (*quiz, error) {
+	q := &quiz{}
+	err := row.Scan(&q.ID, &q.CourseID, &q.Pertemuan, &q.Title, &q.Description, &q.Kategori, &q.TimeLimitMinutes,
+		&q.MaxAttempts, &q.ShuffleQuestions, &q.ShuffleOptions, &q.OpensAt, &q.ClosesAt, &q.Scoring, &q.Published)
+	return q, err
+}
+
+// loadQuiz memuat kuis beserta soal dan opsinya sesuai urutan
+func loadQuiz(quizID int) (*quiz, error) {
+	q, err := scanQuiz(config.DB.QueryRow(quizSelect+" WHERE id = ? AND deleted_at IS NULL", quizID))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT id, type, question, points, COALESCE(accepted_answers, '')
+		FROM quiz_questions WHERE quiz_id = ? ORDER BY sort_order, id
+	`, quizID)
+	if err != nil {
+		return nil, err
+	}
+	index := map[int]int{}
+	for rows.Next() {
+		var question quizQuestion
+		var accepted string
+		if err := rows.Scan(&question.ID, &question.Type, &question.Question, &question.Points, &accepted); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if accepted != "" {
+			json.Unmarshal([]byte(accepted), &question.Accepted)
+		}
+		index[question.ID] = len(q.Questions)
+		q.Questions = append(q.Questions, question)
+	}
+	rows.Close()
+
+	optRows, err := config.DB.Query(`
+		SELECT o.id, o.question_id, o.option_text, o.is_correct
+		FROM quiz_options o
+		JOIN quiz_questions qq ON qq.id = o.question_id
+		WHERE qq.quiz_id = ?
+		ORDER BY o.sort_order, o.id
+	`, quizID)
+	if err != nil {
+		return nil, err
+	}
+	defer optRows.Close()
+	for optRows.Next() {
+		var o quizOption
+		var questionID int
+		if err := optRows.Scan(&o.ID, &questionID, &o.Text, &o.IsCorrect); err != nil {
+			return nil, err
+		}
+		if i, ok := index[questionID]; ok {
+			q.Questions[i].Options = append(q.Questions[i].Options, o)
+		}
+	}
+	return q, nil
+}
+
+// quizQuestionInput - satu soal pada body create/update kuis
+type quizQuestionInput struct {
+	Type     string  `json:"type" binding:"required,oneof=multiple_choice true_false short_answer essay"`
+	Question string  `json:"question" binding:"required"`
+	Points   float64 `json:"points" binding:"gt=0"`
+	Options  []struct {
+		Text      string `json:"text"`
+		IsCorrect bool   `json:"is_correct"`
+	} `json:"options"`
+	CorrectAnswer   *bool    `json:"correct_answer"`   // true_false
+	AcceptedAnswers []string `json:"accepted_answers"` // short_answer
+}
+
+// quizInput - body create/update kuis
+type quizInput struct {
+	CourseID         string              `json:"course_id"`
+	Pertemuan        int                 `json:"pertemuan" binding:"required,min=1,max=16"`
+	Title            string              `json:"title" binding:"required"`
+	Description      string              `json:"description"`
+	Kategori         string              `json:"kategori"`
+	TimeLimitMinutes int                 `json:"time_limit_minutes" binding:"gte=0,lte=600"`
+	MaxAttempts      *int                `json:"max_attempts"`
+	ShuffleQuestions bool                `json:"shuffle_questions"`
+	ShuffleOptions   bool                `json:"shuffle_options"`
+	OpensAt          string              `json:"opens_at"`
+	ClosesAt         string              `json:"closes_at"`
+	Scoring          string              `json:"scoring"`
+	Questions        []quizQuestionInput `json:"questions" binding:"required,min=1,dive"`
+}
+
+// parseQuizTime menerima format datetime-local atau "2006-01-02 15:04:05"; kosong = tidak dibatasi
+func parseQuizTime(value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return sql.NullTime{Time: t, Valid: true}, nil
+		}
+	}
+	return sql.NullTime{}, fmt.Errorf("format waktu %q salah (gunakan datetime-local)", value)
+}
+
+// toQuiz memvalidasi input dan mengubahnya menjadi quiz (tanpa ID)
+func (in quizInput) toQuiz() (*quiz, error) {
+	q := &quiz{
+		CourseID:         in.CourseID,
+		Pertemuan:        in.Pertemuan,
+		Title:            strings.TrimSpace(in.Title),
+		Description:      in.Description,
+		Kategori:         in.Kategori,
+		TimeLimitMinutes: in.TimeLimitMinutes,
+		MaxAttempts:      1,
+		ShuffleQuestions: in.ShuffleQuestions,
+		ShuffleOptions:   in.ShuffleOptions,
+		Scoring:          in.Scoring,
+	}
+	if q.Kategori == "" {
+		q.Kategori = "kuis"
+	}
+	if q.Kategori != "kuis" && q.Kategori != "uts" && q.Kategori != "uas" {
+		return nil, fmt.Errorf("kategori harus kuis, uts, atau uas")
+	}
+	if q.Scoring == "" {
+		q.Scoring = "highest"
+	}
+	if q.Scoring != "highest" && q.Scoring != "latest" {
+		return nil, fmt.Errorf("scoring harus highest atau latest")
+	}
+	if in.MaxAttempts != nil {
+		if *in.MaxAttempts < 0 || *in.MaxAttempts > 20 {
+			return nil, fmt.Errorf("max_attempts harus 0-20 (0 = tidak dibatasi)")
+		}
+		q.MaxAttempts = *in.MaxAttempts
+	}
+
+	var err error
+	if q.OpensAt, err = parseQuizTime(in.OpensAt); err != nil {
+		return nil, err
+	}
+	if q.ClosesAt, err = parseQuizTime(in.ClosesAt); err != nil {
+		return nil, err
+	}
+	if q.OpensAt.Valid && q.ClosesAt.Valid && !q.ClosesAt.Time.After(q.OpensAt.Time) {
+		return nil, fmt.Errorf("closes_at harus setelah opens_at")
+	}
+
+	for i, qi := range in.Questions {
+		question := quizQuestion{Type: qi.Type, Question: strings.TrimSpace(qi.Question), Points: qi.Points}
+		switch qi.Type {
+		case questionMultipleChoice:
+			correct := 0
+			for _, o := range qi.Options {
+				if strings.TrimSpace(o.Text) == "" {
+					return nil, fmt.Errorf("soal %d: teks opsi wajib diisi", i+1)
+				}
+				if o.IsCorrect {
+					correct++
+				}
+				question.Options = append(question.Options, quizOption{Text: strings.TrimSpace(o.Text), IsCorrect: o.IsCorrect})
+			}
+			if len(question.Options) < 2 || correct != 1 {
+				return nil, fmt.Errorf("soal %d: pilihan ganda butuh minimal 2 opsi dengan tepat 1 jawaban benar", i+1)
+			}
+		case questionTrueFalse:
+			if qi.CorrectAnswer == nil {
+				return nil, fmt.Errorf("soal %d: correct_answer wajib diisi untuk soal benar/salah", i+1)
+			}
+			question.Options = []quizOption{
+				{Text: "Benar", IsCorrect: *qi.CorrectAnswer},
+				{Text: "Salah", IsCorrect: !*qi.CorrectAnswer},
+			}
+		case questionShortAnswer:
+			for _, a := range qi.AcceptedAnswers {
+				if normalized := normalizeShortAnswer(a); normalized != "" {
+					question.Accepted = append(question.Accepted, strings.TrimSpace(a))
+				}
+			}
+			if len(question.Accepted) == 0 {
+				return nil, fmt.Errorf("soal %d: accepted_answers wajib diisi untuk jawaban singkat", i+1)
+			}
+		}
+		q.Questions = append(q.Questions, question)
+	}
+	return q, nil
+}
+
+// normalizeShortAnswer - huruf kecil dan spasi dirapikan untuk membandingkan jawaban singkat
+func normalizeShortAnswer(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// insertQuizQuestions menulis soal dan opsi kuis
+func insertQuizQuestions(tx *sql.Tx, quizID int64, questions []quizQuestion) error {
+	for i, question := range questions {
+		var accepted interface{}
+		if len(question.Accepted) > 0 {
+			data, _ := json.Marshal(question.Accepted)
+			accepted = string(data)
+		}
+		result, err := tx.Exec(`
+			INSERT INTO quiz_questions (quiz_id, type, question, points, accepted_answers, sort_order)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, quizID, question.Type, question.Question, question.Points, accepted, i+1)
+		if err != nil {
+			return err
+		}
+		questionID, _ := result.LastInsertId()
+		for j, o := range question.Options {
+			if _, err := tx.Exec(`
+				INSERT INTO quiz_options (question_id, option_text, is_correct, sort_order) VALUES (?, ?, ?, ?)
+			`, questionID, o.Text, o.IsCorrect, j+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dosenQuiz - kuis :quiz_id pada mata kuliah yang diampu dosen login
+func dosenQuiz(c *gin.Context) (*quiz, bool) {
+	quizID, err := strconv.Atoi(c.Param("quiz_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid quiz ID")
+		return nil, false
+	}
+
+	q, err := loadQuiz(quizID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Kuis tidak ditemukan")
+		return nil, false
+	}
+	if _, ok := getDosenCourseAccess(c, q.CourseID); !ok {
+		return nil, false
+	}
+	return q, true
+}
+
+// quizHasAttempts - soal tidak boleh diubah setelah ada mahasiswa yang mengerjakan
+func quizHasAttempts(quizID int) bool {
+	var exists bool
+	config.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM quiz_attempts WHERE quiz_id = ?)", quizID).Scan(&exists)
+	return exists
+}
+
+// CreateQuiz - Buat kuis/ujian online pada pertemuan mata kuliah
+func CreateQuiz(c *gin.Context) {
+	var input quizInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+	if input.CourseID == "" {
+		utils.ValidationError(c, "course_id wajib diisi")
+		return
+	}
+
+	dosenID, ok := getDosenCourseAccess(c, input.CourseID)
+	if !ok {
+		return
+	}
+
+	q, err := input.toQuiz()
+	if err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat kuis: "+err.Error())
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO quizzes (course_id, pertemuan, title, description, kategori, time_limit_minutes, max_attempts,
+			shuffle_questions, shuffle_options, opens_at, closes_at, scoring, is_published, semester_id, created_by,
+			created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, NOW(), NOW())
+	`, q.CourseID, q.Pertemuan, q.Title, q.Description, q.Kategori, q.TimeLimitMinutes, q.MaxAttempts,
+		q.ShuffleQuestions, q.ShuffleOptions, q.OpensAt, q.ClosesAt, q.Scoring, currentSemesterID(), dosenID)
+	var quizID int64
+	if err == nil {
+		quizID, _ = result.LastInsertId()
+		err = insertQuizQuestions(tx, quizID, q.Questions)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat kuis: "+err.Error())
+		return
+	}
+
+	created, _ := loadQuiz(int(quizID))
+	utils.SuccessResponse(c, created.detailResponse(), "Kuis berhasil dibuat")
+}
+
+// UpdateQuiz - Ubah pengaturan kuis; soal hanya bisa diganti selama belum ada yang mengerjakan
+func UpdateQuiz(c *gin.Context) {
+	current, ok := dosenQuiz(c)
+	if !ok {
+		return
+	}
+
+	var input quizInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+	input.CourseID = current.CourseID
+
+	q, err := input.toQuiz()
+	if err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	replaceQuestions := !quizHasAttempts(current.ID)
+	if !replaceQuestions && q.Kategori != current.Kategori {
+		utils.ErrorResponse(c, http.StatusConflict, "Kuis sudah dikerjakan mahasiswa; kategori tidak bisa diubah")
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan kuis: "+err.Error())
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE quizzes SET pertemuan = ?, title = ?, description = ?, kategori = ?, time_limit_minutes = ?,
+			max_attempts = ?, shuffle_questions = ?, shuffle_options = ?, opens_at = ?, closes_at = ?, scoring = ?,
+			updated_at = NOW()
+		WHERE id = ?
+	`, q.Pertemuan, q.Title, q.Description, q.Kategori, q.TimeLimitMinutes, q.MaxAttempts,
+		q.ShuffleQuestions, q.ShuffleOptions, q.OpensAt, q.ClosesAt, q.Scoring, current.ID)
+	if err == nil && replaceQuestions {
+		// Opsi ikut terhapus lewat ON DELETE CASCADE
+		if _, err = tx.Exec("DELETE FROM quiz_questions WHERE quiz_id = ?", current.ID); err == nil {
+			err = insertQuizQuestions(tx, int64(current.ID), q.Questions)
+		}
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan kuis: "+err.Error())
+		return
+	}
+
+	message := "Kuis berhasil disimpan"
+	if !replaceQuestions {
+		message = "Pengaturan kuis disimpan; soal tidak diubah karena kuis sudah dikerjakan"
+	}
+	updated, _ := loadQuiz(current.ID)
+	utils.SuccessResponse(c, updated.detailResponse(), message)
+}
+
+// PublishQuiz - Tampilkan atau sembunyikan kuis dari mahasiswa
+func PublishQuiz(c *gin.Context) {
+	q, ok := dosenQuiz(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Published bool `json:"published"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	if _, err := config.DB.Exec("UPDATE quizzes SET is_published = ?, updated_at = NOW() WHERE id = ?", input.Published, q.ID); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengubah status kuis: "+err.Error())
+		return
+	}
+
+	if input.Published && !q.Published {
+		notifyCourseStudents(q.CourseID, q.ID, fmt.Sprintf("Kuis baru \"%s\" untuk pertemuan %d sudah dibuka", q.Title, q.Pertemuan))
+	}
+	utils.SuccessResponse(c, gin.H{"quiz_id": q.ID, "is_published": input.Published}, "Status kuis berhasil diubah")
+}
+
+// notifyCourseStudents - notifikasi sistem ke semua mahasiswa peserta mata kuliah
+func notifyCourseStudents(courseID string, sourceID int, message string) {
+	rows, err := config.DB.Query(`
+		SELECT DISTINCT m.user_id FROM mahasiswa_mata_kuliah mmk
+		JOIN mahasiswa m ON m.id = mmk.mahasiswa_id
+		WHERE mmk.mata_kuliah_kode = ?
+	`, courseID)
+	if err != nil {
+		return
+	}
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if rows.Scan(&userID) == nil {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	rows.Close()
+
+	for _, userID := range userIDs {
+		createSystemNotification(userID, sourceID, message)
+	}
+}
+
+// DeleteQuiz - Hapus kuis (soft delete); nilainya tidak lagi dihitung ke nilai akhir
+func DeleteQuiz(c *gin.Context) {
+	q, ok := dosenQuiz(c)
+	if !ok {
+		return
+	}
+
+	if isGradebookLocked(q.CourseID) && quizHasAttempts(q.ID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Nilai mata kuliah sudah dikunci")
+		return
+	}
+
+	if _, err := config.DB.Exec("UPDATE quizzes SET deleted_at = NOW() WHERE id = ?", q.ID); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus kuis: "+err.Error())
+		return
+	}
+	utils.SuccessResponse(c, nil, "Kuis berhasil dihapus")
+}
+
+// GetQuizDetail - Detail kuis beserta soal dan kunci jawaban untuk dosen
+func GetQuizDetail(c *gin.Context) {
+	q, ok := dosenQuiz(c)
+	if !ok {
+		return
+	}
+	utils.SuccessResponse(c, q.detailResponse(), "Detail kuis berhasil diambil")
+}
+
+// GetCourseQuizzes - Daftar kuis mata kuliah beserta ringkasan pengerjaan
+func GetCourseQuizzes(c *gin.Context) {
+	courseID := c.Param("course_id")
+	if _, ok := getDosenCourseAccess(c, courseID); !ok {
+		return
+	}
+
+	rows, err := config.DB.Query(quizSelect+" WHERE course_id = ? AND deleted_at IS NULL ORDER BY pertemuan, id", courseID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil kuis: "+err.Error())
+		return
+	}
+	var quizzes []*quiz
+	for rows.Next() {
+		if q, err := scanQuiz(rows); err == nil {
+			quizzes = append(quizzes, q)
+		}
+	}
+	rows.Close()
+
+	result := []gin.H{}
+	for _, q := range quizzes {
+		full, err := loadQuiz(q.ID)
+		if err != nil {
+			continue
+		}
+		item := full.settingsResponse()
+		var students, needsGrading int
+		config.DB.QueryRow(`
+			SELECT COUNT(DISTINCT student_id), COUNT(CASE WHEN status = 'submitted' THEN 1 END)
+			FROM quiz_attempts WHERE quiz_id = ?
+		`, q.ID).Scan(&students, &needsGrading)
+		item["students_attempted"] = students
+		item["needs_grading"] = needsGrading
+		result = append(result, item)
+	}
+
+	utils.SuccessResponse(c, result, "Daftar kuis berhasil diambil")
+}
+
+// pertemuanQuizzes - kuis pada satu pertemuan untuk GetPertemuanDetail.
+// Mahasiswa hanya melihat kuis yang dipublikasi beserta ringkasan percobaannya sendiri.
+func pertemuanQuizzes(courseID string, pertemuan int, role interface{}, userID interface{}) []gin.H {
+	query := quizSelect + " WHERE course_id = ? AND pertemuan = ? AND deleted_at IS NULL"
+	if role == "mahasiswa" {
+		query += " AND is_published = 1"
+	}
+	rows, err := config.DB.Query(query+" ORDER BY id", courseID, pertemuan)
+	if err != nil {
+		return nil
+	}
+	var quizzes []*quiz
+	for rows.Next() {
+		if q, err := scanQuiz(rows); err == nil {
+			quizzes = append(quizzes, q)
+		}
+	}
+	rows.Close()
+
+	var studentID int
+	if role == "mahasiswa" {
+		config.DB.QueryRow("SELECT id FROM mahasiswa WHERE user_id = ?", userID).Scan(&studentID)
+	}
+
+	now := dbNow()
+	var result []gin.H
+	for _, q := range quizzes {
+		full, err := loadQuiz(q.ID)
+		if err != nil {
+			continue
+		}
+		item := full.settingsResponse()
+		item["is_open"] = full.isOpen(now)
+		if studentID != 0 {
+			finalizeExpiredAttempts(full)
+			summary := studentQuizSummary(full, studentID)
+			for k, v := range summary {
+				item[k] = v
+			}
+		}
+		result = append(result, item)
+	}
+	return result
+}
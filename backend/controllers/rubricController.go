@@ -0,0 +1,660 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rubricLevel - satu tingkat capaian pada kriteria (mis. "Sangat baik" = 25 poin)
+type rubricLevel struct {
+	ID          int
+	Label       string
+	Description string
+	Points      float64
+}
+
+// rubricCriterion - satu kriteria penilaian beserta tingkat capaiannya
+type rubricCriterion struct {
+	ID          int
+	Title       string
+	Description string
+	Levels      []rubricLevel
+}
+
+func (c rubricCriterion) maxPoints() float64 {
+	max := 0.0
+	for _, l := range c.Levels {
+		max = math.Max(max, l.Points)
+	}
+	return max
+}
+
+// rubric - rubrik milik dosen yang bisa dipasang ke banyak tugas
+type rubric struct {
+	ID          int
+	DosenID     int
+	Title       string
+	Description string
+	Criteria    []rubricCriterion
+}
+
+func (r rubric) maxPoints() float64 {
+	total := 0.0
+	for _, c := range r.Criteria {
+		total += c.maxPoints()
+	}
+	return total
+}
+
+func (r rubric) toResponse() gin.H {
+	criteria := []gin.H{}
+	for _, c := range r.Criteria {
+		levels := []gin.H{}
+		for _, l := range c.Levels {
+			levels = append(levels, gin.H{"id": l.ID, "label": l.Label, "description": l.Description, "points": l.Points})
+		}
+		criteria = append(criteria, gin.H{
+			"id":          c.ID,
+			"title":       c.Title,
+			"description": c.Description,
+			"max_points":  c.maxPoints(),
+			"levels":      levels,
+		})
+	}
+	return gin.H{
+		"id":          r.ID,
+		"title":       r.Title,
+		"description": r.Description,
+		"max_points":  r.maxPoints(),
+		"criteria":    criteria,
+	}
+}
+
+// loadRubric memuat rubrik lengkap dengan kriteria dan tingkat capaian sesuai urutan
+func loadRubric(rubricID int) (*rubric, error) {
+	r := &rubric{ID: rubricID}
+	err := config.DB.QueryRow("SELECT dosen_id, title, COALESCE(description, '') FROM rubrics WHERE id = ?", rubricID).
+		Scan(&r.DosenID, &r.Title, &r.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT c.id, c.title, COALESCE(c.description, ''), l.id, l.label, COALESCE(l.description, ''), l.points
+		FROM rubric_criteria c
+		JOIN rubric_levels l ON l.criterion_id = c.id
+		WHERE c.rubric_id = ?
+		ORDER BY c.sort_order, c.id, l.sort_order, l.id
+	`, rubricID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var crit rubricCriterion
+		var level rubricLevel
+		if err := rows.Scan(&crit.ID, &crit.Title, &crit.Description, &level.ID, &level.Label, &level.Description, &level.Points); err != nil {
+			return nil, err
+		}
+		if n := len(r.Criteria); n == 0 || r.Criteria[n-1].ID != crit.ID {
+			r.Criteria = append(r.Criteria, crit)
+		}
+		last := &r.Criteria[len(r.Criteria)-1]
+		last.Levels = append(last.Levels, level)
+	}
+	return r, nil
+}
+
+// rubricInput - body create/update rubrik
+type rubricInput struct {
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description"`
+	Criteria    []struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Levels      []struct {
+			Label       string  `json:"label"`
+			Description string  `json:"description"`
+			Points      float64 `json:"points"`
+		} `json:"levels"`
+	} `json:"criteria"`
+}
+
+func (in rubricInput) validate() error {
+	if strings.TrimSpace(in.Title) == "" {
+		return fmt.Errorf("Judul rubrik wajib diisi")
+	}
+	if len(in.Criteria) == 0 {
+		return fmt.Errorf("Rubrik minimal memiliki satu kriteria")
+	}
+	total := 0.0
+	for i, c := range in.Criteria {
+		if strings.TrimSpace(c.Title) == "" {
+			return fmt.Errorf("Judul kriteria ke-%d wajib diisi", i+1)
+		}
+		if len(c.Levels) == 0 {
+			return fmt.Errorf("Kriteria \"%s\" minimal memiliki satu tingkat capaian", c.Title)
+		}
+		max := 0.0
+		for _, l := range c.Levels {
+			if strings.TrimSpace(l.Label) == "" {
+				return fmt.Errorf("Label tingkat capaian pada kriteria \"%s\" wajib diisi", c.Title)
+			}
+			if l.Points < 0 {
+				return fmt.Errorf("Poin pada kriteria \"%s\" tidak boleh negatif", c.Title)
+			}
+			max = math.Max(max, l.Points)
+		}
+		total += max
+	}
+	if total <= 0 {
+		return fmt.Errorf("Total poin maksimal rubrik harus lebih dari 0")
+	}
+	return nil
+}
+
+// insertRubricCriteria menulis kriteria dan tingkat capaian rubrik
+func insertRubricCriteria(tx *sql.Tx, rubricID int64, in rubricInput) error {
+	for i, c := range in.Criteria {
+		result, err := tx.Exec(`
+			INSERT INTO rubric_criteria (rubric_id, title, description, sort_order) VALUES (?, ?, ?, ?)
+		`, rubricID, strings.TrimSpace(c.Title), c.Description, i+1)
+		if err != nil {
+			return err
+		}
+		criterionID, _ := result.LastInsertId()
+		for j, l := range c.Levels {
+			if _, err := tx.Exec(`
+				INSERT INTO rubric_levels (criterion_id, label, description, points, sort_order) VALUES (?, ?, ?, ?, ?)
+			`, criterionID, strings.TrimSpace(l.Label), l.Description, l.Points, j+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rubricInUse - rubrik sudah dipakai menilai submission (struktur tidak boleh diubah lagi)
+func rubricInUse(rubricID int) bool {
+	var used bool
+	config.DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM submission_rubric_scores srs
+			JOIN rubric_criteria c ON c.id = srs.criterion_id
+			WHERE c.rubric_id = ?
+		)
+	`, rubricID).Scan(&used)
+	return used
+}
+
+// rubricDosenID - id dosen yang login; menulis response error jika tidak ada
+func rubricDosenID(c *gin.Context) (int, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized")
+		return 0, false
+	}
+
+	var dosenID int
+	if err := config.DB.QueryRow("SELECT id FROM dosen WHERE user_id = ?", userID).Scan(&dosenID); err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Dosen not found")
+		return 0, false
+	}
+	return dosenID, true
+}
+
+// ownedRubric - rubrik :rubric_id milik dosen yang login
+func ownedRubric(c *gin.Context) (*rubric, bool) {
+	dosenID, ok := rubricDosenID(c)
+	if !ok {
+		return nil, false
+	}
+
+	rubricID, err := strconv.Atoi(c.Param("rubric_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid rubric ID")
+		return nil, false
+	}
+
+	r, err := loadRubric(rubricID)
+	if err != nil || r.DosenID != dosenID {
+		utils.ErrorResponse(c, http.StatusNotFound, "Rubrik tidak ditemukan")
+		return nil, false
+	}
+	return r, true
+}
+
+// GetRubrics - Daftar rubrik milik dosen
+func GetRubrics(c *gin.Context) {
+	dosenID, ok := rubricDosenID(c)
+	if !ok {
+		return
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT r.id, r.title, COALESCE(r.description, ''),
+			(SELECT COUNT(*) FROM rubric_criteria c WHERE c.rubric_id = r.id),
+			(SELECT COUNT(*) FROM tugas t WHERE t.rubric_id = r.id AND t.deleted_at IS NULL)
+		FROM rubrics r
+		WHERE r.dosen_id = ?
+		ORDER BY r.updated_at DESC
+	`, dosenID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil rubrik: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	rubrics := []gin.H{}
+	for rows.Next() {
+		var id, criteria, tugas int
+		var title, description string
+		if err := rows.Scan(&id, &title, &description, &criteria, &tugas); err != nil {
+			continue
+		}
+		rubrics = append(rubrics, gin.H{
+			"id":          id,
+			"title":       title,
+			"description": description,
+			"criteria":    criteria,
+			"used_by":     tugas,
+		})
+	}
+
+	utils.SuccessResponse(c, rubrics, "Rubrik berhasil diambil")
+}
+
+// GetRubric - Detail rubrik
+func GetRubric(c *gin.Context) {
+	r, ok := ownedRubric(c)
+	if !ok {
+		return
+	}
+	utils.SuccessResponse(c, r.toResponse(), "Rubrik berhasil diambil")
+}
+
+// CreateRubric - Buat rubrik baru beserta kriteria dan tingkat capaian
+func CreateRubric(c *gin.Context) {
+	dosenID, ok := rubricDosenID(c)
+	if !ok {
+		return
+	}
+
+	var input rubricInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+	if err := input.validate(); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat rubrik: "+err.Error())
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO rubrics (dosen_id, title, description, created_at, updated_at) VALUES (?, ?, ?, NOW(), NOW())
+	`, dosenID, strings.TrimSpace(input.Title), input.Description)
+	if err == nil {
+		rubricID, _ := result.LastInsertId()
+		if err = insertRubricCriteria(tx, rubricID, input); err == nil {
+			err = tx.Commit()
+		}
+		if err == nil {
+			r, _ := loadRubric(int(rubricID))
+			utils.SuccessResponse(c, r.toResponse(), "Rubrik berhasil dibuat")
+			return
+		}
+	}
+	utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat rubrik: "+err.Error())
+}
+
+// UpdateRubric - Ganti isi rubrik; ditolak jika rubrik sudah dipakai menilai
+func UpdateRubric(c *gin.Context) {
+	r, ok := ownedRubric(c)
+	if !ok {
+		return
+	}
+
+	var input rubricInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+	if err := input.validate(); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	if rubricInUse(r.ID) {
+		utils.ErrorResponse(c, http.StatusConflict, "Rubrik sudah dipakai untuk menilai, buat rubrik baru untuk perubahan")
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan rubrik: "+err.Error())
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE rubrics SET title = ?, description = ?, updated_at = NOW() WHERE id = ?",
+		strings.TrimSpace(input.Title), input.Description, r.ID)
+	if err == nil {
+		// Tingkat capaian ikut terhapus lewat ON DELETE CASCADE
+		_, err = tx.Exec("DELETE FROM rubric_criteria WHERE rubric_id = ?", r.ID)
+	}
+	if err == nil {
+		err = insertRubricCriteria(tx, int64(r.ID), input)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan rubrik: "+err.Error())
+		return
+	}
+
+	updated, _ := loadRubric(r.ID)
+	utils.SuccessResponse(c, updated.toResponse(), "Rubrik berhasil disimpan")
+}
+
+// DeleteRubric - Hapus rubrik yang belum dipakai menilai (tugas yang memakainya dilepas)
+func DeleteRubric(c *gin.Context) {
+	r, ok := ownedRubric(c)
+	if !ok {
+		return
+	}
+
+	if rubricInUse(r.ID) {
+		utils.ErrorResponse(c, http.StatusConflict, "Rubrik sudah dipakai untuk menilai dan tidak bisa dihapus")
+		return
+	}
+
+	if _, err := config.DB.Exec("DELETE FROM rubrics WHERE id = ?", r.ID); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus rubrik: "+err.Error())
+		return
+	}
+	utils.SuccessResponse(c, nil, "Rubrik berhasil dihapus")
+}
+
+// taskRubricID - rubrik yang terpasang pada tugas (0 = tidak ada)
+func taskRubricID(taskID int) int {
+	var rubricID sql.NullInt64
+	config.DB.QueryRow("SELECT rubric_id FROM tugas WHERE id = ?", taskID).Scan(&rubricID)
+	return int(rubricID.Int64)
+}
+
+// GetTugasRubric - Rubrik yang terpasang pada tugas
+func GetTugasRubric(c *gin.Context) {
+	_, taskID, ok := loadCourseTask(c)
+	if !ok {
+		return
+	}
+
+	rubricID := taskRubricID(taskID)
+	if rubricID == 0 {
+		utils.SuccessResponse(c, nil, "Tugas belum memakai rubrik")
+		return
+	}
+	r, err := loadRubric(rubricID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil rubrik: "+err.Error())
+		return
+	}
+	utils.SuccessResponse(c, r.toResponse(), "Rubrik tugas berhasil diambil")
+}
+
+// SetTugasRubric - Pasang (atau lepas dengan rubric_id null) rubrik pada tugas
+func SetTugasRubric(c *gin.Context) {
+	dosenID, taskID, ok := loadCourseTask(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		RubricID *int `json:"rubric_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	var rubricID interface{}
+	if input.RubricID != nil {
+		var owner int
+		if err := config.DB.QueryRow("SELECT dosen_id FROM rubrics WHERE id = ?", *input.RubricID).Scan(&owner); err != nil || owner != dosenID {
+			utils.ErrorResponse(c, http.StatusNotFound, "Rubrik tidak ditemukan")
+			return
+		}
+		rubricID = *input.RubricID
+	}
+
+	if _, err := config.DB.Exec("UPDATE tugas SET rubric_id = ?, updated_at = NOW() WHERE id = ?", rubricID, taskID); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memasang rubrik: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{"task_id": taskID, "rubric_id": rubricID}, "Rubrik tugas berhasil disimpan")
+}
+
+// submissionRubricBreakdown - rincian nilai rubrik submission (nil jika dinilai tanpa rubrik)
+func submissionRubricBreakdown(submissionID int) gin.H {
+	rows, err := config.DB.Query(`
+		SELECT r.id, r.title, c.id, c.title, l.id, l.label, srs.points,
+			(SELECT MAX(ml.points) FROM rubric_levels ml WHERE ml.criterion_id = c.id),
+			COALESCE(srs.comment, '')
+		FROM submission_rubric_scores srs
+		JOIN rubric_criteria c ON c.id = srs.criterion_id
+		JOIN rubrics r ON r.id = c.rubric_id
+		LEFT JOIN rubric_levels l ON l.id = srs.level_id
+		WHERE srs.submission_id = ?
+		ORDER BY c.sort_order, c.id
+	`, submissionID)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var rubricID int
+	var rubricTitle string
+	var total, max float64
+	criteria := []gin.H{}
+	for rows.Next() {
+		var criterionID int
+		var levelID sql.NullInt64
+		var criterionTitle, comment string
+		var levelLabel sql.NullString
+		var points, maxPoints float64
+		if err := rows.Scan(&rubricID, &rubricTitle, &criterionID, &criterionTitle, &levelID, &levelLabel, &points, &maxPoints, &comment); err != nil {
+			continue
+		}
+		total += points
+		max += maxPoints
+		criteria = append(criteria, gin.H{
+			"criterion_id": criterionID,
+			"criterion":    criterionTitle,
+			"level_id":     levelID.Int64,
+			"level":        levelLabel.String,
+			"points":       points,
+			"max_points":   maxPoints,
+			"comment":      comment,
+		})
+	}
+	if len(criteria) == 0 {
+		return nil
+	}
+
+	return gin.H{
+		"rubric_id":    rubricID,
+		"rubric_title": rubricTitle,
+		"total_points": total,
+		"max_points":   max,
+		"criteria":     criteria,
+	}
+}
+
+// GetSubmissionRubricGrade - Rubrik tugas dan isian nilai rubrik submission untuk dosen
+func GetSubmissionRubricGrade(c *gin.Context) {
+	submissionID, ok := loadDosenSubmission(c)
+	if !ok {
+		return
+	}
+
+	var taskID int
+	var feedback string
+	config.DB.QueryRow("SELECT task_id, COALESCE(feedback, '') FROM submissions WHERE id = ?", submissionID).Scan(&taskID, &feedback)
+
+	var rubricData gin.H
+	if rubricID := taskRubricID(taskID); rubricID != 0 {
+		if r, err := loadRubric(rubricID); err == nil {
+			rubricData = r.toResponse()
+		}
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"submission_id": submissionID,
+		"rubric":        rubricData,
+		"grading":       submissionRubricBreakdown(submissionID),
+		"feedback":      feedback,
+	}, "Penilaian rubrik berhasil diambil")
+}
+
+// GradeSubmissionWithRubric - Nilai submission dengan rubrik tugas; nilai 0-100 dihitung dari total poin
+func GradeSubmissionWithRubric(c *gin.Context) {
+	submissionID, ok := loadDosenSubmission(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		VersionID int64  `json:"version_id"`
+		Feedback  string `json:"feedback"`
+		Scores    []struct {
+			CriterionID int    `json:"criterion_id" binding:"required"`
+			LevelID     int    `json:"level_id" binding:"required"`
+			Comment     string `json:"comment"`
+		} `json:"scores" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, err.Error())
+		return
+	}
+
+	var taskID int
+	var courseID string
+	config.DB.QueryRow(`
+		SELECT s.task_id, t.course_id FROM submissions s JOIN tugas t ON t.id = s.task_id WHERE s.id = ?
+	`, submissionID).Scan(&taskID, &courseID)
+
+	if isGradebookLocked(courseID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Nilai mata kuliah sudah dikunci")
+		return
+	}
+
+	rubricID := taskRubricID(taskID)
+	if rubricID == 0 {
+		utils.ValidationError(c, "Tugas ini belum memakai rubrik")
+		return
+	}
+	r, err := loadRubric(rubricID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil rubrik: "+err.Error())
+		return
+	}
+
+	// Setiap kriteria harus diisi tepat satu tingkat capaian
+	levels := map[int]map[int]float64{}
+	for _, crit := range r.Criteria {
+		levels[crit.ID] = map[int]float64{}
+		for _, l := range crit.Levels {
+			levels[crit.ID][l.ID] = l.Points
+		}
+	}
+	scored := map[int]bool{}
+	total := 0.0
+	for _, s := range input.Scores {
+		critLevels, ok := levels[s.CriterionID]
+		if !ok {
+			utils.ValidationError(c, fmt.Sprintf("Kriteria %d bukan bagian dari rubrik tugas", s.CriterionID))
+			return
+		}
+		points, ok := critLevels[s.LevelID]
+		if !ok {
+			utils.ValidationError(c, fmt.Sprintf("Tingkat capaian %d tidak ada pada kriteria %d", s.LevelID, s.CriterionID))
+			return
+		}
+		if scored[s.CriterionID] {
+			utils.ValidationError(c, fmt.Sprintf("Kriteria %d dinilai lebih dari sekali", s.CriterionID))
+			return
+		}
+		scored[s.CriterionID] = true
+		total += points
+	}
+	if len(scored) != len(r.Criteria) {
+		utils.ValidationError(c, "Semua kriteria rubrik wajib dinilai")
+		return
+	}
+
+	versionID, ok := resolveGradedVersion(c, submissionID, input.VersionID)
+	if !ok {
+		return
+	}
+	grade := math.Round(total/r.maxPoints()*10000) / 100
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan nilai: "+err.Error())
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM submission_rubric_scores WHERE submission_id = ?", submissionID)
+	for _, s := range input.Scores {
+		if err != nil {
+			break
+		}
+		_, err = tx.Exec(`
+			INSERT INTO submission_rubric_scores (submission_id, criterion_id, level_id, points, comment)
+			VALUES (?, ?, ?, ?, ?)
+		`, submissionID, s.CriterionID, s.LevelID, levels[s.CriterionID][s.LevelID], s.Comment)
+	}
+	if err == nil {
+		_, err = tx.Exec(submissionGradeQuery, versionID, grade, input.Feedback, submissionID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan nilai: "+err.Error())
+		return
+	}
+
+	var latePenalty float64
+	config.DB.QueryRow("SELECT late_penalty FROM submissions WHERE id = ?", submissionID).Scan(&latePenalty)
+
+	utils.SuccessResponse(c, gin.H{
+		"submission_id": submissionID,
+		"version_id":    versionID,
+		"grade":         grade,
+		"late_penalty":  latePenalty,
+		"final_grade":   applyLatePenalty(grade, latePenalty),
+		"feedback":      input.Feedback,
+		"rubric":        submissionRubricBreakdown(submissionID),
+	}, "Submission graded successfully")
+}
@@ -0,0 +1,440 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// courseSlot - jadwal mingguan satu mata kuliah
+type courseSlot struct {
+	Kode      string
+	Nama      string
+	DosenID   int
+	DosenName string
+	Hari      string
+	Start     time.Time
+	End       time.Time
+	Ruangan   string
+}
+
+func (s courseSlot) label() string {
+	return fmt.Sprintf("%s %s-%s", s.Hari, s.Start.Format("15:04"), s.End.Format("15:04"))
+}
+
+// overlaps - Dua slot bentrok jika harinya sama dan rentang jamnya beririsan
+func (s courseSlot) overlaps(o courseSlot) bool {
+	return s.Hari != "" && s.Hari == o.Hari && s.Start.Before(o.End) && o.Start.Before(s.End)
+}
+
+// normalizeHari - Samakan penulisan hari ("senin", "SENIN" -> "Senin"); kosong jika tidak dikenal
+func normalizeHari(hari string) string {
+	for name := range hariToWeekday {
+		if strings.EqualFold(name, strings.TrimSpace(hari)) {
+			return name
+		}
+	}
+	return ""
+}
+
+// loadCourseSlots - Jadwal mingguan mata kuliah aktif (opsional dibatasi kode tertentu).
+// Ruangan diambil dari tabel schedule seperti pada geofence.
+func loadCourseSlots(kodes ...string) (map[string]courseSlot, error) {
+	query := `
+		SELECT mk.kode, mk.nama, mk.dosen_id, COALESCE(d.name, ''),
+		       COALESCE(mk.hari, ''), COALESCE(mk.jam_mulai, ''), COALESCE(mk.jam_selesai, ''),
+		       COALESCE((
+		           SELECT s.ruangan FROM schedule s
+		           WHERE s.mata_kuliah_kode = mk.kode AND s.ruangan IS NOT NULL AND s.ruangan <> ''
+		           ORDER BY s.id LIMIT 1
+		       ), '')
+		FROM mata_kuliah mk
+		LEFT JOIN dosen d ON mk.dosen_id = d.id
+		WHERE mk.deleted_at IS NULL`
+	var args []interface{}
+	if len(kodes) > 0 {
+		query += " AND mk.kode IN (?" + strings.Repeat(", ?", len(kodes)-1) + ")"
+		for _, kode := range kodes {
+			args = append(args, kode)
+		}
+	}
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slots := map[string]courseSlot{}
+	for rows.Next() {
+		var s courseSlot
+		var hari, jamMulai, jamSelesai string
+		if rows.Scan(&s.Kode, &s.Nama, &s.DosenID, &s.DosenName, &hari, &jamMulai, &jamSelesai, &s.Ruangan) != nil {
+			continue
+		}
+		start, ok1 := parseClock(jamMulai)
+		end, ok2 := parseClock(jamSelesai)
+		s.Hari = normalizeHari(hari)
+		if ok1 && ok2 && end.After(start) {
+			s.Start, s.End = start, end
+		} else {
+			s.Hari = "" // jadwal belum lengkap: tidak pernah bentrok
+		}
+		slots[s.Kode] = s
+	}
+	return slots, nil
+}
+
+// scheduleConflict - satu bentrok jadwal antara dua mata kuliah
+type scheduleConflict struct {
+	Jenis   string // ruangan, dosen, mahasiswa
+	CourseA courseSlot
+	CourseB courseSlot
+	Subjek  string // kode ruangan, nama dosen, atau NIM/nama mahasiswa
+	Jumlah  int    // jumlah mahasiswa terdampak (jenis mahasiswa)
+}
+
+func (c scheduleConflict) toResponse() gin.H {
+	resp := gin.H{
+		"jenis":  c.Jenis,
+		"subjek": c.Subjek,
+		"hari":   c.CourseA.Hari,
+		"course_a": gin.H{
+			"kode":    c.CourseA.Kode,
+			"nama":    c.CourseA.Nama,
+			"jadwal":  c.CourseA.label(),
+			"ruangan": c.CourseA.Ruangan,
+			"dosen":   c.CourseA.DosenName,
+		},
+		"course_b": gin.H{
+			"kode":    c.CourseB.Kode,
+			"nama":    c.CourseB.Nama,
+			"jadwal":  c.CourseB.label(),
+			"ruangan": c.CourseB.Ruangan,
+			"dosen":   c.CourseB.DosenName,
+		},
+	}
+	if c.Jenis == "mahasiswa" {
+		resp["jumlah_mahasiswa"] = c.Jumlah
+	}
+	return resp
+}
+
+func conflictsResponse(conflicts []scheduleConflict) []gin.H {
+	list := make([]gin.H, 0, len(conflicts))
+	for _, c := range conflicts {
+		list = append(list, c.toResponse())
+	}
+	return list
+}
+
+// resourceConflicts - Bentrok ruangan dan dosen antara slot a dan b
+func resourceConflicts(a, b courseSlot) []scheduleConflict {
+	if !a.overlaps(b) {
+		return nil
+	}
+	var conflicts []scheduleConflict
+	if a.Ruangan != "" && strings.EqualFold(a.Ruangan, b.Ruangan) {
+		conflicts = append(conflicts, scheduleConflict{Jenis: "ruangan", CourseA: a, CourseB: b, Subjek: a.Ruangan})
+	}
+	if a.DosenID != 0 && a.DosenID == b.DosenID {
+		conflicts = append(conflicts, scheduleConflict{Jenis: "dosen", CourseA: a, CourseB: b, Subjek: a.DosenName})
+	}
+	return conflicts
+}
+
+// sharedStudents - Jumlah mahasiswa yang mengambil kedua mata kuliah pada semester aktif
+func sharedStudents(kodeA, kodeB string) int {
+	semester := semesterArgs(currentSemesterID())
+	args := append([]interface{}{kodeA, kodeB}, semester...)
+	args = append(args, semester...)
+
+	var count int
+	config.DB.QueryRow(`
+		SELECT COUNT(DISTINCT a.mahasiswa_id)
+		FROM mahasiswa_mata_kuliah a
+		JOIN mahasiswa_mata_kuliah b ON a.mahasiswa_id = b.mahasiswa_id
+		WHERE a.mata_kuliah_kode = ? AND b.mata_kuliah_kode = ?
+			AND `+semesterFilter("a.semester_id")+` AND `+semesterFilter("b.semester_id")+`
+	`, args...).Scan(&count)
+	return count
+}
+
+// checkCourseSchedule - Bentrok ruangan, dosen dan mahasiswa bila mata kuliah
+// dipindah ke jadwal candidate. Dipakai saat admin mengatur jadwal.
+func checkCourseSchedule(candidate courseSlot) ([]scheduleConflict, error) {
+	slots, err := loadCourseSlots()
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []scheduleConflict
+	for kode, other := range slots {
+		if kode == candidate.Kode || !candidate.overlaps(other) {
+			continue
+		}
+		conflicts = append(conflicts, resourceConflicts(candidate, other)...)
+		if n := sharedStudents(candidate.Kode, kode); n > 0 {
+			conflicts = append(conflicts, scheduleConflict{
+				Jenis: "mahasiswa", CourseA: candidate, CourseB: other,
+				Subjek: fmt.Sprintf("%d mahasiswa mengambil kedua mata kuliah", n), Jumlah: n,
+			})
+		}
+	}
+	sortConflicts(conflicts)
+	return conflicts, nil
+}
+
+// checkStudentSchedule - Bentrok waktu antar mata kuliah yang akan diambil mahasiswa,
+// termasuk terhadap mata kuliah yang sudah diambil pada semesterID (semester periode KRS).
+// Dipakai saat KRS.
+func checkStudentSchedule(studentID int, semesterID sql.NullInt64, kodes []string) ([]scheduleConflict, error) {
+	all := map[string]bool{}
+	for _, kode := range kodes {
+		all[kode] = true
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT mata_kuliah_kode FROM mahasiswa_mata_kuliah
+		WHERE mahasiswa_id = ? AND `+semesterFilter("semester_id")+`
+	`, append([]interface{}{studentID}, semesterArgs(semesterID)...)...)
+	if err != nil {
+		return nil, err
+	}
+	enrolled := map[string]bool{}
+	for rows.Next() {
+		var kode string
+		if rows.Scan(&kode) == nil {
+			enrolled[kode] = true
+			all[kode] = true
+		}
+	}
+	rows.Close()
+
+	list := make([]string, 0, len(all))
+	for kode := range all {
+		list = append(list, kode)
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	slots, err := loadCourseSlots(list...)
+	if err != nil {
+		return nil, err
+	}
+
+	var nim, name string
+	config.DB.QueryRow("SELECT nim, name FROM mahasiswa WHERE id = ?", studentID).Scan(&nim, &name)
+
+	var conflicts []scheduleConflict
+	for _, a := range kodes {
+		for _, b := range list {
+			// Pasangan dua mata kuliah baru cukup dicek sekali (a < b);
+			// pasangan dua mata kuliah lama sudah ada sebelum KRS ini
+			if a == b || (!enrolled[b] && b < a) || enrolled[a] {
+				continue
+			}
+			if slots[a].overlaps(slots[b]) {
+				conflicts = append(conflicts, scheduleConflict{
+					Jenis: "mahasiswa", CourseA: slots[a], CourseB: slots[b],
+					Subjek: strings.TrimSpace(nim + " " + name), Jumlah: 1,
+				})
+			}
+		}
+	}
+	sortConflicts(conflicts)
+	return conflicts, nil
+}
+
+// listScheduleConflicts - Semua bentrok ruangan, dosen dan mahasiswa pada jadwal saat ini
+func listScheduleConflicts() ([]scheduleConflict, error) {
+	slots, err := loadCourseSlots()
+	if err != nil {
+		return nil, err
+	}
+
+	kodes := make([]string, 0, len(slots))
+	for kode := range slots {
+		kodes = append(kodes, kode)
+	}
+	sort.Strings(kodes)
+
+	var conflicts []scheduleConflict
+	for i, a := range kodes {
+		for _, b := range kodes[i+1:] {
+			if !slots[a].overlaps(slots[b]) {
+				continue
+			}
+			conflicts = append(conflicts, resourceConflicts(slots[a], slots[b])...)
+			if n := sharedStudents(a, b); n > 0 {
+				conflicts = append(conflicts, scheduleConflict{
+					Jenis: "mahasiswa", CourseA: slots[a], CourseB: slots[b],
+					Subjek: fmt.Sprintf("%d mahasiswa mengambil kedua mata kuliah", n), Jumlah: n,
+				})
+			}
+		}
+	}
+	sortConflicts(conflicts)
+	return conflicts, nil
+}
+
+// sortConflicts - Urutkan per jenis lalu kode mata kuliah agar hasil stabil
+func sortConflicts(conflicts []scheduleConflict) {
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		if conflicts[i].Jenis != conflicts[j].Jenis {
+			return conflicts[i].Jenis < conflicts[j].Jenis
+		}
+		if conflicts[i].CourseA.Kode != conflicts[j].CourseA.Kode {
+			return conflicts[i].CourseA.Kode < conflicts[j].CourseA.Kode
+		}
+		return conflicts[i].CourseB.Kode < conflicts[j].CourseB.Kode
+	})
+}
+
+// GetScheduleConflicts - Admin: daftar semua bentrok jadwal (?jenis=ruangan|dosen|mahasiswa)
+func GetScheduleConflicts(c *gin.Context) {
+	conflicts, err := listScheduleConflicts()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa jadwal: "+err.Error())
+		return
+	}
+
+	jenis := c.Query("jenis")
+	summary := gin.H{"ruangan": 0, "dosen": 0, "mahasiswa": 0}
+	var filtered []scheduleConflict
+	for _, conflict := range conflicts {
+		summary[conflict.Jenis] = summary[conflict.Jenis].(int) + 1
+		if jenis == "" || jenis == conflict.Jenis {
+			filtered = append(filtered, conflict)
+		}
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"conflicts": conflictsResponse(filtered),
+		"total":     len(filtered),
+		"summary":   summary,
+	}, "Bentrok jadwal berhasil diperiksa")
+}
+
+// UpdateCourseSchedule - Admin: atur hari, jam dan ruangan mata kuliah.
+// Ditolak jika bentrok kecuali force=true.
+func UpdateCourseSchedule(c *gin.Context) {
+	courseID := c.Param("course_id")
+
+	var input struct {
+		Hari       string `json:"hari" binding:"required"`
+		JamMulai   string `json:"jam_mulai" binding:"required"`
+		JamSelesai string `json:"jam_selesai" binding:"required"`
+		Ruangan    string `json:"ruangan"`
+		Force      bool   `json:"force"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	hari := normalizeHari(input.Hari)
+	if hari == "" {
+		utils.ValidationError(c, "Hari tidak valid")
+		return
+	}
+	start, ok1 := parseClock(input.JamMulai)
+	end, ok2 := parseClock(input.JamSelesai)
+	if !ok1 || !ok2 {
+		utils.ValidationError(c, "Format jam harus HH:MM")
+		return
+	}
+	if !end.After(start) {
+		utils.ValidationError(c, "Jam selesai harus setelah jam mulai")
+		return
+	}
+
+	slots, err := loadCourseSlots(courseID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil mata kuliah: "+err.Error())
+		return
+	}
+	candidate, found := slots[courseID]
+	if !found {
+		utils.ErrorResponse(c, http.StatusNotFound, "Mata kuliah tidak ditemukan")
+		return
+	}
+
+	ruangan := strings.TrimSpace(input.Ruangan)
+	if ruangan != "" {
+		var exists bool
+		config.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM ruangan WHERE kode = ?)", ruangan).Scan(&exists)
+		if !exists {
+			utils.ValidationError(c, "Ruangan "+ruangan+" tidak terdaftar")
+			return
+		}
+		candidate.Ruangan = ruangan
+	}
+	candidate.Hari, candidate.Start, candidate.End = hari, start, end
+
+	conflicts, err := checkCourseSchedule(candidate)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memeriksa jadwal: "+err.Error())
+		return
+	}
+	if len(conflicts) > 0 && !input.Force {
+		utils.ConflictResponse(c, gin.H{
+			"conflicts": conflictsResponse(conflicts),
+			"total":     len(conflicts),
+		}, "Jadwal bentrok dengan mata kuliah lain")
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuka transaksi")
+		return
+	}
+	defer tx.Rollback()
+
+	jamMulai, jamSelesai := start.Format("15:04:05"), end.Format("15:04:05")
+	if _, err := tx.Exec(`
+		UPDATE mata_kuliah SET hari = ?, jam_mulai = ?, jam_selesai = ?, updated_at = NOW() WHERE kode = ?
+	`, hari, jamMulai, jamSelesai, courseID); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan jadwal: "+err.Error())
+		return
+	}
+
+	// Baris schedule menyimpan ruangan; satu baris per mata kuliah
+	var scheduleID int
+	if tx.QueryRow("SELECT id FROM schedule WHERE mata_kuliah_kode = ? ORDER BY id LIMIT 1", courseID).Scan(&scheduleID) == nil {
+		_, err = tx.Exec(`
+			UPDATE schedule SET hari = ?, jam_mulai = ?, jam_selesai = ?, ruangan = ? WHERE id = ?
+		`, strings.ToLower(hari), jamMulai, jamSelesai, candidate.Ruangan, scheduleID)
+	} else {
+		_, err = tx.Exec(`
+			INSERT INTO schedule (mata_kuliah_kode, hari, jam_mulai, jam_selesai, ruangan) VALUES (?, ?, ?, ?, ?)
+		`, courseID, strings.ToLower(hari), jamMulai, jamSelesai, candidate.Ruangan)
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan ruangan: "+err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan jadwal")
+		return
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"kode":        courseID,
+		"hari":        hari,
+		"jam_mulai":   jamMulai,
+		"jam_selesai": jamSelesai,
+		"ruangan":     candidate.Ruangan,
+		"conflicts":   conflictsResponse(conflicts),
+	}, "Jadwal mata kuliah berhasil disimpan")
+}
@@ -0,0 +1,564 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// semesterAkademik - semester beserta pekan ujian dan hari libur
+type semesterAkademik struct {
+	ID             int
+	Nama           string
+	TahunAjaran    string
+	Jenis          string // ganjil, genap, pendek
+	TanggalMulai   time.Time
+	TanggalSelesai time.Time
+	UTSMulai       sql.NullTime
+	UTSSelesai     sql.NullTime
+	UASMulai       sql.NullTime
+	UASSelesai     sql.NullTime
+	IsActive       bool
+	Libur          map[string]string // "2006-01-02" -> keterangan
+}
+
+const semesterSelect = `
+	SELECT id, nama, tahun_ajaran, jenis, tanggal_mulai, tanggal_selesai,
+	       uts_mulai, uts_selesai, uas_mulai, uas_selesai, is_active
+	FROM semester_akademik
+`
+
+// loadSemester - Satu semester beserta hari liburnya
+func loadSemester(where string, args ...interface{}) (semesterAkademik, error) {
+	var s semesterAkademik
+	err := config.DB.QueryRow(semesterSelect+" WHERE "+where+" LIMIT 1", args...).Scan(
+		&s.ID, &s.Nama, &s.TahunAjaran, &s.Jenis, &s.TanggalMulai, &s.TanggalSelesai,
+		&s.UTSMulai, &s.UTSSelesai, &s.UASMulai, &s.UASSelesai, &s.IsActive)
+	if err != nil {
+		return s, err
+	}
+
+	s.Libur = map[string]string{}
+	rows, err := config.DB.Query("SELECT tanggal, COALESCE(keterangan, '') FROM hari_libur WHERE semester_id = ?", s.ID)
+	if err != nil {
+		return s, nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tanggal time.Time
+		var keterangan string
+		if rows.Scan(&tanggal, &keterangan) == nil {
+			s.Libur[tanggal.Format("2006-01-02")] = keterangan
+		}
+	}
+	return s, nil
+}
+
+// activeSemester - Semester yang ditandai aktif, atau yang rentang tanggalnya mencakup hari ini
+func activeSemester() (semesterAkademik, error) {
+	s, err := loadSemester("is_active = 1 ORDER BY tanggal_mulai DESC")
+	if err == nil {
+		return s, nil
+	}
+	return loadSemester("CURDATE() BETWEEN tanggal_mulai AND tanggal_selesai ORDER BY tanggal_mulai DESC")
+}
+
+// currentSemesterID - id semester aktif untuk kolom semester_id (NULL jika belum diatur)
+func currentSemesterID() sql.NullInt64 {
+	s, err := activeSemester()
+	if err != nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(s.ID), Valid: true}
+}
+
+// calendarDate - Tanggal kalender tanpa jam (UTC) agar perbandingan tidak terpengaruh zona waktu
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func inRange(d time.Time, from, to sql.NullTime) bool {
+	return from.Valid && to.Valid && !d.Before(calendarDate(from.Time)) && !d.After(calendarDate(to.Time))
+}
+
+// dayKind - Jenis hari dalam kalender akademik: kuliah, libur, uts, uas atau di_luar_semester
+func (s semesterAkademik) dayKind(d time.Time) (string, string) {
+	d = calendarDate(d)
+	if d.Before(calendarDate(s.TanggalMulai)) || d.After(calendarDate(s.TanggalSelesai)) {
+		return "di_luar_semester", ""
+	}
+	if keterangan, ok := s.Libur[d.Format("2006-01-02")]; ok {
+		return "libur", keterangan
+	}
+	if inRange(d, s.UTSMulai, s.UTSSelesai) {
+		return "uts", "Pekan UTS"
+	}
+	if inRange(d, s.UASMulai, s.UASSelesai) {
+		return "uas", "Pekan UAS"
+	}
+	return "kuliah", ""
+}
+
+func nullInt(v sql.NullInt64) interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Int64
+}
+
+func nullDate(t sql.NullTime) interface{} {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time.Format("2006-01-02")
+}
+
+func (s semesterAkademik) toResponse() gin.H {
+	libur := []gin.H{}
+	for tanggal, keterangan := range s.Libur {
+		libur = append(libur, gin.H{"tanggal": tanggal, "keterangan": keterangan})
+	}
+	return gin.H{
+		"id":              s.ID,
+		"nama":            s.Nama,
+		"tahun_ajaran":    s.TahunAjaran,
+		"jenis":           s.Jenis,
+		"tanggal_mulai":   s.TanggalMulai.Format("2006-01-02"),
+		"tanggal_selesai": s.TanggalSelesai.Format("2006-01-02"),
+		"uts_mulai":       nullDate(s.UTSMulai),
+		"uts_selesai":     nullDate(s.UTSSelesai),
+		"uas_mulai":       nullDate(s.UASMulai),
+		"uas_selesai":     nullDate(s.UASSelesai),
+		"is_active":       s.IsActive,
+		"libur":           libur,
+	}
+}
+
+// courseMeeting - satu pertemuan terjadwal dalam kalender semester
+type courseMeeting struct {
+	PertemuanKe int
+	Tanggal     time.Time
+	Pengganti   bool
+}
+
+// courseMeetings - Tanggal pertemuan 1-16 mata kuliah dalam semester: setiap hari kuliah
+// mingguan yang bukan libur atau pekan ujian, lalu ditimpa jadwal pertemuan pengganti.
+func courseMeetings(s semesterAkademik, courseID string) ([]courseMeeting, error) {
+	var hari string
+	if err := config.DB.QueryRow("SELECT COALESCE(hari, '') FROM mata_kuliah WHERE kode = ?", courseID).Scan(&hari); err != nil {
+		return nil, err
+	}
+
+	var meetings []courseMeeting
+	if weekday, ok := hariToWeekday[normalizeHari(hari)]; ok {
+		d := calendarDate(s.TanggalMulai)
+		for d.Weekday() != weekday {
+			d = d.AddDate(0, 0, 1)
+		}
+		for end := calendarDate(s.TanggalSelesai); !d.After(end) && len(meetings) < exportPertemuanCount; d = d.AddDate(0, 0, 7) {
+			if kind, _ := s.dayKind(d); kind != "kuliah" {
+				continue
+			}
+			meetings = append(meetings, courseMeeting{PertemuanKe: len(meetings) + 1, Tanggal: d})
+		}
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT pertemuan_ke, tanggal FROM pertemuan_pengganti
+		WHERE course_id = ? AND tanggal BETWEEN ? AND ? AND (semester_id IS NULL OR semester_id = ?)
+	`, courseID, s.TanggalMulai.Format("2006-01-02"), s.TanggalSelesai.Format("2006-01-02"), s.ID)
+	if err != nil {
+		return meetings, nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m courseMeeting
+		if rows.Scan(&m.PertemuanKe, &m.Tanggal) != nil {
+			continue
+		}
+		m.Tanggal, m.Pengganti = calendarDate(m.Tanggal), true
+		replaced := false
+		for i := range meetings {
+			if meetings[i].PertemuanKe == m.PertemuanKe {
+				meetings[i], replaced = m, true
+			}
+		}
+		if !replaced {
+			meetings = append(meetings, m)
+		}
+	}
+	sort.Slice(meetings, func(i, j int) bool { return meetings[i].PertemuanKe < meetings[j].PertemuanKe })
+	return meetings, nil
+}
+
+// pertemuanForDate - Nomor pertemuan mata kuliah pada tanggal tertentu (0 jika tidak ada kuliah)
+func pertemuanForDate(s semesterAkademik, courseID string, date time.Time) (int, error) {
+	meetings, err := courseMeetings(s, courseID)
+	if err != nil {
+		return 0, err
+	}
+	key := calendarDate(date).Format("2006-01-02")
+	for _, m := range meetings {
+		if m.Tanggal.Format("2006-01-02") == key {
+			return m.PertemuanKe, nil
+		}
+	}
+	return 0, nil
+}
+
+// semesterScope - Filter opsional ?semester_id= untuk query daftar; kolom adalah nama kolom semester_id
+func semesterScope(c *gin.Context, column string) (string, []interface{}) {
+	id, err := strconv.Atoi(c.Query("semester_id"))
+	if err != nil || id <= 0 {
+		return "", nil
+	}
+	return " AND " + column + " = ?", []interface{}{id}
+}
+
+// ==================== ENDPOINTS ====================
+
+// semesterInput - Body request membuat/mengubah semester
+type semesterInput struct {
+	Nama           string `json:"nama" binding:"required"`
+	TahunAjaran    string `json:"tahun_ajaran" binding:"required"`
+	Jenis          string `json:"jenis" binding:"required,oneof=ganjil genap pendek"`
+	TanggalMulai   string `json:"tanggal_mulai" binding:"required"`
+	TanggalSelesai string `json:"tanggal_selesai" binding:"required"`
+	UTSMulai       string `json:"uts_mulai"`
+	UTSSelesai     string `json:"uts_selesai"`
+	UASMulai       string `json:"uas_mulai"`
+	UASSelesai     string `json:"uas_selesai"`
+}
+
+// parseDates - Validasi tanggal semester; mengembalikan pesan error kosong jika valid
+func (in semesterInput) parseDates() (dates [6]interface{}, msg string) {
+	values := [6]string{in.TanggalMulai, in.TanggalSelesai, in.UTSMulai, in.UTSSelesai, in.UASMulai, in.UASSelesai}
+	var parsed [6]time.Time
+	for i, v := range values {
+		if v == "" {
+			if i < 2 {
+				return dates, "Tanggal mulai dan selesai semester wajib diisi"
+			}
+			continue
+		}
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return dates, "Format tanggal harus YYYY-MM-DD"
+		}
+		parsed[i], dates[i] = t, v
+	}
+
+	if !parsed[1].After(parsed[0]) {
+		return dates, "Tanggal selesai harus setelah tanggal mulai"
+	}
+	for i, label := range map[int]string{2: "UTS", 4: "UAS"} {
+		if (dates[i] == nil) != (dates[i+1] == nil) {
+			return dates, "Tanggal mulai dan selesai pekan " + label + " harus diisi bersamaan"
+		}
+		if dates[i] == nil {
+			continue
+		}
+		if parsed[i+1].Before(parsed[i]) || parsed[i].Before(parsed[0]) || parsed[i+1].After(parsed[1]) {
+			return dates, "Pekan " + label + " harus berada di dalam rentang semester"
+		}
+	}
+	return dates, ""
+}
+
+// GetSemesters - Admin: daftar semester
+func GetSemesters(c *gin.Context) {
+	rows, err := config.DB.Query(semesterSelect + " ORDER BY tanggal_mulai DESC")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil semester: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	list := []gin.H{}
+	for rows.Next() {
+		var s semesterAkademik
+		if rows.Scan(&s.ID, &s.Nama, &s.TahunAjaran, &s.Jenis, &s.TanggalMulai, &s.TanggalSelesai,
+			&s.UTSMulai, &s.UTSSelesai, &s.UASMulai, &s.UASSelesai, &s.IsActive) != nil {
+			continue
+		}
+		item := s.toResponse()
+		delete(item, "libur")
+		list = append(list, item)
+	}
+
+	utils.SuccessResponse(c, list, "Semester berhasil diambil")
+}
+
+// CreateSemester - Admin: buat semester baru
+func CreateSemester(c *gin.Context) {
+	var input semesterInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+	dates, msg := input.parseDates()
+	if msg != "" {
+		utils.ValidationError(c, msg)
+		return
+	}
+
+	result, err := config.DB.Exec(`
+		INSERT INTO semester_akademik
+		(nama, tahun_ajaran, jenis, tanggal_mulai, tanggal_selesai, uts_mulai, uts_selesai, uas_mulai, uas_selesai)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, input.Nama, input.TahunAjaran, input.Jenis, dates[0], dates[1], dates[2], dates[3], dates[4], dates[5])
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuat semester: "+err.Error())
+		return
+	}
+	id, _ := result.LastInsertId()
+
+	s, _ := loadSemester("id = ?", id)
+	utils.SuccessResponse(c, s.toResponse(), "Semester berhasil dibuat")
+}
+
+// UpdateSemester - Admin: ubah tanggal semester dan pekan ujian
+func UpdateSemester(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ValidationError(c, "ID semester tidak valid")
+		return
+	}
+
+	var input semesterInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+	dates, msg := input.parseDates()
+	if msg != "" {
+		utils.ValidationError(c, msg)
+		return
+	}
+
+	result, err := config.DB.Exec(`
+		UPDATE semester_akademik
+		SET nama = ?, tahun_ajaran = ?, jenis = ?, tanggal_mulai = ?, tanggal_selesai = ?,
+		    uts_mulai = ?, uts_selesai = ?, uas_mulai = ?, uas_selesai = ?, updated_at = NOW()
+		WHERE id = ?
+	`, input.Nama, input.TahunAjaran, input.Jenis, dates[0], dates[1], dates[2], dates[3], dates[4], dates[5], id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengubah semester: "+err.Error())
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		if _, err := loadSemester("id = ?", id); err != nil {
+			utils.ErrorResponse(c, http.StatusNotFound, "Semester tidak ditemukan")
+			return
+		}
+	}
+
+	s, _ := loadSemester("id = ?", id)
+	utils.SuccessResponse(c, s.toResponse(), "Semester berhasil diubah")
+}
+
+// ActivateSemester - Admin: jadikan semester sebagai semester aktif
+func ActivateSemester(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ValidationError(c, "ID semester tidak valid")
+		return
+	}
+
+	s, err := loadSemester("id = ?", id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Semester tidak ditemukan")
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal membuka transaksi")
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE semester_akademik SET is_active = 0 WHERE is_active = 1"); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengaktifkan semester: "+err.Error())
+		return
+	}
+	if _, err := tx.Exec("UPDATE semester_akademik SET is_active = 1 WHERE id = ?", id); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengaktifkan semester: "+err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengaktifkan semester")
+		return
+	}
+
+	s.IsActive = true
+	utils.SuccessResponse(c, s.toResponse(), "Semester "+s.Nama+" sekarang aktif")
+}
+
+// AddHariLibur - Admin: tambah hari libur pada semester
+func AddHariLibur(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ValidationError(c, "ID semester tidak valid")
+		return
+	}
+
+	var input struct {
+		Tanggal    string `json:"tanggal" binding:"required"`
+		Keterangan string `json:"keterangan" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ValidationError(c, "Invalid input: "+err.Error())
+		return
+	}
+
+	tanggal, err := time.Parse("2006-01-02", input.Tanggal)
+	if err != nil {
+		utils.ValidationError(c, "Format tanggal harus YYYY-MM-DD")
+		return
+	}
+
+	s, err := loadSemester("id = ?", id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Semester tidak ditemukan")
+		return
+	}
+	if kind, _ := s.dayKind(tanggal); kind == "di_luar_semester" {
+		utils.ValidationError(c, "Tanggal libur harus berada di dalam rentang semester")
+		return
+	}
+
+	_, err = config.DB.Exec(`
+		INSERT INTO hari_libur (semester_id, tanggal, keterangan) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE keterangan = VALUES(keterangan)
+	`, id, input.Tanggal, strings.TrimSpace(input.Keterangan))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyimpan hari libur: "+err.Error())
+		return
+	}
+
+	s, _ = loadSemester("id = ?", id)
+	utils.SuccessResponse(c, s.toResponse(), "Hari libur berhasil disimpan")
+}
+
+// DeleteHariLibur - Admin: hapus hari libur dari semester
+func DeleteHariLibur(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ValidationError(c, "ID semester tidak valid")
+		return
+	}
+
+	result, err := config.DB.Exec("DELETE FROM hari_libur WHERE semester_id = ? AND tanggal = ?", id, c.Param("tanggal"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menghapus hari libur: "+err.Error())
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Hari libur tidak ditemukan")
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Hari libur berhasil dihapus")
+}
+
+// GetActiveSemester - Semester aktif beserta pekan ujian dan hari libur
+func GetActiveSemester(c *gin.Context) {
+	s, err := activeSemester()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Belum ada semester aktif")
+		return
+	}
+
+	resp := s.toResponse()
+	kind, keterangan := s.dayKind(time.Now())
+	resp["hari_ini"] = gin.H{"jenis": kind, "keterangan": keterangan}
+	utils.SuccessResponse(c, resp, "Semester aktif berhasil diambil")
+}
+
+// GetCalendarPertemuan - Petakan tanggal ke nomor pertemuan mata kuliah.
+// ?course_id= wajib; ?tanggal= untuk satu tanggal, tanpa tanggal untuk seluruh jadwal semester.
+func GetCalendarPertemuan(c *gin.Context) {
+	courseID := c.Query("course_id")
+	if courseID == "" {
+		utils.ValidationError(c, "course_id wajib diisi")
+		return
+	}
+
+	var s semesterAkademik
+	var err error
+	if v := c.Query("semester_id"); v != "" {
+		s, err = loadSemester("id = ?", v)
+	} else {
+		s, err = activeSemester()
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Semester tidak ditemukan")
+		return
+	}
+
+	meetings, err := courseMeetings(s, courseID)
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(c, http.StatusNotFound, "Mata kuliah tidak ditemukan")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal menyusun kalender: "+err.Error())
+		return
+	}
+
+	if v := c.Query("tanggal"); v != "" {
+		tanggal, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			utils.ValidationError(c, "Format tanggal harus YYYY-MM-DD")
+			return
+		}
+
+		kind, keterangan := s.dayKind(tanggal)
+		var pertemuan interface{}
+		pengganti := false
+		for _, m := range meetings {
+			if m.Tanggal.Equal(calendarDate(tanggal)) {
+				pertemuan, pengganti = m.PertemuanKe, m.Pengganti
+				kind = "kuliah"
+			}
+		}
+
+		utils.SuccessResponse(c, gin.H{
+			"course_id":    courseID,
+			"semester_id":  s.ID,
+			"tanggal":      v,
+			"hari":         hariIndonesia(tanggal),
+			"jenis_hari":   kind,
+			"keterangan":   keterangan,
+			"pertemuan_ke": pertemuan,
+			"pengganti":    pengganti,
+		}, "Pemetaan tanggal berhasil")
+		return
+	}
+
+	list := []gin.H{}
+	for _, m := range meetings {
+		list = append(list, gin.H{
+			"pertemuan_ke": m.PertemuanKe,
+			"tanggal":      m.Tanggal.Format("2006-01-02"),
+			"hari":         hariIndonesia(m.Tanggal),
+			"pengganti":    m.Pengganti,
+		})
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"course_id": courseID,
+		"semester":  s.toResponse(),
+		"pertemuan": list,
+		"total":     len(list),
+	}, "Kalender pertemuan berhasil diambil")
+}
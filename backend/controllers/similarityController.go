@@ -0,0 +1,311 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultSimilarityThreshold - estimasi Jaccard minimum (shingle 5 kata) agar pasangan dilaporkan
+const defaultSimilarityThreshold = 0.2
+
+// similarityPassageLimit - jumlah cuplikan sama yang disimpan per pasangan
+const similarityPassageLimit = 5
+
+// similarityRunTimeout - run yang masih running lebih lama dari ini dianggap gagal (misalnya server restart)
+const similarityRunTimeout = 30 * time.Minute
+
+// getSimilarityThreshold membaca ambang dari env SIMILARITY_THRESHOLD (0-1)
+func getSimilarityThreshold() float64 {
+	if v := os.Getenv("SIMILARITY_THRESHOLD"); v != "" {
+		if t, err := strconv.ParseFloat(v, 64); err == nil && t > 0 && t <= 1 {
+			return t
+		}
+	}
+	return defaultSimilarityThreshold
+}
+
+// similaritySubmission - submission yang ikut diperiksa
+type similaritySubmission struct {
+	ID         int
+	StudentID  int
+	Name       string
+	NIM        string
+	AnswerText string
+	FileURL    string
+}
+
+// StartSimilarityCheck - Jalankan pemeriksaan kemiripan untuk satu tugas di background
+func StartSimilarityCheck(c *gin.Context) {
+	_, taskID, ok := loadCourseTask(c)
+	if !ok {
+		return
+	}
+
+	expireStaleSimilarityRuns(taskID)
+
+	// running_task_id unik: hanya satu run berjalan per tugas, diklaim atomik oleh INSERT
+	result, err := config.DB.Exec(`
+		INSERT INTO similarity_runs (task_id, running_task_id, status, requested_by, started_at)
+		VALUES (?, ?, 'running', ?, NOW())
+	`, taskID, taskID, c.GetInt("user_id"))
+	if config.IsDuplicateKeyError(err) {
+		utils.ErrorResponse(c, http.StatusConflict, "Pemeriksaan kemiripan untuk tugas ini sedang berjalan")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal memulai pemeriksaan kemiripan: "+err.Error())
+		return
+	}
+	runID, _ := result.LastInsertId()
+
+	go runSimilarityCheck(int(runID), taskID)
+
+	utils.SuccessResponse(c, gin.H{
+		"run_id":  runID,
+		"task_id": taskID,
+		"status":  "running",
+	}, "Pemeriksaan kemiripan dijalankan")
+}
+
+// expireStaleSimilarityRuns menandai run yang macet sebagai gagal agar tugas bisa diperiksa ulang
+func expireStaleSimilarityRuns(taskID int) {
+	config.DB.Exec(`
+		UPDATE similarity_runs
+		SET status = 'failed', notes = 'Pemeriksaan terhenti sebelum selesai', running_task_id = NULL, finished_at = NOW()
+		WHERE running_task_id = ? AND started_at < NOW() - INTERVAL ? SECOND
+	`, taskID, int(similarityRunTimeout.Seconds()))
+}
+
+// runSimilarityCheck membandingkan semua pasangan submission satu tugas dan menyimpan pasangan di atas ambang
+func runSimilarityCheck(runID, taskID int) {
+	finish := func(status, notes string, checked, flagged int) {
+		config.DB.Exec(`
+			UPDATE similarity_runs
+			SET status = ?, notes = ?, submissions_checked = ?, pairs_flagged = ?, running_task_id = NULL,
+				finished_at = NOW()
+			WHERE id = ?
+		`, status, notes, checked, flagged, runID)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Similarity check %d gagal: %v", runID, r)
+			finish("failed", fmt.Sprint(r), 0, 0)
+		}
+	}()
+
+	rows, err := config.DB.Query(`
+		SELECT s.id, s.student_id, m.name, m.nim, COALESCE(s.answer_text, ''), COALESCE(s.file_url, '')
+		FROM submissions s
+		JOIN mahasiswa m ON m.id = s.student_id
+		WHERE s.task_id = ? AND s.deleted_at IS NULL
+	`, taskID)
+	if err != nil {
+		finish("failed", err.Error(), 0, 0)
+		return
+	}
+	var subs []similaritySubmission
+	for rows.Next() {
+		var s similaritySubmission
+		if err := rows.Scan(&s.ID, &s.StudentID, &s.Name, &s.NIM, &s.AnswerText, &s.FileURL); err == nil {
+			subs = append(subs, s)
+		}
+	}
+	rows.Close()
+
+	// Teks tiap submission = jawaban teks + teks hasil ekstraksi file (PDF/DOCX)
+	var notes []string
+	var docs []*utils.SimilarityDoc
+	for _, s := range subs {
+		text := s.AnswerText
+		if s.FileURL != "" {
+			extracted, err := utils.ExtractDocumentText("." + s.FileURL)
+			if err != nil {
+				notes = append(notes, fmt.Sprintf("%s: file tidak dapat dibaca (%v)", s.NIM, err))
+			}
+			text += "\n" + extracted
+		}
+		doc := utils.NewSimilarityDoc(s.ID, text)
+		if !doc.Comparable() {
+			notes = append(notes, fmt.Sprintf("%s: teks terlalu pendek untuk dibandingkan", s.NIM))
+			continue
+		}
+		docs = append(docs, doc)
+	}
+
+	threshold := getSimilarityThreshold()
+	flagged := 0
+	for i := 0; i < len(docs); i++ {
+		for j := i + 1; j < len(docs); j++ {
+			a, b := docs[i], docs[j]
+			estimate := utils.EstimateSimilarity(a, b)
+			if estimate < threshold {
+				continue
+			}
+
+			passages, _ := json.Marshal(utils.MatchingPassages(a, b, similarityPassageLimit))
+			_, err := config.DB.Exec(`
+				INSERT INTO similarity_results (run_id, task_id, submission_a, submission_b, score, jaccard, passages)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+			`, runID, taskID, a.ID, b.ID, estimate, utils.Jaccard(a, b), string(passages))
+			if err != nil {
+				log.Printf("Similarity check %d: gagal menyimpan pasangan %d-%d: %v", runID, a.ID, b.ID, err)
+				continue
+			}
+			flagged++
+		}
+	}
+
+	finish("done", strings.Join(notes, "\n"), len(docs), flagged)
+}
+
+// GetSimilarityReport - Hasil pemeriksaan kemiripan terakhir untuk satu tugas
+func GetSimilarityReport(c *gin.Context) {
+	_, taskID, ok := loadCourseTask(c)
+	if !ok {
+		return
+	}
+
+	expireStaleSimilarityRuns(taskID)
+
+	var runID, checked, flagged int
+	var status, notes string
+	var startedAt time.Time
+	var finishedAt sql.NullTime
+	err := config.DB.QueryRow(`
+		SELECT id, status, COALESCE(notes, ''), submissions_checked, pairs_flagged, started_at, finished_at
+		FROM similarity_runs
+		WHERE task_id = ?
+		ORDER BY id DESC LIMIT 1
+	`, taskID).Scan(&runID, &status, &notes, &checked, &flagged, &startedAt, &finishedAt)
+	if err != nil {
+		utils.SuccessResponse(c, nil, "Belum ada pemeriksaan kemiripan untuk tugas ini")
+		return
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT r.submission_a, ma.name, ma.nim, r.submission_b, mb.name, mb.nim, r.score, r.jaccard, COALESCE(r.passages, '[]')
+		FROM similarity_results r
+		JOIN submissions sa ON sa.id = r.submission_a
+		JOIN mahasiswa ma ON ma.id = sa.student_id
+		JOIN submissions sb ON sb.id = r.submission_b
+		JOIN mahasiswa mb ON mb.id = sb.student_id
+		WHERE r.run_id = ?
+		ORDER BY r.score DESC
+	`, runID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil hasil kemiripan: "+err.Error())
+		return
+	}
+	defer rows.Close()
+
+	pairs := []gin.H{}
+	for rows.Next() {
+		var subA, subB int
+		var nameA, nimA, nameB, nimB, passagesJSON string
+		var score, jaccard float64
+		if err := rows.Scan(&subA, &nameA, &nimA, &subB, &nameB, &nimB, &score, &jaccard, &passagesJSON); err != nil {
+			continue
+		}
+		var passages []utils.SimilarityPassage
+		json.Unmarshal([]byte(passagesJSON), &passages)
+		pairs = append(pairs, gin.H{
+			"submission_a": gin.H{"submission_id": subA, "student_name": nameA, "student_nim": nimA},
+			"submission_b": gin.H{"submission_id": subB, "student_name": nameB, "student_nim": nimB},
+			"score":        score,
+			"jaccard":      jaccard,
+			"passages":     passages,
+		})
+	}
+
+	var notesList []string
+	if notes != "" {
+		notesList = strings.Split(notes, "\n")
+	}
+
+	finished := ""
+	if finishedAt.Valid {
+		finished = finishedAt.Time.Format("2006-01-02 15:04:05")
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"run_id":              runID,
+		"task_id":             taskID,
+		"status":              status,
+		"threshold":           getSimilarityThreshold(),
+		"submissions_checked": checked,
+		"pairs_flagged":       flagged,
+		"started_at":          startedAt.Format("2006-01-02 15:04:05"),
+		"finished_at":         finished,
+		"notes":               notesList,
+		"pairs":               pairs,
+	}, "Hasil pemeriksaan kemiripan berhasil diambil")
+}
+
+// courseSimilarityHighlights - skor kemiripan tertinggi per submission dari pemeriksaan terakhir
+// tiap tugas di mata kuliah, untuk ditampilkan di daftar pengumpulan dosen
+func courseSimilarityHighlights(courseID string) map[int]gin.H {
+	rows, err := config.DB.Query(`
+		SELECT r.submission_a, r.submission_b, r.score, ma.name, mb.name
+		FROM similarity_results r
+		JOIN submissions sa ON sa.id = r.submission_a
+		JOIN mahasiswa ma ON ma.id = sa.student_id
+		JOIN submissions sb ON sb.id = r.submission_b
+		JOIN mahasiswa mb ON mb.id = sb.student_id
+		WHERE r.run_id IN (
+			SELECT MAX(sr.id) FROM similarity_runs sr
+			JOIN tugas t ON t.id = sr.task_id
+			WHERE t.course_id = ? AND sr.status = 'done'
+			GROUP BY sr.task_id
+		)
+	`, courseID)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	type best struct {
+		score float64
+		with  string
+		pairs int
+	}
+	highest := map[int]*best{}
+	note := func(submissionID int, score float64, with string) {
+		b, ok := highest[submissionID]
+		if !ok {
+			b = &best{}
+			highest[submissionID] = b
+		}
+		b.pairs++
+		if score > b.score {
+			b.score, b.with = score, with
+		}
+	}
+	for rows.Next() {
+		var subA, subB int
+		var score float64
+		var nameA, nameB string
+		if err := rows.Scan(&subA, &subB, &score, &nameA, &nameB); err != nil {
+			continue
+		}
+		note(subA, score, nameB)
+		note(subB, score, nameA)
+	}
+
+	result := make(map[int]gin.H, len(highest))
+	for id, b := range highest {
+		result[id] = gin.H{"max_score": b.score, "most_similar_to": b.with, "flagged_pairs": b.pairs}
+	}
+	return result
+}
@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Setiap pengumpulan tugas disimpan sebagai versi yang tidak pernah diubah.
+// Baris submissions tetap menjadi ringkasan (versi terakhir + nilai), submission_versions menyimpan riwayatnya.
+
+// submissionContentHash - SHA-256 atas jawaban teks dan isi file yang dikumpulkan
+func submissionContentHash(answerText, fileURL string) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(answerText))
+	h.Write([]byte{0})
+	if fileURL != "" {
+		f, err := os.Open("." + fileURL)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// submissionVersion - satu versi pengumpulan tugas
+type submissionVersion struct {
+	ID           int64
+	SubmissionID int
+	TaskID       int
+	StudentID    int
+	VersionNo    int
+	FileURL      string
+	AnswerText   string
+	ContentHash  string
+	ReceiptCode  string
+	SubmittedAt  time.Time
+	LateDays     int
+	LatePenalty  float64
+}
+
+func (v submissionVersion) toResponse() gin.H {
+	return gin.H{
+		"version_id":   v.ID,
+		"version_no":   v.VersionNo,
+		"file_url":     v.FileURL,
+		"answer_text":  v.AnswerText,
+		"content_hash": v.ContentHash,
+		"receipt_code": v.ReceiptCode,
+		"submitted_at": v.SubmittedAt.Format("2006-01-02 15:04:05"),
+		"late_days":    v.LateDays,
+		"late_penalty": v.LatePenalty,
+	}
+}
+
+// receiptResponse - bukti pengumpulan yang dikembalikan ke mahasiswa setelah upload
+func (v submissionVersion) receiptResponse() gin.H {
+	return gin.H{
+		"receipt_code": v.ReceiptCode,
+		"version_no":   v.VersionNo,
+		"content_hash": v.ContentHash,
+		"submitted_at": v.SubmittedAt.Format("2006-01-02 15:04:05"),
+		"verify_url":   getDocumentVerifyURL() + v.ReceiptCode,
+	}
+}
+
+// recordSubmissionVersion menambah versi baru dan mencatat bukti pengumpulannya di dokumen_terbit
+// sehingga kode bukti bisa dicek lewat endpoint verifikasi dokumen publik.
+func recordSubmissionVersion(tx *sql.Tx, v *submissionVersion, taskTitle string, issuedBy int) error {
+	if err := tx.QueryRow("SELECT COALESCE(MAX(version_no), 0) + 1 FROM submission_versions WHERE submission_id = ?",
+		v.SubmissionID).Scan(&v.VersionNo); err != nil {
+		return err
+	}
+
+	code, err := generateVerificationCode()
+	if err != nil {
+		return err
+	}
+	v.ReceiptCode = code
+
+	result, err := tx.Exec(`
+		INSERT INTO submission_versions
+		(submission_id, task_id, student_id, version_no, file_url, answer_text, content_hash, receipt_code,
+		 submitted_at, late_days, late_penalty, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())
+	`, v.SubmissionID, v.TaskID, v.StudentID, v.VersionNo, v.FileURL, v.AnswerText, v.ContentHash, v.ReceiptCode,
+		v.SubmittedAt, v.LateDays, v.LatePenalty)
+	if err != nil {
+		return err
+	}
+	v.ID, _ = result.LastInsertId()
+
+	_, err = tx.Exec(`
+		INSERT INTO dokumen_terbit (kode_verifikasi, jenis, student_id, referensi, ringkasan, checksum, issued_by, created_at)
+		VALUES (?, 'bukti_tugas', ?, ?, ?, ?, ?, NOW())
+	`, v.ReceiptCode, v.StudentID, strconv.FormatInt(v.ID, 10),
+		truncateRunes(fmt.Sprintf("Tugas \"%s\" versi %d diterima %s", taskTitle, v.VersionNo, v.SubmittedAt.Format("2006-01-02 15:04:05")), 255),
+		v.ContentHash, issuedBy)
+	return err
+}
+
+// truncateRunes memotong string ke maksimal n karakter
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// loadSubmissionVersions - riwayat versi, terbaru di atas
+func loadSubmissionVersions(where string, args ...interface{}) ([]submissionVersion, error) {
+	rows, err := config.DB.Query(`
+		SELECT id, submission_id, task_id, student_id, version_no, COALESCE(file_url, ''), COALESCE(answer_text, ''),
+			COALESCE(content_hash, ''), COALESCE(receipt_code, ''), submitted_at, late_days, late_penalty
+		FROM submission_versions
+		WHERE `+where+`
+		ORDER BY version_no DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []submissionVersion
+	for rows.Next() {
+		var v submissionVersion
+		if err := rows.Scan(&v.ID, &v.SubmissionID, &v.TaskID, &v.StudentID, &v.VersionNo, &v.FileURL, &v.AnswerText,
+			&v.ContentHash, &v.ReceiptCode, &v.SubmittedAt, &v.LateDays, &v.LatePenalty); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// activeVersionID - versi yang dinilai: pilihan dosen, atau versi terakhir jika belum dipilih
+func activeVersionID(submissionID int) (int64, error) {
+	var id sql.NullInt64
+	err := config.DB.QueryRow(`
+		SELECT COALESCE(s.graded_version_id, (SELECT MAX(v.id) FROM submission_versions v WHERE v.submission_id = s.id))
+		FROM submissions s WHERE s.id = ?
+	`, submissionID).Scan(&id)
+	return id.Int64, err
+}
+
+// resolveGradedVersion - versi yang akan dinilai: pilihan dosen (harus milik submission),
+// selain itu versi yang dinilai sebelumnya atau versi terakhir
+func resolveGradedVersion(c *gin.Context, submissionID int, requested int64) (int64, bool) {
+	if requested == 0 {
+		versionID, _ := activeVersionID(submissionID)
+		return versionID, true
+	}
+
+	var exists bool
+	config.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM submission_versions WHERE id = ? AND submission_id = ?)",
+		requested, submissionID).Scan(&exists)
+	if !exists {
+		utils.ValidationError(c, "Versi pengumpulan tidak ditemukan pada submission ini")
+		return 0, false
+	}
+	return requested, true
+}
+
+// submissionGradeQuery - simpan nilai dan feedback (versi, nilai, feedback, submission);
+// feedback kosong mempertahankan feedback sebelumnya, keterlambatan mengikuti versi yang dinilai
+const submissionGradeQuery = `
+	UPDATE submissions s
+	LEFT JOIN submission_versions v ON v.id = ?
+	SET s.grade = ?, s.feedback = COALESCE(NULLIF(?, ''), s.feedback), s.graded_version_id = v.id,
+		s.late_days = COALESCE(v.late_days, s.late_days), s.late_penalty = COALESCE(v.late_penalty, s.late_penalty),
+		s.updated_at = NOW()
+	WHERE s.id = ?
+`
+
+// GetMySubmissionVersions - Riwayat pengumpulan mahasiswa untuk satu tugas beserta bukti tiap upload
+func GetMySubmissionVersions(c *gin.Context) {
+	mahasiswaID, ok := mahasiswaIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	taskID, err := strconv.Atoi(c.Param("task_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid task ID")
+		return
+	}
+
+	versions, err := loadSubmissionVersions("task_id = ? AND student_id = ?", taskID, mahasiswaID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil riwayat pengumpulan: "+err.Error())
+		return
+	}
+
+	var gradedVersionID sql.NullInt64
+	config.DB.QueryRow("SELECT graded_version_id FROM submissions WHERE task_id = ? AND student_id = ?",
+		taskID, mahasiswaID).Scan(&gradedVersionID)
+
+	result := []gin.H{}
+	for _, v := range versions {
+		item := v.toResponse()
+		item["receipt"] = v.receiptResponse()
+		item["is_graded"] = gradedVersionID.Valid && gradedVersionID.Int64 == v.ID
+		result = append(result, item)
+	}
+
+	utils.SuccessResponse(c, result, "Riwayat pengumpulan berhasil diambil")
+}
+
+// loadDosenSubmission - validasi dosen mengampu mata kuliah dari submission :submission_id
+func loadDosenSubmission(c *gin.Context) (int, bool) {
+	submissionID, err := strconv.Atoi(c.Param("submission_id"))
+	if err != nil {
+		utils.ValidationError(c, "Invalid submission ID")
+		return 0, false
+	}
+
+	var courseID string
+	err = config.DB.QueryRow(`
+		SELECT t.course_id FROM submissions s JOIN tugas t ON s.task_id = t.id WHERE s.id = ?
+	`, submissionID).Scan(&courseID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Submission not found")
+		return 0, false
+	}
+
+	if _, ok := getDosenCourseAccess(c, courseID); !ok {
+		return 0, false
+	}
+	return submissionID, true
+}
+
+// GetSubmissionVersions - Riwayat versi satu submission untuk dosen
+func GetSubmissionVersions(c *gin.Context) {
+	submissionID, ok := loadDosenSubmission(c)
+	if !ok {
+		return
+	}
+
+	versions, err := loadSubmissionVersions("submission_id = ?", submissionID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Gagal mengambil riwayat pengumpulan: "+err.Error())
+		return
+	}
+
+	activeID, _ := activeVersionID(submissionID)
+	var latestID int64
+	if len(versions) > 0 {
+		latestID = versions[0].ID
+	}
+
+	result := []gin.H{}
+	for _, v := range versions {
+		item := v.toResponse()
+		item["is_graded"] = v.ID == activeID
+		item["is_latest"] = v.ID == latestID
+		result = append(result, item)
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"submission_id":     submissionID,
+		"graded_version_id": activeID,
+		"versions":          result,
+	}, "Riwayat pengumpulan berhasil diambil")
+}
@@ -405,9 +405,9 @@ func CreatePayment(c *gin.Context) {
 
 	// Simpan ke ukt_invoices
 	_, err = config.DB.Exec(`
-		INSERT INTO ukt_invoices (student_id, uuid, amount, status, created_at, expired_at, payment_method)
-		VALUES (?, ?, ?, 'pending', NOW(), ?, ?)
-	`, mahasiswaID, invoiceUUID, input.Nominal, expiredAt, input.Metode)
+		INSERT INTO ukt_invoices (student_id, uuid, amount, status, created_at, expired_at, payment_method, semester_id)
+		VALUES (?, ?, ?, 'pending', NOW(), ?, ?, ?)
+	`, mahasiswaID, invoiceUUID, input.Nominal, expiredAt, input.Metode, currentSemesterID())
 	
 	if err != nil {
 		fmt.Printf("Gagal menyimpan ukt_invoices: %v\n", err)
@@ -1028,9 +1028,9 @@ func CreatePaymentForChild(c *gin.Context) {
 
 	// Simpan ke ukt_invoices
 	_, err = config.DB.Exec(`
-		INSERT INTO ukt_invoices (student_id, uuid, amount, status, created_at, expired_at, payment_method)
-		VALUES (?, ?, ?, 'pending', NOW(), ?, ?)
-	`, mahasiswaID, invoiceUUID, input.Nominal, expiredAt, input.Metode)
+		INSERT INTO ukt_invoices (student_id, uuid, amount, status, created_at, expired_at, payment_method, semester_id)
+		VALUES (?, ?, ?, 'pending', NOW(), ?, ?, ?)
+	`, mahasiswaID, invoiceUUID, input.Nominal, expiredAt, input.Metode, currentSemesterID())
 	
 	if err != nil {
 		// Continue even if invoice save fails
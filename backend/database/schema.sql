@@ -239,3 +239,565 @@ ALTER TABLE tugas ADD COLUMN file_tugas VARCHAR(255) NULL AFTER description;
 ALTER TABLE tugas ADD COLUMN due_date DATETIME NULL AFTER file_tugas;
 ALTER TABLE tugas ADD COLUMN type ENUM('materi', 'tugas') DEFAULT 'tugas' AFTER due_date;
 ALTER TABLE tugas ADD COLUMN deleted_at TIMESTAMP NULL AFTER updated_at;
+
+-- Secret per sesi untuk token QR yang berotasi (mirip TOTP)
+ALTER TABLE attendance_sessions ADD COLUMN qr_secret VARCHAR(128) NULL AFTER qr_token;
+
+-- Ruangan dengan koordinat untuk geofence absensi
+CREATE TABLE ruangan (
+    kode VARCHAR(50) PRIMARY KEY,
+    nama VARCHAR(255) NOT NULL,
+    latitude DECIMAL(10, 7) NOT NULL,
+    longitude DECIMAL(10, 7) NOT NULL,
+    radius_meter INT NOT NULL DEFAULT 50,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- Geofence bisa diaktifkan per mata kuliah
+ALTER TABLE mata_kuliah ADD COLUMN geofence_enabled TINYINT(1) NOT NULL DEFAULT 0;
+
+-- Log scan absensi yang ditolak karena di luar geofence
+CREATE TABLE attendance_geofence_logs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    session_id INT NOT NULL,
+    student_id INT NOT NULL,
+    ruangan_kode VARCHAR(50) NULL,
+    latitude DECIMAL(10, 7) NULL,
+    longitude DECIMAL(10, 7) NULL,
+    accuracy_meter DECIMAL(10, 2) NULL,
+    distance_meter DECIMAL(12, 2) NULL,
+    reason VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (session_id) REFERENCES attendance_sessions(id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES mahasiswa(id) ON DELETE CASCADE,
+    INDEX idx_geofence_logs_session (session_id)
+);
+
+-- Perangkat terdaftar per mahasiswa untuk absensi QR
+CREATE TABLE mahasiswa_devices (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    mahasiswa_id INT NOT NULL,
+    device_fingerprint VARCHAR(128) NOT NULL,
+    device_name VARCHAR(255) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP NULL,
+    FOREIGN KEY (mahasiswa_id) REFERENCES mahasiswa(id) ON DELETE CASCADE,
+    UNIQUE KEY unique_mahasiswa_device (mahasiswa_id, device_fingerprint),
+    INDEX idx_device_fingerprint (device_fingerprint)
+);
+
+-- Sidik jari perangkat yang dipakai saat scan absensi
+ALTER TABLE attendance ADD COLUMN device_fingerprint VARCHAR(128) NULL;
+ALTER TABLE attendance ADD INDEX idx_attendance_session_device (session_id, device_fingerprint);
+
+-- Kebijakan absensi per mata kuliah (course_id NULL = default kampus)
+CREATE TABLE attendance_policies (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    course_id VARCHAR(100) NULL,
+    early_minutes INT NOT NULL DEFAULT 15,
+    late_after_minutes INT NOT NULL DEFAULT 15,
+    cutoff_minutes INT NOT NULL DEFAULT 60,
+    late_status ENUM('hadir', 'terlambat') NOT NULL DEFAULT 'terlambat',
+    enforce_weekday TINYINT(1) NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_policy_course (course_id)
+);
+
+INSERT INTO attendance_policies (course_id, early_minutes, late_after_minutes, cutoff_minutes, late_status, enforce_weekday)
+VALUES (NULL, 15, 15, 60, 'terlambat', 1);
+
+-- Status terlambat
+ALTER TABLE attendance MODIFY COLUMN status ENUM('hadir', 'terlambat', 'izin', 'sakit', 'alpa') DEFAULT 'hadir';
+ALTER TABLE attendance_summary MODIFY COLUMN status ENUM('hadir', 'terlambat', 'izin', 'sakit', 'alpa') DEFAULT 'hadir';
+
+-- Pengajuan izin/sakit mahasiswa beserta bukti
+CREATE TABLE attendance_excuses (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    student_id INT NOT NULL,
+    course_id VARCHAR(100) NOT NULL,
+    pertemuan_ke INT NOT NULL,
+    jenis ENUM('izin', 'sakit') NOT NULL,
+    alasan TEXT NOT NULL,
+    file_url VARCHAR(255),
+    status ENUM('pending', 'approved', 'rejected') DEFAULT 'pending',
+    reviewed_by INT NULL,
+    review_note TEXT,
+    reviewed_at DATETIME NULL,
+    applied_session_id INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_excuse_course (course_id, pertemuan_ke, status),
+    INDEX idx_excuse_student (student_id),
+    FOREIGN KEY (student_id) REFERENCES mahasiswa(id) ON DELETE CASCADE,
+    FOREIGN KEY (reviewed_by) REFERENCES dosen(id) ON DELETE SET NULL
+);
+
+-- Peringatan batas minimal kehadiran (satu kali per level per mahasiswa per mata kuliah)
+CREATE TABLE attendance_alerts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    student_id INT NOT NULL,
+    course_id VARCHAR(100) NOT NULL,
+    level ENUM('warning', 'failing') NOT NULL,
+    percent DECIMAL(5,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_alert (student_id, course_id, level),
+    FOREIGN KEY (student_id) REFERENCES mahasiswa(id) ON DELETE CASCADE
+);
+
+-- Pertemuan pengganti / jadwal ulang (tanggal, jam dan ruangan sendiri)
+CREATE TABLE pertemuan_pengganti (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    course_id VARCHAR(100) NOT NULL,
+    pertemuan_ke INT NOT NULL,
+    tanggal DATE NOT NULL,
+    jam_mulai TIME NOT NULL,
+    jam_selesai TIME NOT NULL,
+    ruangan VARCHAR(50) NULL,
+    alasan TEXT,
+    dosen_id INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_pengganti (course_id, pertemuan_ke),
+    INDEX idx_pengganti_tanggal (tanggal),
+    FOREIGN KEY (dosen_id) REFERENCES dosen(id) ON DELETE CASCADE
+);
+
+-- Absensi offline: struk scan yang disinkronkan belakangan, ditinjau dosen
+ALTER TABLE attendance ADD COLUMN offline_synced TINYINT(1) NOT NULL DEFAULT 0;
+ALTER TABLE attendance ADD COLUMN offline_captured_at DATETIME NULL;
+ALTER TABLE attendance ADD COLUMN offline_review ENUM('pending', 'accepted', 'rejected') NULL;
+ALTER TABLE attendance ADD INDEX idx_attendance_offline (session_id, offline_synced);
+
+-- Audit trail perubahan status kehadiran (scan, manual, izin, penutupan sesi, sinkronisasi offline)
+CREATE TABLE attendance_audit (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    attendance_id INT NULL,
+    session_id INT NOT NULL,
+    student_id INT NOT NULL,
+    old_status ENUM('hadir', 'terlambat', 'izin', 'sakit', 'alpa') NULL,
+    new_status ENUM('hadir', 'terlambat', 'izin', 'sakit', 'alpa') NOT NULL,
+    source ENUM('scan', 'manual', 'excuse', 'auto_close', 'offline_sync') NOT NULL,
+    changed_by INT NULL,
+    reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_audit_session (session_id),
+    INDEX idx_audit_student (student_id),
+    INDEX idx_audit_attendance (attendance_id),
+    FOREIGN KEY (session_id) REFERENCES attendance_sessions(id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES mahasiswa(id) ON DELETE CASCADE,
+    FOREIGN KEY (changed_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Bobot komponen nilai per mata kuliah (total 100) dan status kunci gradebook
+CREATE TABLE komponen_nilai (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    course_id VARCHAR(100) NOT NULL UNIQUE,
+    bobot_tugas DECIMAL(5,2) NOT NULL DEFAULT 25,
+    bobot_kuis DECIMAL(5,2) NOT NULL DEFAULT 10,
+    bobot_uts DECIMAL(5,2) NOT NULL DEFAULT 25,
+    bobot_uas DECIMAL(5,2) NOT NULL DEFAULT 30,
+    bobot_kehadiran DECIMAL(5,2) NOT NULL DEFAULT 10,
+    locked_at DATETIME NULL,
+    locked_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (locked_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Nilai kuis, UTS dan UAS per mahasiswa
+CREATE TABLE nilai_ujian (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    course_id VARCHAR(100) NOT NULL,
+    student_id INT NOT NULL,
+    jenis ENUM('kuis', 'uts', 'uas') NOT NULL,
+    nilai DECIMAL(5,2) NOT NULL,
+    keterangan VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_nilai_ujian (course_id, student_id, jenis),
+    FOREIGN KEY (student_id) REFERENCES mahasiswa(id) ON DELETE CASCADE
+);
+
+-- Nilai akhir yang disimpan saat gradebook dikunci
+CREATE TABLE nilai_akhir (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    course_id VARCHAR(100) NOT NULL,
+    student_id INT NOT NULL,
+    nilai_tugas DECIMAL(5,2) NOT NULL,
+    nilai_kuis DECIMAL(5,2) NOT NULL,
+    nilai_uts DECIMAL(5,2) NOT NULL,
+    nilai_uas DECIMAL(5,2) NOT NULL,
+    nilai_kehadiran DECIMAL(5,2) NOT NULL,
+    nilai_akhir DECIMAL(5,2) NOT NULL,
+    huruf ENUM('A', 'B', 'C', 'D', 'E') NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_nilai_akhir (course_id, student_id),
+    FOREIGN KEY (student_id) REFERENCES mahasiswa(id) ON DELETE CASCADE
+);
+
+-- Dokumen PDF yang diterbitkan (KHS, transkrip, rekap absensi, kwitansi) beserta kode verifikasi
+CREATE TABLE dokumen_terbit (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    kode_verifikasi VARCHAR(20) NOT NULL UNIQUE,
+    jenis ENUM('khs', 'transkrip', 'rekap_absensi', 'kwitansi') NOT NULL,
+    student_id INT NOT NULL,
+    referensi VARCHAR(100),
+    ringkasan VARCHAR(255),
+    checksum CHAR(64) NOT NULL,
+    issued_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_dokumen_student (student_id),
+    FOREIGN KEY (student_id) REFERENCES mahasiswa(id) ON DELETE CASCADE,
+    FOREIGN KEY (issued_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- KRS: periode pengisian, mata kuliah yang ditawarkan, dosen wali dan rencana studi mahasiswa
+CREATE TABLE periode_krs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    nama VARCHAR(100) NOT NULL,
+    tanggal_mulai DATETIME NOT NULL,
+    tanggal_selesai DATETIME NOT NULL,
+    status ENUM('open', 'closed') NOT NULL DEFAULT 'open',
+    created_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE periode_krs_matkul (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    periode_id INT NOT NULL,
+    mata_kuliah_kode VARCHAR(100) NOT NULL,
+    UNIQUE KEY unique_periode_matkul (periode_id, mata_kuliah_kode),
+    FOREIGN KEY (periode_id) REFERENCES periode_krs(id) ON DELETE CASCADE
+);
+
+CREATE TABLE dosen_wali (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    mahasiswa_id INT NOT NULL UNIQUE,
+    dosen_id INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (mahasiswa_id) REFERENCES mahasiswa(id) ON DELETE CASCADE,
+    FOREIGN KEY (dosen_id) REFERENCES dosen(id) ON DELETE CASCADE
+);
+
+CREATE TABLE krs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    periode_id INT NOT NULL,
+    mahasiswa_id INT NOT NULL,
+    status ENUM('draft', 'diajukan', 'disetujui', 'ditolak') NOT NULL DEFAULT 'draft',
+    total_sks INT NOT NULL DEFAULT 0,
+    max_sks INT NOT NULL,
+    catatan TEXT,
+    submitted_at DATETIME NULL,
+    reviewed_by INT NULL,
+    reviewed_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_krs_periode (periode_id, mahasiswa_id),
+    FOREIGN KEY (periode_id) REFERENCES periode_krs(id) ON DELETE CASCADE,
+    FOREIGN KEY (mahasiswa_id) REFERENCES mahasiswa(id) ON DELETE CASCADE,
+    FOREIGN KEY (reviewed_by) REFERENCES dosen(id) ON DELETE SET NULL
+);
+
+CREATE TABLE krs_detail (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    krs_id INT NOT NULL,
+    mata_kuliah_kode VARCHAR(100) NOT NULL,
+    sks INT NOT NULL,
+    UNIQUE KEY unique_krs_matkul (krs_id, mata_kuliah_kode),
+    FOREIGN KEY (krs_id) REFERENCES krs(id) ON DELETE CASCADE
+);
+
+-- Kalender akademik: semester, pekan UTS/UAS dan hari libur
+CREATE TABLE semester_akademik (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    nama VARCHAR(100) NOT NULL,
+    tahun_ajaran VARCHAR(20) NOT NULL,
+    jenis ENUM('ganjil', 'genap', 'pendek') NOT NULL,
+    tanggal_mulai DATE NOT NULL,
+    tanggal_selesai DATE NOT NULL,
+    uts_mulai DATE NULL,
+    uts_selesai DATE NULL,
+    uas_mulai DATE NULL,
+    uas_selesai DATE NULL,
+    is_active TINYINT(1) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+CREATE TABLE hari_libur (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    semester_id INT NOT NULL,
+    tanggal DATE NOT NULL,
+    keterangan VARCHAR(255),
+    UNIQUE KEY unique_libur (semester_id, tanggal),
+    FOREIGN KEY (semester_id) REFERENCES semester_akademik(id) ON DELETE CASCADE
+);
+
+-- Data akademik dikaitkan ke semester
+ALTER TABLE attendance_sessions ADD COLUMN semester_id INT NULL, ADD INDEX idx_sessions_semester (semester_id);
+ALTER TABLE tugas ADD COLUMN semester_id INT NULL, ADD INDEX idx_tugas_semester (semester_id);
+ALTER TABLE ukt_invoices ADD COLUMN semester_id INT NULL, ADD INDEX idx_invoices_semester (semester_id);
+ALTER TABLE mahasiswa_mata_kuliah ADD COLUMN semester_id INT NULL, ADD INDEX idx_mmk_semester (semester_id);
+ALTER TABLE periode_krs ADD COLUMN semester_id INT NULL AFTER nama, ADD INDEX idx_periode_semester (semester_id);
+
+-- Token feed kalender .ics per user
+CREATE TABLE calendar_tokens (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL UNIQUE,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    last_accessed_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Kebijakan keterlambatan tugas dan perpanjangan deadline per mahasiswa
+ALTER TABLE tugas
+    ADD COLUMN late_policy ENUM('none', 'hard_close', 'grace', 'penalty') NOT NULL DEFAULT 'none',
+    ADD COLUMN grace_minutes INT NOT NULL DEFAULT 0,
+    ADD COLUMN penalty_per_day DECIMAL(5,2) NOT NULL DEFAULT 0,
+    ADD COLUMN max_penalty DECIMAL(5,2) NOT NULL DEFAULT 100;
+
+ALTER TABLE submissions
+    ADD COLUMN submitted_at DATETIME NULL AFTER grade,
+    ADD COLUMN late_days INT NOT NULL DEFAULT 0 AFTER submitted_at,
+    ADD COLUMN late_penalty DECIMAL(5,2) NOT NULL DEFAULT 0 AFTER late_days;
+
+CREATE TABLE tugas_extensions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    task_id INT NOT NULL,
+    student_id INT NOT NULL,
+    due_date DATETIME NOT NULL,
+    reason VARCHAR(255),
+    granted_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_extension (task_id, student_id),
+    FOREIGN KEY (task_id) REFERENCES tugas(id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES mahasiswa(id) ON DELETE CASCADE
+);
+
+-- Riwayat versi pengumpulan tugas (setiap upload disimpan, tidak ditimpa)
+CREATE TABLE submission_versions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    submission_id INT NOT NULL,
+    task_id INT NOT NULL,
+    student_id INT NOT NULL,
+    version_no INT NOT NULL,
+    file_url VARCHAR(255),
+    answer_text TEXT,
+    content_hash CHAR(64) NULL,
+    receipt_code VARCHAR(20) NULL UNIQUE,
+    submitted_at DATETIME NOT NULL,
+    late_days INT NOT NULL DEFAULT 0,
+    late_penalty DECIMAL(5,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_version (submission_id, version_no),
+    INDEX idx_versions_task_student (task_id, student_id),
+    FOREIGN KEY (submission_id) REFERENCES submissions(id) ON DELETE CASCADE
+);
+
+ALTER TABLE submissions ADD COLUMN graded_version_id INT NULL AFTER grade;
+ALTER TABLE dokumen_terbit MODIFY jenis ENUM('khs', 'transkrip', 'rekap_absensi', 'kwitansi', 'bukti_tugas') NOT NULL;
+
+-- Submission lama menjadi versi 1 (tanpa hash/bukti karena isi aslinya tidak tercatat)
+INSERT INTO submission_versions (submission_id, task_id, student_id, version_no, file_url, answer_text, submitted_at, late_days, late_penalty)
+SELECT id, task_id, student_id, 1, file_url, answer_text, COALESCE(submitted_at, created_at), late_days, late_penalty
+FROM submissions;
+
+-- Pemeriksaan kemiripan submission per tugas (shingle + MinHash)
+CREATE TABLE similarity_runs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    task_id INT NOT NULL,
+    status ENUM('running', 'done', 'failed') NOT NULL DEFAULT 'running',
+    submissions_checked INT NOT NULL DEFAULT 0,
+    pairs_flagged INT NOT NULL DEFAULT 0,
+    notes TEXT,
+    requested_by INT NULL,
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME NULL,
+    INDEX idx_similarity_task (task_id),
+    FOREIGN KEY (task_id) REFERENCES tugas(id) ON DELETE CASCADE
+);
+
+CREATE TABLE similarity_results (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    run_id INT NOT NULL,
+    task_id INT NOT NULL,
+    submission_a INT NOT NULL,
+    submission_b INT NOT NULL,
+    score DECIMAL(5,4) NOT NULL,
+    jaccard DECIMAL(5,4) NOT NULL,
+    passages TEXT,
+    INDEX idx_similarity_run (run_id),
+    FOREIGN KEY (run_id) REFERENCES similarity_runs(id) ON DELETE CASCADE,
+    FOREIGN KEY (submission_a) REFERENCES submissions(id) ON DELETE CASCADE,
+    FOREIGN KEY (submission_b) REFERENCES submissions(id) ON DELETE CASCADE
+);
+
+-- Rubrik penilaian tugas dan feedback tertulis
+CREATE TABLE rubrics (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    dosen_id INT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (dosen_id) REFERENCES dosen(id) ON DELETE CASCADE
+);
+
+CREATE TABLE rubric_criteria (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    rubric_id INT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    sort_order INT NOT NULL DEFAULT 0,
+    FOREIGN KEY (rubric_id) REFERENCES rubrics(id) ON DELETE CASCADE
+);
+
+CREATE TABLE rubric_levels (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    criterion_id INT NOT NULL,
+    label VARCHAR(100) NOT NULL,
+    description TEXT,
+    points DECIMAL(6,2) NOT NULL,
+    sort_order INT NOT NULL DEFAULT 0,
+    FOREIGN KEY (criterion_id) REFERENCES rubric_criteria(id) ON DELETE CASCADE
+);
+
+CREATE TABLE submission_rubric_scores (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    submission_id INT NOT NULL,
+    criterion_id INT NOT NULL,
+    level_id INT NULL,
+    points DECIMAL(6,2) NOT NULL,
+    comment TEXT,
+    UNIQUE KEY unique_submission_criterion (submission_id, criterion_id),
+    FOREIGN KEY (submission_id) REFERENCES submissions(id) ON DELETE CASCADE,
+    FOREIGN KEY (criterion_id) REFERENCES rubric_criteria(id),
+    FOREIGN KEY (level_id) REFERENCES rubric_levels(id) ON DELETE SET NULL
+);
+
+ALTER TABLE tugas ADD COLUMN rubric_id INT NULL,
+    ADD CONSTRAINT fk_tugas_rubric FOREIGN KEY (rubric_id) REFERENCES rubrics(id) ON DELETE SET NULL;
+ALTER TABLE submissions ADD COLUMN feedback TEXT NULL AFTER grade;
+
+-- Kuis/ujian online per pertemuan
+CREATE TABLE quizzes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    course_id VARCHAR(10) NOT NULL,
+    pertemuan INT NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    kategori ENUM('kuis', 'uts', 'uas') NOT NULL DEFAULT 'kuis',
+    time_limit_minutes INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 1,
+    shuffle_questions BOOLEAN NOT NULL DEFAULT FALSE,
+    shuffle_options BOOLEAN NOT NULL DEFAULT FALSE,
+    opens_at DATETIME NULL,
+    closes_at DATETIME NULL,
+    scoring ENUM('highest', 'latest') NOT NULL DEFAULT 'highest',
+    is_published BOOLEAN NOT NULL DEFAULT FALSE,
+    semester_id INT NULL,
+    created_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL,
+    INDEX idx_quiz_pertemuan (course_id, pertemuan),
+    FOREIGN KEY (course_id) REFERENCES mata_kuliah(kode) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES dosen(id)
+);
+
+CREATE TABLE quiz_questions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    quiz_id INT NOT NULL,
+    type ENUM('multiple_choice', 'true_false', 'short_answer', 'essay') NOT NULL,
+    question TEXT NOT NULL,
+    points DECIMAL(6,2) NOT NULL,
+    accepted_answers TEXT NULL,
+    sort_order INT NOT NULL DEFAULT 0,
+    FOREIGN KEY (quiz_id) REFERENCES quizzes(id) ON DELETE CASCADE
+);
+
+CREATE TABLE quiz_options (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    question_id INT NOT NULL,
+    option_text TEXT NOT NULL,
+    is_correct BOOLEAN NOT NULL DEFAULT FALSE,
+    sort_order INT NOT NULL DEFAULT 0,
+    FOREIGN KEY (question_id) REFERENCES quiz_questions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE quiz_attempts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    quiz_id INT NOT NULL,
+    student_id INT NOT NULL,
+    attempt_no INT NOT NULL,
+    started_at DATETIME NOT NULL,
+    deadline_at DATETIME NULL,
+    submitted_at DATETIME NULL,
+    status ENUM('in_progress', 'submitted', 'graded') NOT NULL DEFAULT 'in_progress',
+    score DECIMAL(8,2) NULL,
+    max_score DECIMAL(8,2) NULL,
+    percentage DECIMAL(5,2) NULL,
+    question_order TEXT NOT NULL,
+    option_order TEXT NOT NULL,
+    UNIQUE KEY unique_quiz_attempt (quiz_id, student_id, attempt_no),
+    FOREIGN KEY (quiz_id) REFERENCES quizzes(id) ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES mahasiswa(id) ON DELETE CASCADE
+);
+
+CREATE TABLE quiz_answers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    attempt_id INT NOT NULL,
+    question_id INT NOT NULL,
+    option_id INT NULL,
+    answer_text TEXT,
+    is_correct BOOLEAN NULL,
+    points_awarded DECIMAL(6,2) NULL,
+    feedback TEXT,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY unique_attempt_question (attempt_id, question_id),
+    FOREIGN KEY (attempt_id) REFERENCES quiz_attempts(id) ON DELETE CASCADE,
+    FOREIGN KEY (question_id) REFERENCES quiz_questions(id) ON DELETE CASCADE,
+    FOREIGN KEY (option_id) REFERENCES quiz_options(id) ON DELETE SET NULL
+);
+
+-- Nilai akhir per semester: kunci ulang memperbarui baris, buka kunci menandai revisi tanpa menghapus
+ALTER TABLE nilai_akhir ADD COLUMN semester_id INT NULL AFTER student_id,
+    ADD COLUMN status ENUM('final', 'revisi') NOT NULL DEFAULT 'final' AFTER huruf,
+    ADD COLUMN updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    ADD INDEX idx_nilai_akhir_semester (semester_id);
+ALTER TABLE nilai_ujian ADD COLUMN semester_id INT NULL AFTER keterangan, ADD INDEX idx_nilai_ujian_semester (semester_id);
+
+-- Satu pemeriksaan kemiripan berjalan per tugas (running_task_id NULL setelah selesai)
+ALTER TABLE similarity_runs ADD COLUMN running_task_id INT NULL AFTER task_id,
+    ADD UNIQUE KEY unique_similarity_running (running_task_id);
+
+-- Backfill alpa yang gagal di tengah jalan diulang worker (alpa_backfilled_at NULL setelah ditutup)
+ALTER TABLE attendance_sessions ADD COLUMN closed_at DATETIME NULL AFTER expires_at,
+    ADD COLUMN alpa_backfilled_at DATETIME NULL AFTER closed_at;
+UPDATE attendance_sessions SET closed_at = expires_at, alpa_backfilled_at = expires_at WHERE status = 'closed';
+
+-- Pertemuan pengganti per semester: jadwal ulang semester lalu tidak menimpa semester berikutnya
+ALTER TABLE pertemuan_pengganti ADD COLUMN semester_id INT NULL AFTER course_id,
+    DROP INDEX unique_pengganti,
+    ADD UNIQUE KEY unique_pengganti (course_id, pertemuan_ke, semester_id),
+    ADD INDEX idx_pengganti_semester (semester_id);
+UPDATE pertemuan_pengganti pp
+JOIN semester_akademik s ON pp.tanggal BETWEEN s.tanggal_mulai AND s.tanggal_selesai
+SET pp.semester_id = s.id
+WHERE pp.semester_id IS NULL;
+
+-- Nilai akhir dan kunci gradebook per semester: mengulang mata kuliah tidak menimpa nilai semester lalu
+ALTER TABLE nilai_akhir DROP INDEX unique_nilai_akhir,
+    ADD UNIQUE KEY unique_nilai_akhir (course_id, student_id, semester_id);
+ALTER TABLE komponen_nilai ADD COLUMN locked_semester_id INT NULL AFTER locked_by;
+UPDATE komponen_nilai SET locked_semester_id = (SELECT id FROM semester_akademik WHERE is_active = 1 LIMIT 1)
+WHERE locked_at IS NOT NULL;
+
+-- Pengambilan mata kuliah per semester agar mata kuliah bisa diulang
+ALTER TABLE mahasiswa_mata_kuliah DROP INDEX unique_enrollment,
+    ADD UNIQUE KEY unique_enrollment (mahasiswa_id, mata_kuliah_kode, semester_id);
@@ -32,7 +32,7 @@ type Client struct {
 
 // WebSocketHub manages WebSocket connections
 type WebSocketHub struct {
-	Clients      map[int]*Client // Map user ID to client
+	Clients      map[int]map[*Client]bool // Map user ID to its connections (one per device)
 	Register     chan *Client
 	Unregister   chan *Client
 	Broadcast    chan models.WebsocketMessage
@@ -43,7 +43,7 @@ type WebSocketHub struct {
 // NewWebSocketHub creates a new WebSocket hub
 func NewWebSocketHub(db *gorm.DB) *WebSocketHub {
 	return &WebSocketHub{
-		Clients:    make(map[int]*Client),
+		Clients:    make(map[int]map[*Client]bool),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
 		Broadcast:  make(chan models.WebsocketMessage, 256),
@@ -57,7 +57,10 @@ func (h *WebSocketHub) Run() {
 		select {
 		case client := <-h.Register:
 			h.mu.Lock()
-			h.Clients[client.UserID] = client
+			if h.Clients[client.UserID] == nil {
+				h.Clients[client.UserID] = make(map[*Client]bool)
+			}
+			h.Clients[client.UserID][client] = true
 			h.mu.Unlock()
 			log.Printf("Client registered: %d (total: %d)", client.UserID, len(h.Clients))
 
@@ -73,37 +76,65 @@ func (h *WebSocketHub) Run() {
 			client.SendMessage(msg)
 
 		case client := <-h.Unregister:
-			h.mu.Lock()
-			if c, ok := h.Clients[client.UserID]; ok {
-				close(c.Send)
-				delete(h.Clients, client.UserID)
-			}
-			h.mu.Unlock()
+			h.removeClient(client)
 			log.Printf("Client unregistered: %d (total: %d)", client.UserID, len(h.Clients))
 
 		case message := <-h.Broadcast:
+			var stale []*Client
 			h.mu.RLock()
-			for _, client := range h.Clients {
-				select {
-				case client.Send <- h.marshalMessage(message):
-				default:
-					close(client.Send)
-					delete(h.Clients, client.UserID)
+			for _, conns := range h.Clients {
+				for client := range conns {
+					select {
+					case client.Send <- h.marshalMessage(message):
+					default:
+						stale = append(stale, client)
+					}
 				}
 			}
 			h.mu.RUnlock()
+
+			for _, client := range stale {
+				h.removeClient(client)
+			}
 		}
 	}
 }
 
-// BroadcastToUser sends a message to a specific user
+// removeClient closes and forgets a single connection; safe to call more than once
+func (h *WebSocketHub) removeClient(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	conns, ok := h.Clients[client.UserID]
+	if !ok || !conns[client] {
+		return
+	}
+
+	close(client.Send)
+	delete(conns, client)
+	if len(conns) == 0 {
+		delete(h.Clients, client.UserID)
+	}
+}
+
+// BroadcastToUser sends a message to every connection of a specific user
 func (h *WebSocketHub) BroadcastToUser(userID int, message models.WebsocketMessage) {
+	data := h.marshalMessage(message)
+
+	// Send while holding the read lock so removeClient cannot close a channel mid-send
+	var stale []*Client
 	h.mu.RLock()
-	client, ok := h.Clients[userID]
+	for client := range h.Clients[userID] {
+		select {
+		case client.Send <- data:
+		default:
+			stale = append(stale, client)
+		}
+	}
 	h.mu.RUnlock()
 
-	if ok {
-		client.SendMessage(message)
+	for _, client := range stale {
+		h.removeClient(client)
 	}
 }
 
@@ -304,8 +335,7 @@ func (c *Client) SendMessage(msg models.WebsocketMessage) {
 	select {
 	case c.Send <- data:
 	default:
-		close(c.Send)
-		delete(c.Hub.Clients, c.UserID)
+		c.Hub.removeClient(c)
 	}
 }
 
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"nf-student-hub-backend/config"
+	"nf-student-hub-backend/controllers"
 	"nf-student-hub-backend/routes"
 
 	"github.com/fatih/color"
@@ -31,6 +32,7 @@ func main() {
 	os.MkdirAll("uploads/tugas", 0755)
 	os.MkdirAll("uploads/tugasdosen", 0755)
 	os.MkdirAll("uploads/profile", 0755)
+	os.MkdirAll("uploads/izin", 0755)
 
 	r := gin.Default()
 
@@ -50,6 +52,9 @@ func main() {
 
 	routes.SetupRoutes(r, config.GormDB)
 
+	// Tutup sesi absensi kadaluarsa dan catat alpa otomatis
+	controllers.StartAttendanceExpiryWorker()
+
 	nama := os.Getenv("NAMA")
 	if nama == "" {
 		nama = "c4ndalena server"
@@ -13,6 +13,7 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	// Initialize WebSocket hub
 	wsHub := handlers.NewWebSocketHub(db)
 	go wsHub.Run()
+	controllers.SetAttendanceHub(wsHub)
 
 	// Initialize chat controller with hub
 	chatController := controllers.NewChatController(db, wsHub)
@@ -24,6 +25,12 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	// Webhook Pakasir.com (tanpa auth)
 	r.POST("/api/webhook/pakasir", controllers.PakasirWebhook)
 
+	// Verifikasi keaslian dokumen PDF (tanpa auth)
+	r.GET("/api/dokumen/verify/:kode", controllers.VerifyDokumen)
+
+	// Feed kalender .ics pribadi (dilindungi token di URL)
+	r.GET("/api/kalender/ics/:token", controllers.GetCalendarFeed)
+
 	// WebSocket route for real-time chat
 	r.GET("/ws/chat", middlewares.WebSocketAuthMiddleware(), func(c *gin.Context) {
 		wsHub.HandleWebSocket(c)
@@ -40,6 +47,12 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	api.GET("/profile/public/:role/:username", controllers.GetPublicProfile)
 	api.GET("/profile/public/:role/:username/posts", controllers.GetUserPosts)
 
+	// === KALENDER AKADEMIK ===
+	api.GET("/semester/aktif", controllers.GetActiveSemester)
+	api.GET("/kalender/pertemuan", controllers.GetCalendarPertemuan)
+	api.GET("/kalender/feed", middlewares.RoleMiddleware("mahasiswa", "dosen"), controllers.GetCalendarFeedURL)
+	api.POST("/kalender/feed/reset", middlewares.RoleMiddleware("mahasiswa", "dosen"), controllers.ResetCalendarFeedToken)
+
 	// === UKT ROUTES (Shared for mahasiswa & orangtua) ===
 	ukt := api.Group("/ukt")
 	{
@@ -55,6 +68,16 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		ukt.POST("/cancel/:uuid", controllers.CancelPayment)
 	}
 
+	// === DOKUMEN PDF (Shared for mahasiswa & orangtua) ===
+	dokumen := api.Group("/dokumen")
+	dokumen.Use(middlewares.RoleMiddleware("mahasiswa", "orangtua"))
+	{
+		dokumen.GET("/khs", controllers.GetKHSDocument)
+		dokumen.GET("/transkrip", controllers.GetTranskripDocument)
+		dokumen.GET("/rekap-absensi", controllers.GetRekapAbsensiDocument)
+		dokumen.GET("/kwitansi/:payment_id", controllers.GetKwitansiDocument)
+	}
+
 	// === ORANGTUA SPECIFIC ROUTES ===
 	ortu := api.Group("/ortu")
 	ortu.Use(middlewares.RoleMiddleware("orangtua"))
@@ -66,6 +89,7 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		ortu.GET("/anak/absensi/hari-ini", controllers.GetChildAttendanceToday)
 		ortu.GET("/anak/profile", controllers.GetChildProfile)
 		ortu.GET("/anak/akademik", controllers.GetChildAcademicInfo)
+		ortu.GET("/anak/izin", controllers.GetChildExcuses)
 
 		// UKT khusus orangtua
 		ortu.POST("/ukt/bayar", controllers.CreatePaymentForChild)
@@ -108,6 +132,18 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		mahasiswa.PUT("/profile", controllers.UpdateMahasiswaProfile)
 		mahasiswa.GET("/absensi/summary", controllers.GetAttendanceSummary)
 		mahasiswa.POST("/absensi/scan", controllers.ScanAttendance)
+		mahasiswa.GET("/absensi/offline-key", controllers.GetOfflineReceiptKey)
+		mahasiswa.POST("/absensi/sync", controllers.SyncOfflineAttendance)
+		mahasiswa.GET("/absensi/audit", controllers.GetMyAttendanceAudit)
+		mahasiswa.GET("/khs", controllers.GetMahasiswaKHS)
+		mahasiswa.GET("/transkrip", controllers.GetMahasiswaTranskrip)
+
+		// KRS (rencana studi)
+		mahasiswa.GET("/krs", controllers.GetMyKRS)
+		mahasiswa.PUT("/krs", controllers.SaveMyKRS)
+		mahasiswa.POST("/krs/submit", controllers.SubmitMyKRS)
+
+		mahasiswa.GET("/devices", controllers.GetMyDevices)
 		mahasiswa.GET("/jadwal/hari-ini", controllers.GetMahasiswaJadwalHariIni)
 		mahasiswa.GET("/ukt", controllers.GetUKTInvoices)
 		mahasiswa.POST("/ukt/pay", controllers.CreateUKTPayment)
@@ -115,6 +151,14 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		mahasiswa.GET("/matkul/:course_id/pertemuan/:pertemuan", controllers.GetPertemuanDetail)
 		mahasiswa.POST("/tugas/submit", controllers.SubmitTugas)
 		mahasiswa.GET("/tugas/:task_id/status", controllers.GetSubmissionStatus)
+		mahasiswa.GET("/tugas/:task_id/versions", controllers.GetMySubmissionVersions)
+
+		// Kuis/ujian online
+		mahasiswa.GET("/quiz/:quiz_id", controllers.GetMahasiswaQuiz)
+		mahasiswa.POST("/quiz/:quiz_id/start", controllers.StartQuiz)
+		mahasiswa.PUT("/quiz/:quiz_id/answers", controllers.SaveQuizAnswers)
+		mahasiswa.POST("/quiz/:quiz_id/submit", controllers.SubmitQuiz)
+		mahasiswa.GET("/quiz/:quiz_id/result", controllers.GetQuizResult)
 
 		// ROUTE UNTUK SEMUA MATA KULIAH TANPA FILTER
 		mahasiswa.GET("/courses", controllers.GetMahasiswaCourses)
@@ -133,6 +177,10 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 
 		// ROUTE UNTUK ABSENSI PER COURSE DENGAN PERTEMUAN
 		mahasiswa.GET("/absensi/pertemuan", controllers.GetAttendanceByCoursePertemuan)
+
+		// Pengajuan izin/sakit
+		mahasiswa.POST("/izin", controllers.SubmitExcuse)
+		mahasiswa.GET("/izin", controllers.GetMyExcuses)
 	}
 
 	// === DOSEN ROUTES ===
@@ -150,19 +198,94 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		dosen.POST("/absensi/update-status", controllers.UpdateAttendanceStatus)
 		dosen.POST("/absensi/close", controllers.CloseAttendanceSession)
 		dosen.GET("/absensi/qr/:token", controllers.GetQRCode)
+		dosen.GET("/absensi/qr/:token/image", controllers.GetQRCodeImage)
 		dosen.GET("/absensi/summary", controllers.GetAttendanceByPertemuan)
 
 		// ROUTE BARU UNTUK RIWAYAT PERTEMUAN DOSEN
 		dosen.GET("/absensi/riwayat-pertemuan", controllers.GetRiwayatPertemuanDosen)
 
+		// Export rekap absensi (csv/xlsx)
+		dosen.GET("/absensi/export", controllers.ExportDosenAttendance)
+
 		// ROUTE BARU UNTUK REALTIME ATTENDANCE
 		dosen.GET("/absensi/realtime/:session_id", controllers.GetRealtimeAttendance)
 
+		// Laporan scan mencurigakan (satu perangkat untuk beberapa mahasiswa)
+		dosen.GET("/absensi/:session_id/suspicious", controllers.GetSuspiciousScans)
+		dosen.POST("/absensi/:session_id/suspicious/downgrade", controllers.DowngradeSuspiciousScans)
+		dosen.GET("/absensi/:session_id/offline", controllers.GetOfflineSyncedAttendance)
+		dosen.POST("/absensi/:session_id/offline/review", controllers.ReviewOfflineAttendance)
+		dosen.GET("/absensi/audit", controllers.GetDosenAttendanceAudit)
+
+		// Geofence absensi
+		dosen.PUT("/matkul/:course_id/geofence", controllers.SetCourseGeofence)
+		dosen.GET("/absensi/geofence-logs", controllers.GetGeofenceRejections)
+
+		// Kebijakan absensi mata kuliah
+		dosen.GET("/matkul/:course_id/policy", controllers.GetCourseAttendancePolicy)
+		dosen.PUT("/matkul/:course_id/policy", controllers.UpdateCourseAttendancePolicy)
+		dosen.DELETE("/matkul/:course_id/policy", controllers.ResetCourseAttendancePolicy)
+
+		// Pertemuan pengganti (jadwal ulang)
+		dosen.GET("/matkul/:course_id/pengganti", controllers.GetPertemuanPengganti)
+		dosen.POST("/matkul/:course_id/pengganti", controllers.CreatePertemuanPengganti)
+		dosen.PUT("/pengganti/:id", controllers.UpdatePertemuanPengganti)
+		dosen.DELETE("/pengganti/:id", controllers.DeletePertemuanPengganti)
+
+		// Gradebook: bobot komponen, nilai ujian dan nilai akhir
+		dosen.GET("/matkul/:course_id/komponen-nilai", controllers.GetGradeComponents)
+		dosen.PUT("/matkul/:course_id/komponen-nilai", controllers.UpdateGradeComponents)
+		dosen.PUT("/matkul/:course_id/nilai-ujian", controllers.SaveExamScores)
+		dosen.GET("/matkul/:course_id/gradebook", controllers.GetGradebook)
+		dosen.POST("/matkul/:course_id/gradebook/lock", controllers.LockGradebook)
+
+		// Perwalian: persetujuan KRS mahasiswa
+		dosen.GET("/krs", controllers.GetAdviseeKRS)
+		dosen.GET("/krs/:krs_id", controllers.GetAdviseeKRSDetail)
+		dosen.POST("/krs/:krs_id/review", controllers.ReviewAdviseeKRS)
+
+		// Antrian pengajuan izin/sakit mahasiswa
+		dosen.GET("/izin", controllers.GetExcuseQueue)
+		dosen.POST("/izin/:id/review", controllers.ReviewExcuse)
+
 		// Tugas & Materi Management
 		dosen.POST("/tugas", controllers.CreateTugas)
 		dosen.GET("/tugas/:course_id/submissions", controllers.GetTugasSubmissions)
 		dosen.PUT("/tugas/:submission_id/grade", controllers.GradeSubmission)
 		dosen.DELETE("/submissions/:submission_id", controllers.DeleteSubmission)
+		dosen.GET("/submissions/:submission_id/versions", controllers.GetSubmissionVersions)
+		dosen.GET("/submissions/:submission_id/rubric", controllers.GetSubmissionRubricGrade)
+		dosen.POST("/submissions/:submission_id/rubric", controllers.GradeSubmissionWithRubric)
+
+		// Kebijakan keterlambatan & perpanjangan deadline tugas
+		dosen.PUT("/matkul/:course_id/tugas/:task_id/late-policy", controllers.UpdateTugasLatePolicy)
+		dosen.GET("/matkul/:course_id/tugas/:task_id/extensions", controllers.GetTugasExtensions)
+		dosen.POST("/matkul/:course_id/tugas/:task_id/extensions", controllers.GrantTugasExtension)
+		dosen.DELETE("/matkul/:course_id/tugas/:task_id/extensions/:student_id", controllers.RevokeTugasExtension)
+
+		// Pemeriksaan kemiripan jawaban antar mahasiswa
+		dosen.POST("/matkul/:course_id/tugas/:task_id/similarity", controllers.StartSimilarityCheck)
+		dosen.GET("/matkul/:course_id/tugas/:task_id/similarity", controllers.GetSimilarityReport)
+
+		// Rubrik penilaian tugas
+		dosen.GET("/rubrics", controllers.GetRubrics)
+		dosen.POST("/rubrics", controllers.CreateRubric)
+		dosen.GET("/rubrics/:rubric_id", controllers.GetRubric)
+		dosen.PUT("/rubrics/:rubric_id", controllers.UpdateRubric)
+		dosen.DELETE("/rubrics/:rubric_id", controllers.DeleteRubric)
+		dosen.GET("/matkul/:course_id/tugas/:task_id/rubric", controllers.GetTugasRubric)
+		dosen.PUT("/matkul/:course_id/tugas/:task_id/rubric", controllers.SetTugasRubric)
+
+		// Kuis/ujian online per pertemuan
+		dosen.POST("/quiz", controllers.CreateQuiz)
+		dosen.GET("/matkul/:course_id/quiz", controllers.GetCourseQuizzes)
+		dosen.GET("/quiz/:quiz_id", controllers.GetQuizDetail)
+		dosen.PUT("/quiz/:quiz_id", controllers.UpdateQuiz)
+		dosen.DELETE("/quiz/:quiz_id", controllers.DeleteQuiz)
+		dosen.POST("/quiz/:quiz_id/publish", controllers.PublishQuiz)
+		dosen.GET("/quiz/:quiz_id/attempts", controllers.GetQuizAttempts)
+		dosen.GET("/quiz/:quiz_id/attempts/:attempt_id", controllers.GetQuizAttemptDetail)
+		dosen.PUT("/quiz/:quiz_id/attempts/:attempt_id/grade", controllers.GradeQuizAttempt)
 
 		// Materi
 		dosen.POST("/materi/upload", controllers.UploadMateri)
@@ -190,6 +313,45 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		admin.GET("/ukt/mahasiswa", controllers.GetAllMahasiswaUKTStatus)
 		admin.GET("/ukt/riwayat/:mahasiswa_id", controllers.GetRiwayatPembayaranByMahasiswaID)
 		admin.POST("/ukt/reminder/:mahasiswa_id", controllers.SendReminder)
+
+		// Ruangan & geofence absensi
+		admin.GET("/ruangan", controllers.GetRuangan)
+		admin.POST("/ruangan", controllers.SaveRuangan)
+
+		// Kebijakan absensi default kampus
+		admin.GET("/absensi/policy", controllers.GetDefaultAttendancePolicy)
+		admin.PUT("/absensi/policy", controllers.UpdateDefaultAttendancePolicy)
+
+		// Export rekap absensi kampus untuk laporan akreditasi
+		admin.GET("/absensi/export", controllers.ExportCampusAttendance)
+
+		// Audit trail perubahan status absensi
+		admin.GET("/absensi/audit", controllers.GetAdminAttendanceAudit)
+
+		// Buka kunci nilai mata kuliah
+		admin.POST("/nilai/:course_id/unlock", controllers.UnlockGradebook)
+
+		// KRS: periode pengisian dan dosen wali
+		admin.GET("/krs/periode", controllers.GetKRSPeriodes)
+		admin.POST("/krs/periode", controllers.CreateKRSPeriode)
+		admin.POST("/krs/periode/:id/close", controllers.CloseKRSPeriode)
+		admin.PUT("/dosen-wali", controllers.AssignDosenWali)
+
+		// Jadwal mata kuliah dan bentrok ruangan/dosen/mahasiswa
+		admin.PUT("/matkul/:course_id/jadwal", controllers.UpdateCourseSchedule)
+		admin.GET("/jadwal/konflik", controllers.GetScheduleConflicts)
+
+		// Semester akademik, pekan ujian dan hari libur
+		admin.GET("/semester", controllers.GetSemesters)
+		admin.POST("/semester", controllers.CreateSemester)
+		admin.PUT("/semester/:id", controllers.UpdateSemester)
+		admin.POST("/semester/:id/activate", controllers.ActivateSemester)
+		admin.POST("/semester/:id/libur", controllers.AddHariLibur)
+		admin.DELETE("/semester/:id/libur/:tanggal", controllers.DeleteHariLibur)
+
+		// Perangkat absensi mahasiswa
+		admin.GET("/mahasiswa/:mahasiswa_id/devices", controllers.GetMahasiswaDevices)
+		admin.DELETE("/mahasiswa/:mahasiswa_id/devices/:device_id", controllers.ResetMahasiswaDevice)
 	}
 
 	// ==================== CHAT ROUTES ====================
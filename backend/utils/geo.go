@@ -0,0 +1,19 @@
+package utils
+
+import "math"
+
+// earthRadiusMeter - radius rata-rata bumi dalam meter
+const earthRadiusMeter = 6371000.0
+
+// HaversineMeter menghitung jarak dua titik koordinat (derajat) dalam meter
+func HaversineMeter(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return earthRadiusMeter * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ICalTimezone - zona waktu kampus; jam kuliah dan deadline disimpan sebagai jam lokal WIB
+const ICalTimezone = "Asia/Jakarta"
+
+// ICalContentType - MIME type file .ics
+const ICalContentType = "text/calendar; charset=utf-8"
+
+// ICalEvent - satu VEVENT
+type ICalEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	Local       bool        // true: Start/End adalah jam lokal WIB (TZID), false: waktu absolut (UTC)
+	RRule       string      // contoh "FREQ=WEEKLY;UNTIL=20261220T165959Z"
+	ExDates     []time.Time // tanggal yang dikecualikan dari RRule (jam sama dengan Start)
+	Alarm       time.Duration
+}
+
+// BuildICal menyusun kalender VCALENDAR lengkap (RFC 5545)
+func BuildICal(name string, events []ICalEvent) []byte {
+	var b bytes.Buffer
+	line := func(s string) { writeICalLine(&b, s) }
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//NF StudentHub//Jadwal//ID")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + icalEscape(name))
+	line("X-WR-TIMEZONE:" + ICalTimezone)
+	line("BEGIN:VTIMEZONE")
+	line("TZID:" + ICalTimezone)
+	line("BEGIN:STANDARD")
+	line("DTSTART:19700101T000000")
+	line("TZOFFSETFROM:+0700")
+	line("TZOFFSETTO:+0700")
+	line("TZNAME:WIB")
+	line("END:STANDARD")
+	line("END:VTIMEZONE")
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID)
+		line("DTSTAMP:" + stamp)
+		line(icalTime("DTSTART", e.Start, e.Local))
+		line(icalTime("DTEND", e.End, e.Local))
+		if e.RRule != "" {
+			line("RRULE:" + e.RRule)
+		}
+		for _, ex := range e.ExDates {
+			line(icalTime("EXDATE", ex, e.Local))
+		}
+		line("SUMMARY:" + icalEscape(e.Summary))
+		if e.Location != "" {
+			line("LOCATION:" + icalEscape(e.Location))
+		}
+		if e.Description != "" {
+			line("DESCRIPTION:" + icalEscape(e.Description))
+		}
+		if e.Alarm > 0 {
+			line("BEGIN:VALARM")
+			line("ACTION:DISPLAY")
+			line("DESCRIPTION:" + icalEscape(e.Summary))
+			line(fmt.Sprintf("TRIGGER:-PT%dM", int(e.Alarm.Minutes())))
+			line("END:VALARM")
+		}
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+	return b.Bytes()
+}
+
+func icalTime(prop string, t time.Time, local bool) string {
+	if local {
+		return prop + ";TZID=" + ICalTimezone + ":" + t.Format("20060102T150405")
+	}
+	return prop + ":" + t.UTC().Format("20060102T150405Z")
+}
+
+// icalEscape meng-escape teks sesuai RFC 5545 (backslash, koma, titik koma, baris baru)
+func icalEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "").Replace(s)
+}
+
+// writeICalLine menulis satu baris dengan pelipatan 75 oktet dan akhiran CRLF.
+// Baris lanjutan diawali spasi sehingga isinya paling banyak 74 oktet.
+func writeICalLine(b *bytes.Buffer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		// Jangan memotong di tengah karakter UTF-8
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
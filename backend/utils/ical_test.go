@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// unfoldICal - Gabungkan kembali baris yang dilipat (RFC 5545 bagian 3.1)
+func unfoldICal(s string) string {
+	return strings.ReplaceAll(s, "\r\n ", "")
+}
+
+func TestWriteICalLineFolding(t *testing.T) {
+	tests := []string{
+		"SUMMARY:pendek",
+		"DESCRIPTION:" + strings.Repeat("a", 63),
+		"DESCRIPTION:" + strings.Repeat("a", 64),
+		"DESCRIPTION:" + strings.Repeat("abcdefghij", 40),
+		"LOCATION:" + strings.Repeat("Gedung Ç ", 30),
+		"SUMMARY:" + strings.Repeat("日本語", 50),
+		"DESCRIPTION:" + strings.Repeat("😀", 40),
+	}
+
+	for _, line := range tests {
+		var b bytes.Buffer
+		writeICalLine(&b, line)
+		out := b.String()
+
+		if !strings.HasSuffix(out, "\r\n") {
+			t.Fatalf("baris tidak diakhiri CRLF: %q", out)
+		}
+		physical := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+		for i, p := range physical {
+			if len(p) > 75 {
+				t.Errorf("baris fisik %d panjang %d oktet (maks 75)", i, len(p))
+			}
+			if i > 0 && !strings.HasPrefix(p, " ") {
+				t.Errorf("baris lanjutan %d tidak diawali spasi", i)
+			}
+			if !utf8.ValidString(strings.TrimPrefix(p, " ")) {
+				t.Errorf("baris fisik %d memotong karakter UTF-8: %q", i, p)
+			}
+		}
+		if got := unfoldICal(strings.TrimSuffix(out, "\r\n")); got != line {
+			t.Errorf("unfold = %q, seharusnya %q", got, line)
+		}
+		if len(line) <= 75 && len(physical) != 1 {
+			t.Errorf("baris %d oktet seharusnya tidak dilipat", len(line))
+		}
+	}
+}
+
+func TestICalEscape(t *testing.T) {
+	tests := map[string]string{
+		`Kuliah; Praktikum, Lab`: `Kuliah\; Praktikum\, Lab`,
+		`C:\kelas`:               `C:\\kelas`,
+		"baris1\r\nbaris2\nb3\r": `baris1\nbaris2\nb3`,
+	}
+	for in, want := range tests {
+		if got := icalEscape(in); got != want {
+			t.Errorf("icalEscape(%q) = %q, seharusnya %q", in, got, want)
+		}
+	}
+}
+
+func TestBuildICal(t *testing.T) {
+	start := time.Date(2026, 9, 7, 8, 0, 0, 0, time.UTC)
+	deadline := time.Date(2026, 9, 20, 16, 59, 0, 0, time.UTC)
+
+	raw := BuildICal("Jadwal Kuliah", []ICalEvent{
+		{
+			UID:         "kuliah-IF101@nf-studenthub",
+			Summary:     "Algoritma, Struktur Data; Kelas A",
+			Description: "Dosen: " + strings.Repeat("Prof. Dr. Ir. Nama Sangat Panjang ", 4),
+			Location:    "R.301",
+			Start:       start,
+			End:         start.Add(100 * time.Minute),
+			Local:       true,
+			RRule:       "FREQ=WEEKLY;UNTIL=20261220T165959Z",
+			ExDates:     []time.Time{start.AddDate(0, 0, 49)},
+		},
+		{
+			UID:     "tugas-7@nf-studenthub",
+			Summary: "Deadline Tugas 1",
+			Start:   deadline,
+			End:     deadline,
+			Alarm:   time.Hour,
+		},
+	})
+	out := string(raw)
+
+	if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Fatal("ada akhir baris tanpa CR")
+	}
+	for _, p := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(p) > 75 {
+			t.Fatalf("baris fisik melebihi 75 oktet: %q", p)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(unfoldICal(out), "\r\n"), "\r\n")
+	if lines[0] != "BEGIN:VCALENDAR" || lines[len(lines)-1] != "END:VCALENDAR" {
+		t.Fatal("VCALENDAR tidak lengkap")
+	}
+
+	want := []string{
+		"VERSION:2.0",
+		"X-WR-CALNAME:Jadwal Kuliah",
+		"TZID:Asia/Jakarta",
+		"DTSTART;TZID=Asia/Jakarta:20260907T080000",
+		"DTEND;TZID=Asia/Jakarta:20260907T094000",
+		"RRULE:FREQ=WEEKLY;UNTIL=20261220T165959Z",
+		"EXDATE;TZID=Asia/Jakarta:20261026T080000",
+		`SUMMARY:Algoritma\, Struktur Data\; Kelas A`,
+		"LOCATION:R.301",
+		"DESCRIPTION:Dosen: " + strings.Repeat("Prof. Dr. Ir. Nama Sangat Panjang ", 4),
+		"DTSTART:20260920T165900Z",
+		"TRIGGER:-PT60M",
+	}
+	for _, w := range want {
+		found := false
+		for _, l := range lines {
+			if l == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("baris %q tidak ada", w)
+		}
+	}
+
+	if n := strings.Count(out, "BEGIN:VEVENT\r\n"); n != 2 || strings.Count(out, "END:VEVENT\r\n") != 2 {
+		t.Errorf("jumlah VEVENT %d, seharusnya 2", n)
+	}
+}
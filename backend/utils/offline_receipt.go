@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// OfflineReceiptKey menurunkan kunci struk absensi offline untuk pasangan mahasiswa dan perangkat.
+// Kunci diberikan ke perangkat saat online, lalu dipakai menandatangani struk saat scan tanpa koneksi.
+func OfflineReceiptKey(studentID int, deviceFingerprint string) string {
+	mac := hmac.New(sha256.New, getQRSigningKey())
+	fmt.Fprintf(mac, "offline-receipt|%d|%s", studentID, deviceFingerprint)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignOfflineReceipt menandatangani struk: HMAC-SHA256(key, "qr_payload|student_id|captured_at")
+// dengan captured_at berupa unix timestamp (detik)
+func SignOfflineReceipt(key, qrPayload string, studentID int, capturedAt int64) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%s|%d|%d", qrPayload, studentID, capturedAt)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyOfflineReceipt memeriksa tanda tangan struk offline
+func VerifyOfflineReceipt(key, qrPayload string, studentID int, capturedAt int64, signature string) bool {
+	expected := SignOfflineReceipt(key, qrPayload, studentID, capturedAt)
+	return hmac.Equal([]byte(expected), []byte(strings.TrimSpace(signature)))
+}
@@ -0,0 +1,355 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PDF writer minimal: halaman A4, font standar Helvetica/Helvetica-Bold (WinAnsi),
+// teks, garis dan tabel sederhana. Koordinat y dihitung dari atas halaman.
+
+const (
+	PDFPageWidth  = 595.0
+	PDFPageHeight = 842.0
+	PDFMargin     = 50.0
+)
+
+// PDFColumn - definisi kolom tabel
+type PDFColumn struct {
+	Title string
+	Width float64
+	Align string // "left" (default), "right" atau "center"
+}
+
+// PDF - dokumen yang sedang disusun
+type PDF struct {
+	Title  string
+	Y      float64 // posisi kursor dari atas halaman
+	Footer string  // dicetak di setiap halaman bersama nomor halaman
+	pages  []*bytes.Buffer
+}
+
+// NewPDF membuat dokumen baru dengan satu halaman kosong
+func NewPDF(title string) *PDF {
+	p := &PDF{Title: title}
+	p.AddPage()
+	return p
+}
+
+// AddPage menambah halaman dan mengembalikan kursor ke margin atas
+func (p *PDF) AddPage() {
+	p.pages = append(p.pages, &bytes.Buffer{})
+	p.Y = PDFMargin
+}
+
+func (p *PDF) page() *bytes.Buffer {
+	return p.pages[len(p.pages)-1]
+}
+
+// ensureSpace pindah ke halaman baru jika sisa ruang kurang dari height
+func (p *PDF) ensureSpace(height float64) {
+	if p.Y+height > PDFPageHeight-PDFMargin-20 {
+		p.AddPage()
+	}
+}
+
+// Text menulis teks pada posisi x dan baris dasar y (dari atas)
+func (p *PDF) Text(x, y, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(p.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+		font, size, x, PDFPageHeight-y, pdfEscape(text))
+}
+
+// TextAligned menulis teks di dalam lebar width dengan perataan kiri, kanan atau tengah
+func (p *PDF) TextAligned(x, y, width, size float64, bold bool, align, text string) {
+	switch align {
+	case "right":
+		x += width - PDFTextWidth(text, size, bold)
+	case "center":
+		x += (width - PDFTextWidth(text, size, bold)) / 2
+	}
+	p.Text(x, y, size, bold, text)
+}
+
+// Line menggambar garis dari (x1, y1) ke (x2, y2)
+func (p *PDF) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(p.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n",
+		x1, PDFPageHeight-y1, x2, PDFPageHeight-y2)
+}
+
+// FillRect mengisi persegi dengan warna abu-abu (0 hitam, 1 putih)
+func (p *PDF) FillRect(x, y, width, height, gray float64) {
+	fmt.Fprintf(p.page(), "%.2f g %.2f %.2f %.2f %.2f re f 0 g\n",
+		gray, x, PDFPageHeight-y-height, width, height)
+}
+
+// Heading menulis judul di tengah halaman lalu memajukan kursor
+func (p *PDF) Heading(text string, size float64) {
+	p.ensureSpace(size + 8)
+	p.Y += size
+	p.TextAligned(PDFMargin, p.Y, PDFPageWidth-2*PDFMargin, size, true, "center", text)
+	p.Y += 8
+}
+
+// Paragraph menulis teks yang dibungkus sesuai lebar halaman
+func (p *PDF) Paragraph(text string, size float64) {
+	for _, line := range PDFWrapText(text, size, false, PDFPageWidth-2*PDFMargin) {
+		p.ensureSpace(size + 4)
+		p.Y += size + 2
+		p.Text(PDFMargin, p.Y, size, false, line)
+	}
+	p.Y += 4
+}
+
+// KeyValue menulis pasangan label dan nilai dalam satu baris
+func (p *PDF) KeyValue(label, value string, labelWidth float64) {
+	p.ensureSpace(16)
+	p.Y += 14
+	p.Text(PDFMargin, p.Y, 10, false, label)
+	p.Text(PDFMargin+labelWidth, p.Y, 10, false, ": "+value)
+}
+
+// Space memajukan kursor
+func (p *PDF) Space(height float64) {
+	p.Y += height
+}
+
+// Rule menggambar garis horizontal selebar area tulis
+func (p *PDF) Rule() {
+	p.ensureSpace(6)
+	p.Y += 4
+	p.Line(PDFMargin, p.Y, PDFPageWidth-PDFMargin, p.Y)
+	p.Y += 4
+}
+
+// Table menggambar tabel dengan header berlatar abu-abu; header diulang di halaman baru
+func (p *PDF) Table(columns []PDFColumn, rows [][]string) {
+	const rowHeight, size = 16.0, 9.0
+
+	var total float64
+	for _, col := range columns {
+		total += col.Width
+	}
+
+	drawHeader := func() {
+		p.FillRect(PDFMargin, p.Y, total, rowHeight, 0.88)
+		x := PDFMargin
+		for _, col := range columns {
+			p.TextAligned(x+3, p.Y+11.5, col.Width-6, size, true, col.Align, col.Title)
+			x += col.Width
+		}
+		p.Line(PDFMargin, p.Y+rowHeight, PDFMargin+total, p.Y+rowHeight)
+		p.Y += rowHeight
+	}
+
+	p.ensureSpace(rowHeight * 2)
+	p.Line(PDFMargin, p.Y, PDFMargin+total, p.Y)
+	drawHeader()
+
+	for _, row := range rows {
+		if p.Y+rowHeight > PDFPageHeight-PDFMargin-20 {
+			p.AddPage()
+			p.Line(PDFMargin, p.Y, PDFMargin+total, p.Y)
+			drawHeader()
+		}
+		x := PDFMargin
+		for i, col := range columns {
+			if i < len(row) {
+				text := pdfTruncate(row[i], size, col.Width-6)
+				p.TextAligned(x+3, p.Y+11.5, col.Width-6, size, false, col.Align, text)
+			}
+			x += col.Width
+		}
+		p.Y += rowHeight
+		p.Line(PDFMargin, p.Y, PDFMargin+total, p.Y)
+	}
+	p.Y += 6
+}
+
+// Bytes menyusun file PDF lengkap
+func (p *PDF) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	// Objek 1-5 tetap, lalu pasangan page/content untuk setiap halaman
+	pageCount := len(p.pages)
+	objectCount := 5 + pageCount*2
+
+	beginObject := func() {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n", len(offsets))
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	beginObject()
+	out.WriteString("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	beginObject()
+	kids := make([]string, pageCount)
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+i*2)
+	}
+	fmt.Fprintf(&out, "<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), pageCount)
+
+	beginObject()
+	out.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>\nendobj\n")
+
+	beginObject()
+	out.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>\nendobj\n")
+
+	beginObject()
+	fmt.Fprintf(&out, "<< /Title (%s) /Producer (NF StudentHub) /CreationDate (D:%s) >>\nendobj\n",
+		pdfEscape(p.Title), time.Now().Format("20060102150405"))
+
+	for i, content := range p.pages {
+		stream := content.Bytes()
+		if p.Footer != "" {
+			var footer bytes.Buffer
+			fmt.Fprintf(&footer, "BT /F1 8 Tf %.2f %.2f Td (%s) Tj ET\n", PDFMargin, 30.0, pdfEscape(p.Footer))
+			pageLabel := fmt.Sprintf("Halaman %d dari %d", i+1, pageCount)
+			fmt.Fprintf(&footer, "BT /F1 8 Tf %.2f %.2f Td (%s) Tj ET\n",
+				PDFPageWidth-PDFMargin-PDFTextWidth(pageLabel, 8, false), 30.0, pageLabel)
+			stream = append(append([]byte{}, stream...), footer.Bytes()...)
+		}
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write(stream)
+		zw.Close()
+
+		beginObject()
+		fmt.Fprintf(&out, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+			PDFPageWidth, PDFPageHeight, len(offsets)+1)
+
+		beginObject()
+		fmt.Fprintf(&out, "<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+		out.Write(compressed.Bytes())
+		out.WriteString("\nendstream\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", objectCount+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", objectCount+1, xref)
+
+	return out.Bytes()
+}
+
+// pdfWinAnsi - karakter di luar Latin-1 yang punya kode di WinAnsiEncoding
+var pdfWinAnsi = map[rune]byte{
+	'€': 0x80, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+}
+
+// pdfEncode mengubah teks UTF-8 ke byte WinAnsi; karakter lain diganti '?'
+func pdfEncode(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+			out = append(out, byte(r))
+		case pdfWinAnsi[r] != 0:
+			out = append(out, pdfWinAnsi[r])
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// pdfEscape menyiapkan teks untuk string literal PDF
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, c := range pdfEncode(text) {
+		switch c {
+		case '\\', '(', ')':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n', '\r', '\t':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Lebar glyph Helvetica dan Helvetica-Bold (satuan 1/1000 em) untuk karakter 32-126
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// PDFTextWidth menghitung lebar teks dalam point
+func PDFTextWidth(text string, size float64, bold bool) float64 {
+	widths := &helveticaWidths
+	if bold {
+		widths = &helveticaBoldWidths
+	}
+
+	total := 0
+	for _, c := range pdfEncode(text) {
+		if c >= 32 && c <= 126 {
+			total += widths[c-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// PDFWrapText memecah teks menjadi baris yang muat dalam lebar width
+func PDFWrapText(text string, size float64, bold bool, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && PDFTextWidth(candidate, size, bold) > width {
+				lines = append(lines, line)
+				candidate = word
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// pdfTruncate memotong teks yang lebih lebar dari kolom dan menambahkan "..."
+func pdfTruncate(text string, size, width float64) string {
+	if PDFTextWidth(text, size, false) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && PDFTextWidth(string(runes)+"...", size, false) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// pdfTestStreams - Dekompresi seluruh content stream dalam file PDF
+func pdfTestStreams(t *testing.T, raw []byte) []string {
+	t.Helper()
+	re := regexp.MustCompile(`<< /Length (\d+) /Filter /FlateDecode >>\nstream\n`)
+
+	var streams []string
+	for _, m := range re.FindAllSubmatchIndex(raw, -1) {
+		length, _ := strconv.Atoi(string(raw[m[2]:m[3]]))
+		start := m[1]
+		if start+length > len(raw) || !bytes.HasPrefix(raw[start+length:], []byte("\nendstream")) {
+			t.Fatalf("/Length %d tidak cocok dengan isi stream", length)
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(raw[start : start+length]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		streams = append(streams, string(body))
+	}
+	return streams
+}
+
+func TestPDFStructure(t *testing.T) {
+	p := NewPDF("Rekap (Semester) Ganjil")
+	p.Footer = "Dicetak oleh NF StudentHub"
+	p.Heading("Transkrip Nilai", 14)
+	p.Paragraph("Dokumen ini dibuat otomatis dan sah tanpa tanda tangan basah.", 10)
+
+	rows := make([][]string, 80)
+	for i := range rows {
+		rows[i] = []string{strconv.Itoa(i + 1), fmt.Sprintf("Mata Kuliah %d", i+1), "A"}
+	}
+	p.Table([]PDFColumn{{"No", 40, "right"}, {"Mata Kuliah", 300, ""}, {"Nilai", 60, "center"}}, rows)
+
+	raw := p.Bytes()
+	if !bytes.HasPrefix(raw, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(raw, []byte("%%EOF\n")) {
+		t.Fatal("header atau trailer PDF tidak ada")
+	}
+
+	// startxref menunjuk tabel xref, dan setiap offset xref menunjuk objek bernomor sesuai
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(raw)
+	if m == nil {
+		t.Fatal("startxref tidak ada")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(raw[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d tidak menunjuk tabel xref", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(raw[xref:], -1)
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(raw[offset:], []byte(want)) {
+			t.Fatalf("offset xref objek %d (%d) salah", i+1, offset)
+		}
+	}
+
+	pages := len(p.pages)
+	if pages < 2 {
+		t.Fatalf("tabel 80 baris seharusnya lebih dari satu halaman, dapat %d", pages)
+	}
+	if want := 5 + pages*2; len(entries) != want {
+		t.Fatalf("jumlah entri xref %d, seharusnya %d", len(entries), want)
+	}
+	if !bytes.Contains(raw, []byte(fmt.Sprintf("/Count %d >>", pages))) {
+		t.Fatal("/Count halaman tidak sesuai")
+	}
+	if !bytes.Contains(raw, []byte(`/Title (Rekap \(Semester\) Ganjil)`)) {
+		t.Fatal("judul tidak di-escape")
+	}
+
+	streams := pdfTestStreams(t, raw)
+	if len(streams) != pages {
+		t.Fatalf("jumlah content stream %d, seharusnya %d", len(streams), pages)
+	}
+	for i, s := range streams {
+		if !strings.Contains(s, fmt.Sprintf("(Halaman %d dari %d)", i+1, pages)) {
+			t.Errorf("halaman %d tanpa nomor halaman", i+1)
+		}
+		// Header tabel diulang di setiap halaman
+		if !strings.Contains(s, "(Mata Kuliah) Tj") {
+			t.Errorf("halaman %d tanpa header tabel", i+1)
+		}
+	}
+	if !strings.Contains(streams[len(streams)-1], "(Mata Kuliah 80) Tj") {
+		t.Error("baris terakhir tabel tidak tercetak")
+	}
+}
+
+func TestPDFEscape(t *testing.T) {
+	tests := map[string]string{
+		`Nilai (akhir)`:  `Nilai \(akhir\)`,
+		`C:\data`:        `C:\\data`,
+		"baris\nbaru":    "baris baru",
+		"Café — 5 €":     "Caf\xe9 \x97 5 \x80",
+		"Nama: 王小明":      "Nama: ???",
+		"“kutip” • poin": "\x93kutip\x94 \x95 poin",
+	}
+	for in, want := range tests {
+		if got := pdfEscape(in); got != want {
+			t.Errorf("pdfEscape(%q) = %q, seharusnya %q", in, got, want)
+		}
+	}
+}
+
+func TestPDFWrapText(t *testing.T) {
+	text := "Mahasiswa yang kehadirannya di bawah tujuh puluh lima persen tidak dapat mengikuti ujian akhir semester.\nBaris kedua."
+	const width = 150.0
+
+	lines := PDFWrapText(text, 10, false, width)
+	if len(lines) < 3 {
+		t.Fatalf("teks seharusnya dibungkus, dapat %d baris", len(lines))
+	}
+	for _, line := range lines {
+		if PDFTextWidth(line, 10, false) > width {
+			t.Errorf("baris %q melebihi lebar %v", line, width)
+		}
+	}
+	if got := strings.Join(lines, " "); strings.Join(strings.Fields(got), " ") != strings.Join(strings.Fields(text), " ") {
+		t.Errorf("isi teks berubah setelah dibungkus: %q", got)
+	}
+	if lines[len(lines)-1] != "Baris kedua." {
+		t.Errorf("baris baru tidak dipertahankan: %q", lines[len(lines)-1])
+	}
+}
+
+func TestPDFTruncate(t *testing.T) {
+	got := pdfTruncate("Pemrograman Berorientasi Objek Lanjut", 9, 80)
+	if !strings.HasSuffix(got, "...") || PDFTextWidth(got, 9, false) > 80 {
+		t.Fatalf("pdfTruncate = %q (lebar %v)", got, PDFTextWidth(got, 9, false))
+	}
+	if got := pdfTruncate("Basis Data", 9, 80); got != "Basis Data" {
+		t.Fatalf("teks pendek ikut dipotong: %q", got)
+	}
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// QRPayload - isi QR absensi yang ditandatangani server
+type QRPayload struct {
+	SessionID   int    `json:"sid"`
+	CourseID    string `json:"c"`
+	PertemuanKe int    `json:"p"`
+	Code        string `json:"k"`
+	IssuedAt    int64  `json:"iat"`
+	ExpiresAt   int64  `json:"exp"`
+}
+
+var (
+	ErrQRPayloadInvalid = errors.New("QR Code tidak valid")
+	ErrQRPayloadExpired = errors.New("QR Code sudah kadaluarsa")
+)
+
+// getQRSigningKey membaca kunci tanda tangan QR dari env QR_SIGNING_KEY (fallback ke JWT_SECRET)
+func getQRSigningKey() []byte {
+	if key := os.Getenv("QR_SIGNING_KEY"); key != "" {
+		return []byte(key)
+	}
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	return []byte("your_secret_key_here")
+}
+
+func signQRBody(body string) string {
+	mac := hmac.New(sha256.New, getQRSigningKey())
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewQRPayload menyusun payload untuk kode QR yang berlaku pada waktu t.
+// Payload berlaku sampai akhir window berikutnya, sama dengan toleransi ValidateQRToken.
+func NewQRPayload(sessionID int, courseID string, pertemuanKe int, code string, t time.Time, period int) QRPayload {
+	window := qrTokenWindow(t, period)
+	return QRPayload{
+		SessionID:   sessionID,
+		CourseID:    courseID,
+		PertemuanKe: pertemuanKe,
+		Code:        code,
+		IssuedAt:    window * int64(period),
+		ExpiresAt:   (window + 2) * int64(period),
+	}
+}
+
+// SignQRPayload menghasilkan string "body.signature" (base64url) untuk dienkode ke QR
+func SignQRPayload(p QRPayload) string {
+	raw, _ := json.Marshal(p)
+	body := base64.RawURLEncoding.EncodeToString(raw)
+	return body + "." + signQRBody(body)
+}
+
+// VerifyQRPayload memeriksa tanda tangan dan masa berlaku payload tanpa akses database.
+// now adalah waktu scan; untuk struk offline gunakan waktu pengambilan di struk.
+func VerifyQRPayload(token string, now time.Time) (QRPayload, error) {
+	var p QRPayload
+
+	body, sig, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok || body == "" || sig == "" {
+		return p, ErrQRPayloadInvalid
+	}
+	if !hmac.Equal([]byte(sig), []byte(signQRBody(body))) {
+		return p, ErrQRPayloadInvalid
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil || json.Unmarshal(raw, &p) != nil || p.SessionID <= 0 || p.Code == "" {
+		return QRPayload{}, ErrQRPayloadInvalid
+	}
+	if now.Unix() < p.IssuedAt {
+		return p, ErrQRPayloadInvalid
+	}
+	if now.Unix() >= p.ExpiresAt {
+		return p, ErrQRPayloadExpired
+	}
+	return p, nil
+}
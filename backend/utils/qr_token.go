@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DefaultQRTokenPeriod - lama satu window token QR absensi (detik)
+const DefaultQRTokenPeriod = 30
+
+// qrTokenDigits - panjang kode QR yang dihasilkan
+const qrTokenDigits = 8
+
+// GetQRTokenPeriod membaca periode rotasi token dari env QR_TOKEN_PERIOD
+func GetQRTokenPeriod() int {
+	if v := os.Getenv("QR_TOKEN_PERIOD"); v != "" {
+		if period, err := strconv.Atoi(v); err == nil && period >= 5 {
+			return period
+		}
+	}
+	return DefaultQRTokenPeriod
+}
+
+// GenerateQRSecret membuat secret per sesi absensi untuk penurunan token QR
+func GenerateQRSecret() string {
+	return GenerateRandomString(64)
+}
+
+// qrTokenWindow menghitung nomor window waktu untuk t
+func qrTokenWindow(t time.Time, period int) int64 {
+	return t.Unix() / int64(period)
+}
+
+// qrTokenForWindow menurunkan kode dari secret dan nomor window (mirip TOTP, RFC 6238)
+func qrTokenForWindow(secret string, window int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(window))
+
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < qrTokenDigits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", qrTokenDigits, code%mod)
+}
+
+// CurrentQRToken mengembalikan token QR yang berlaku pada waktu t
+// beserta sisa detik sebelum token berganti
+func CurrentQRToken(secret string, t time.Time, period int) (string, int) {
+	window := qrTokenWindow(t, period)
+	remaining := int((window+1)*int64(period) - t.Unix())
+	return qrTokenForWindow(secret, window), remaining
+}
+
+// ValidateQRToken memeriksa token terhadap window saat ini dan satu window sebelumnya,
+// sehingga QR yang baru saja berganti saat mahasiswa memindai tetap diterima
+func ValidateQRToken(secret, token string, t time.Time, period int) bool {
+	if secret == "" || token == "" {
+		return false
+	}
+
+	window := qrTokenWindow(t, period)
+	for _, w := range []int64{window, window - 1} {
+		expected := qrTokenForWindow(secret, w)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,522 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// QR Code encoder minimal: mode byte, error correction level M, versi 1-10.
+// Cukup untuk payload absensi yang ditandatangani (di bawah 200 byte).
+
+// qrVersionM - struktur blok per versi untuk level M
+type qrVersionM struct {
+	totalCodewords int
+	eccPerBlock    int
+	numBlocks      int
+	alignment      []int
+}
+
+var qrVersionsM = []qrVersionM{
+	{}, // index 0 tidak dipakai
+	{26, 10, 1, nil},
+	{44, 16, 1, []int{6, 18}},
+	{70, 26, 1, []int{6, 22}},
+	{100, 18, 2, []int{6, 26}},
+	{134, 24, 2, []int{6, 30}},
+	{172, 16, 4, []int{6, 34}},
+	{196, 18, 4, []int{6, 22, 38}},
+	{242, 22, 4, []int{6, 24, 42}},
+	{292, 22, 5, []int{6, 26, 46}},
+	{346, 26, 5, []int{6, 28, 50}},
+}
+
+// qrRemainderBits - bit sisa setelah codeword per versi
+var qrRemainderBits = []int{0, 0, 7, 7, 7, 7, 7, 0, 0, 0, 0}
+
+// ErrQRDataTooLong - data melebihi kapasitas versi 10 level M
+var ErrQRDataTooLong = errors.New("data terlalu panjang untuk QR code")
+
+// QRCode - matriks modul QR (true = gelap)
+type QRCode struct {
+	Size    int
+	modules [][]bool
+	reserve [][]bool
+}
+
+// EncodeQR membuat QR code dari data (mode byte, level M)
+func EncodeQR(data []byte) (*QRCode, error) {
+	version := 0
+	for v := 1; v < len(qrVersionsM); v++ {
+		if len(data) <= qrDataCapacity(v) {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrQRDataTooLong
+	}
+
+	info := qrVersionsM[version]
+	dataCodewords := qrEncodeData(data, version)
+	allCodewords := qrAddECCAndInterleave(dataCodewords, info)
+
+	size := version*4 + 17
+	q := &QRCode{Size: size}
+	q.modules = make([][]bool, size)
+	q.reserve = make([][]bool, size)
+	for i := range q.modules {
+		q.modules[i] = make([]bool, size)
+		q.reserve[i] = make([]bool, size)
+	}
+
+	q.drawFunctionPatterns(version)
+	q.drawCodewords(allCodewords)
+
+	// Pilih mask dengan penalti terkecil
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormatBits(mask)
+		penalty := q.penalty()
+		if bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		q.applyMask(mask) // XOR dua kali = kembali semula
+	}
+	q.applyMask(bestMask)
+	q.drawFormatBits(bestMask)
+
+	return q, nil
+}
+
+// Dark - apakah modul pada baris y kolom x gelap
+func (q *QRCode) Dark(x, y int) bool {
+	return q.modules[y][x]
+}
+
+// qrDataCapacity - jumlah byte data maksimal untuk versi
+func qrDataCapacity(version int) int {
+	info := qrVersionsM[version]
+	dataBits := (info.totalCodewords - info.eccPerBlock*info.numBlocks) * 8
+	return (dataBits - 4 - qrCountBits(version)) / 8
+}
+
+// qrCountBits - panjang field jumlah karakter mode byte
+func qrCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// qrEncodeData - Susun bit mode, jumlah, data, terminator dan padding
+func qrEncodeData(data []byte, version int) []byte {
+	info := qrVersionsM[version]
+	capacity := info.totalCodewords - info.eccPerBlock*info.numBlocks
+
+	var bits []bool
+	appendBits := func(value, length int) {
+		for i := length - 1; i >= 0; i-- {
+			bits = append(bits, (value>>uint(i))&1 == 1)
+		}
+	}
+
+	appendBits(0x4, 4) // mode byte
+	appendBits(len(data), qrCountBits(version))
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+
+	capacityBits := capacity * 8
+	for i := 0; i < 4 && len(bits) < capacityBits; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+
+	result := make([]byte, 0, capacity)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				b |= 1 << uint(7-j)
+			}
+		}
+		result = append(result, b)
+	}
+
+	for pad := byte(0xEC); len(result) < capacity; pad ^= 0xEC ^ 0x11 {
+		result = append(result, pad)
+	}
+	return result
+}
+
+// qrAddECCAndInterleave - Bagi data ke blok, tambah Reed-Solomon, lalu interleave
+func qrAddECCAndInterleave(data []byte, info qrVersionM) []byte {
+	numShortBlocks := info.numBlocks - info.totalCodewords%info.numBlocks
+	shortBlockLen := info.totalCodewords / info.numBlocks
+	divisor := qrRSDivisor(info.eccPerBlock)
+
+	blocks := make([][]byte, 0, info.numBlocks)
+	k := 0
+	for i := 0; i < info.numBlocks; i++ {
+		datLen := shortBlockLen - info.eccPerBlock
+		if i >= numShortBlocks {
+			datLen++
+		}
+		dat := append([]byte{}, data[k:k+datLen]...)
+		k += datLen
+
+		ecc := qrRSRemainder(dat, divisor)
+		if i < numShortBlocks {
+			dat = append(dat, 0)
+		}
+		blocks = append(blocks, append(dat, ecc...))
+	}
+
+	result := make([]byte, 0, info.totalCodewords)
+	for i := 0; i < len(blocks[0]); i++ {
+		for j, block := range blocks {
+			if i != shortBlockLen-info.eccPerBlock || j >= numShortBlocks {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+// qrRSDivisor - Polinom generator Reed-Solomon untuk derajat tertentu
+func qrRSDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = qrGFMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = qrGFMultiply(root, 0x02)
+	}
+	return result
+}
+
+// qrRSRemainder - Sisa pembagian polinom data dengan divisor (codeword ECC)
+func qrRSRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= qrGFMultiply(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+// qrGFMultiply - Perkalian di GF(2^8) dengan polinom 0x11D
+func qrGFMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+func (q *QRCode) setFunction(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.reserve[y][x] = true
+}
+
+// drawFunctionPatterns - finder, timing, alignment, dark module dan area format/versi
+func (q *QRCode) drawFunctionPatterns(version int) {
+	size := q.Size
+
+	for i := 0; i < size; i++ {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+
+	q.drawFinder(3, 3)
+	q.drawFinder(size-4, 3)
+	q.drawFinder(3, size-4)
+
+	align := qrVersionsM[version].alignment
+	for i, ax := range align {
+		for j, ay := range align {
+			// Lewati posisi yang bertumpuk dengan finder
+			if (i == 0 && j == 0) || (i == 0 && j == len(align)-1) || (i == len(align)-1 && j == 0) {
+				continue
+			}
+			q.drawAlignment(ax, ay)
+		}
+	}
+
+	// Reservasi area format (diisi ulang oleh drawFormatBits)
+	q.drawFormatBits(0)
+
+	if version >= 7 {
+		rem := version
+		for i := 0; i < 12; i++ {
+			rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+		}
+		bits := version<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := (bits>>uint(i))&1 == 1
+			a := size - 11 + i%3
+			b := i / 3
+			q.setFunction(a, b, dark)
+			q.setFunction(b, a, dark)
+		}
+	}
+}
+
+func (q *QRCode) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= q.Size || y >= q.Size {
+				continue
+			}
+			dist := qrMax(qrAbs(dx), qrAbs(dy))
+			q.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (q *QRCode) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			q.setFunction(cx+dx, cy+dy, qrMax(qrAbs(dx), qrAbs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormatBits - Tulis 15 bit format (level M + mask) di dua lokasi
+func (q *QRCode) drawFormatBits(mask int) {
+	const eccLevelM = 0
+	data := eccLevelM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 == 1 }
+
+	size := q.Size
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(i))
+	}
+	q.setFunction(8, 7, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		q.setFunction(size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, size-15+i, bit(i))
+	}
+	q.setFunction(8, size-8, true)
+}
+
+// drawCodewords - Tempatkan bit codeword secara zig-zag dari kanan bawah
+func (q *QRCode) drawCodewords(data []byte) {
+	size := q.Size
+	i := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				upward := (right+1)&2 == 0
+				y := vert
+				if upward {
+					y = size - 1 - vert
+				}
+				if !q.reserve[y][x] && i < len(data)*8 {
+					q.modules[y][x] = (data[i>>3]>>uint(7-(i&7)))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask - XOR pola mask ke modul data
+func (q *QRCode) applyMask(mask int) {
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if q.reserve[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty - Skor penalti mask (aturan N1-N4)
+func (q *QRCode) penalty() int {
+	size := q.Size
+	score := 0
+	get := func(x, y int, horizontal bool) bool {
+		if horizontal {
+			return q.modules[y][x]
+		}
+		return q.modules[x][y]
+	}
+
+	finderA := []bool{true, false, true, true, true, false, true, false, false, false, false}
+	finderB := []bool{false, false, false, false, true, false, true, true, true, false, true}
+
+	for _, horizontal := range []bool{true, false} {
+		for y := 0; y < size; y++ {
+			run := 1
+			for x := 1; x < size; x++ {
+				if get(x, y, horizontal) == get(x-1, y, horizontal) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += 3 + run - 5
+				}
+				run = 1
+			}
+			if run >= 5 {
+				score += 3 + run - 5
+			}
+
+			for x := 0; x+len(finderA) <= size; x++ {
+				matchA, matchB := true, true
+				for k := range finderA {
+					v := get(x+k, y, horizontal)
+					matchA = matchA && v == finderA[k]
+					matchB = matchB && v == finderB[k]
+				}
+				if matchA {
+					score += 40
+				}
+				if matchB {
+					score += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < size && y+1 < size {
+				c := q.modules[y][x]
+				if c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+					score += 3
+				}
+			}
+		}
+	}
+
+	percent := dark * 100 / (size * size)
+	score += qrAbs(percent-50) / 5 * 10
+	return score
+}
+
+// qrQuietZone - lebar margin kosong (modul) di sekeliling QR
+const qrQuietZone = 4
+
+// PNG - Render QR ke PNG dengan scale piksel per modul
+func (q *QRCode) PNG(scale int) ([]byte, error) {
+	if scale < 1 {
+		scale = 1
+	}
+	dim := (q.Size + qrQuietZone*2) * scale
+	img := image.NewPaletted(image.Rect(0, 0, dim, dim), color.Palette{color.White, color.Black})
+
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if !q.modules[y][x] {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex((x+qrQuietZone)*scale+dx, (y+qrQuietZone)*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG - Render QR ke SVG (satu path, ukuran dalam satuan modul)
+func (q *QRCode) SVG() string {
+	dim := q.Size + qrQuietZone*2
+
+	var path strings.Builder
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if q.modules[y][x] {
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+qrQuietZone, y+qrQuietZone)
+			}
+		}
+	}
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#fff"/><path d="%s" fill="#000"/></svg>`, dim, dim, path.String())
+}
+
+func qrAbs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func qrMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,462 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/png"
+	"testing"
+	"time"
+)
+
+// Decoder referensi untuk pengujian: ditulis ulang dari ISO/IEC 18004 tanpa memakai tabel atau
+// helper encoder, sehingga kesalahan tata letak, interleave atau Reed-Solomon di encoder ikut ketahuan.
+
+// qrSpecBlocksM - tabel 9 ISO/IEC 18004 level M: {jumlah blok, codeword per blok, codeword data per blok}
+var qrSpecBlocksM = map[int][][3]int{
+	1:  {{1, 26, 16}},
+	2:  {{1, 44, 28}},
+	3:  {{1, 70, 44}},
+	4:  {{2, 50, 32}},
+	5:  {{2, 67, 43}},
+	6:  {{4, 43, 27}},
+	7:  {{4, 49, 31}},
+	8:  {{2, 60, 38}, {2, 61, 39}},
+	9:  {{3, 58, 36}, {2, 59, 37}},
+	10: {{4, 69, 43}, {1, 70, 44}},
+}
+
+// qrSpecAlignment - lampiran E: koordinat pusat pola alignment
+var qrSpecAlignment = map[int][]int{
+	2: {6, 18}, 3: {6, 22}, 4: {6, 26}, 5: {6, 30}, 6: {6, 34},
+	7: {6, 22, 38}, 8: {6, 24, 42}, 9: {6, 26, 46}, 10: {6, 28, 50},
+}
+
+// qrSpecVersionInfo - lampiran D: 18 bit informasi versi
+var qrSpecVersionInfo = map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3}
+
+// qrSpecFormatM - lampiran C: 15 bit informasi format level M untuk mask 0-7 (sudah di-XOR 0x5412)
+var qrSpecFormatM = []int{0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0}
+
+// qrSpecByteCapacityM - tabel 7: kapasitas mode byte level M versi 1-10
+var qrSpecByteCapacityM = []int{0, 14, 26, 42, 62, 84, 106, 122, 152, 180, 213}
+
+// qrSpecMask - kondisi mask (i = baris, j = kolom)
+func qrSpecMask(mask, i, j int) bool {
+	switch mask {
+	case 0:
+		return (i+j)%2 == 0
+	case 1:
+		return i%2 == 0
+	case 2:
+		return j%3 == 0
+	case 3:
+		return (i+j)%3 == 0
+	case 4:
+		return (i/2+j/3)%2 == 0
+	case 5:
+		return (i*j)%2+(i*j)%3 == 0
+	case 6:
+		return ((i*j)%2+(i*j)%3)%2 == 0
+	default:
+		return ((i+j)%2+(i*j)%3)%2 == 0
+	}
+}
+
+// qrSpecFunctionModules - modul fungsi (finder, separator, format, timing, alignment, versi)
+func qrSpecFunctionModules(version int) [][]bool {
+	size := version*4 + 17
+	fn := make([][]bool, size)
+	for y := range fn {
+		fn[y] = make([]bool, size)
+		for x := range fn[y] {
+			fn[y][x] = (x < 9 && y < 9) || (x >= size-8 && y < 9) || (x < 9 && y >= size-8) || x == 6 || y == 6
+			if version >= 7 && ((x >= size-11 && x < size-8 && y < 6) || (y >= size-11 && y < size-8 && x < 6)) {
+				fn[y][x] = true
+			}
+		}
+	}
+
+	centers := qrSpecAlignment[version]
+	last := len(centers) - 1
+	for a, cx := range centers {
+		for b, cy := range centers {
+			if (a == 0 && b == 0) || (a == 0 && b == last) || (a == last && b == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					fn[cy+dy][cx+dx] = true
+				}
+			}
+		}
+	}
+	return fn
+}
+
+// qrSpecGF - tabel eksponen dan logaritma GF(2^8) dengan polinom 0x11D
+type qrSpecGF struct {
+	exp [512]byte
+	log [256]int
+}
+
+func newQRSpecGF() *qrSpecGF {
+	gf := &qrSpecGF{}
+	x := 1
+	for i := 0; i < 255; i++ {
+		gf.exp[i] = byte(x)
+		gf.log[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	for i := 255; i < 512; i++ {
+		gf.exp[i] = gf.exp[i-255]
+	}
+	return gf
+}
+
+func (gf *qrSpecGF) mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gf.exp[gf.log[a]+gf.log[b]]
+}
+
+// syndromesZero - codeword valid jika c(α^i) = 0 untuk i = 0..ecc-1
+func (gf *qrSpecGF) syndromesZero(block []byte, ecc int) bool {
+	for i := 0; i < ecc; i++ {
+		var r byte
+		for _, c := range block {
+			r = gf.mul(r, gf.exp[i]) ^ c
+		}
+		if r != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeQRForTest - Baca ulang matriks QR (level M, mode byte) menjadi data aslinya
+func decodeQRForTest(q *QRCode) ([]byte, error) {
+	size := q.Size
+	version := (size - 17) / 4
+	if version < 1 || version > 10 || version*4+17 != size {
+		return nil, fmt.Errorf("ukuran %d tidak valid", size)
+	}
+	dark := func(x, y int) bool { return q.Dark(x, y) }
+
+	// Pola finder: kotak 7x7 dengan cincin terang, separator terang di sisi dalam
+	for _, origin := range [][2]int{{0, 0}, {size - 7, 0}, {0, size - 7}} {
+		for dy := -1; dy <= 7; dy++ {
+			for dx := -1; dx <= 7; dx++ {
+				x, y := origin[0]+dx, origin[1]+dy
+				if x < 0 || y < 0 || x >= size || y >= size {
+					continue
+				}
+				ring := qrMaxInt(qrAbsInt(dx-3), qrAbsInt(dy-3))
+				if want := ring != 2 && ring != 4; dark(x, y) != want {
+					return nil, fmt.Errorf("pola finder salah di (%d,%d)", x, y)
+				}
+			}
+		}
+	}
+	for i := 8; i < size-8; i++ {
+		if dark(i, 6) != (i%2 == 0) || dark(6, i) != (i%2 == 0) {
+			return nil, fmt.Errorf("pola timing salah di %d", i)
+		}
+	}
+	if !dark(8, size-8) {
+		return nil, errors.New("dark module tidak ada")
+	}
+
+	// Informasi format: dua salinan harus identik dan berupa codeword level M yang valid
+	var format1, format2 int
+	put := func(v *int, bit int, on bool) {
+		if on {
+			*v |= 1 << uint(bit)
+		}
+	}
+	for i := 0; i <= 5; i++ {
+		put(&format1, i, dark(8, i))
+	}
+	put(&format1, 6, dark(8, 7))
+	put(&format1, 7, dark(8, 8))
+	put(&format1, 8, dark(7, 8))
+	for i := 9; i < 15; i++ {
+		put(&format1, i, dark(14-i, 8))
+	}
+	for i := 0; i < 8; i++ {
+		put(&format2, i, dark(size-1-i, 8))
+	}
+	for i := 8; i < 15; i++ {
+		put(&format2, i, dark(8, size-15+i))
+	}
+	if format1 != format2 {
+		return nil, fmt.Errorf("salinan format berbeda: %#x vs %#x", format1, format2)
+	}
+	mask := -1
+	for m, f := range qrSpecFormatM {
+		if f == format1 {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		return nil, fmt.Errorf("format %#x bukan level M", format1)
+	}
+
+	// Informasi versi (versi 7 ke atas) di kanan atas dan kiri bawah
+	if version >= 7 {
+		var v1, v2 int
+		for i := 0; i < 18; i++ {
+			put(&v1, i, dark(size-11+i%3, i/3))
+			put(&v2, i, dark(i/3, size-11+i%3))
+		}
+		if v1 != qrSpecVersionInfo[version] || v2 != qrSpecVersionInfo[version] {
+			return nil, fmt.Errorf("informasi versi %#x/%#x, seharusnya %#x", v1, v2, qrSpecVersionInfo[version])
+		}
+	}
+
+	// Baca codeword secara zig-zag, lewati modul fungsi, lalu buka mask
+	fn := qrSpecFunctionModules(version)
+	total := 0
+	for _, g := range qrSpecBlocksM[version] {
+		total += g[0] * g[1]
+	}
+	var raw []byte
+	var cur byte
+	nbits := 0
+	upward := true
+	for right := size - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for n := 0; n < size; n++ {
+			y := n
+			if upward {
+				y = size - 1 - n
+			}
+			for _, x := range []int{right, right - 1} {
+				if fn[y][x] || len(raw) == total {
+					continue
+				}
+				bit := dark(x, y) != qrSpecMask(mask, y, x)
+				cur <<= 1
+				if bit {
+					cur |= 1
+				}
+				nbits++
+				if nbits == 8 {
+					raw, cur, nbits = append(raw, cur), 0, 0
+				}
+			}
+		}
+		upward = !upward
+	}
+	if len(raw) != total {
+		return nil, fmt.Errorf("hanya %d dari %d codeword terbaca", len(raw), total)
+	}
+
+	// De-interleave blok, cek Reed-Solomon tiap blok
+	var blocks [][]byte
+	var dataLens []int
+	maxData := 0
+	for _, g := range qrSpecBlocksM[version] {
+		for i := 0; i < g[0]; i++ {
+			blocks = append(blocks, make([]byte, 0, g[1]))
+			dataLens = append(dataLens, g[2])
+			maxData = qrMaxInt(maxData, g[2])
+		}
+	}
+	ecc := qrSpecBlocksM[version][0][1] - qrSpecBlocksM[version][0][2]
+	k := 0
+	for i := 0; i < maxData; i++ {
+		for b := range blocks {
+			if i < dataLens[b] {
+				blocks[b] = append(blocks[b], raw[k])
+				k++
+			}
+		}
+	}
+	for i := 0; i < ecc; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], raw[k])
+			k++
+		}
+	}
+
+	gf := newQRSpecGF()
+	var data []byte
+	for b, block := range blocks {
+		if !gf.syndromesZero(block, ecc) {
+			return nil, fmt.Errorf("blok %d gagal cek Reed-Solomon", b)
+		}
+		data = append(data, block[:dataLens[b]]...)
+	}
+
+	// Segmen mode byte, terminator dan padding
+	pos := 0
+	read := func(n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v = v<<1 | int(data[pos/8]>>uint(7-pos%8)&1)
+			pos++
+		}
+		return v
+	}
+	if m := read(4); m != 0x4 {
+		return nil, fmt.Errorf("mode %#x bukan mode byte", m)
+	}
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	count := read(countBits)
+	if 4+countBits+count*8 > len(data)*8 {
+		return nil, fmt.Errorf("jumlah karakter %d melebihi kapasitas", count)
+	}
+	out := make([]byte, count)
+	for i := range out {
+		out[i] = byte(read(8))
+	}
+	for i := 0; i < 4 && pos < len(data)*8; i++ {
+		if read(1) != 0 {
+			return nil, errors.New("terminator tidak nol")
+		}
+	}
+	for pos%8 != 0 {
+		if read(1) != 0 {
+			return nil, errors.New("bit pengisi tidak nol")
+		}
+	}
+	for i, pad := pos/8, byte(0xEC); i < len(data); i, pad = i+1, pad^0xEC^0x11 {
+		if data[i] != pad {
+			return nil, fmt.Errorf("byte padding ke-%d %#x, seharusnya %#x", i, data[i], pad)
+		}
+	}
+	return out, nil
+}
+
+func qrAbsInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func qrMaxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// qrTestBytes - data uji deterministik yang mencakup seluruh nilai byte
+func qrTestBytes(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i*97 + 13)
+	}
+	return b
+}
+
+func TestEncodeQRRoundTripEveryVersion(t *testing.T) {
+	for version := 1; version <= 10; version++ {
+		for _, n := range []int{qrSpecByteCapacityM[version-1] + 1, qrSpecByteCapacityM[version]} {
+			data := qrTestBytes(n)
+			q, err := EncodeQR(data)
+			if err != nil {
+				t.Fatalf("%d byte: %v", n, err)
+			}
+			if want := version*4 + 17; q.Size != want {
+				t.Fatalf("%d byte: ukuran %d, seharusnya %d (versi %d)", n, q.Size, want, version)
+			}
+			got, err := decodeQRForTest(q)
+			if err != nil {
+				t.Fatalf("versi %d, %d byte: %v", version, n, err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("versi %d, %d byte: hasil decode berbeda", version, n)
+			}
+		}
+	}
+}
+
+func TestEncodeQRSignedPayload(t *testing.T) {
+	t.Setenv("QR_SIGNING_KEY", "kunci-uji")
+
+	issued := time.Date(2026, 9, 14, 8, 0, 0, 0, time.UTC)
+	token := SignQRPayload(NewQRPayload(48213, "IF-3204", 12, "K7Q2ZP", issued, 30))
+
+	q, err := EncodeQR([]byte(token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version := (q.Size - 17) / 4; version < 8 || version > 9 {
+		t.Fatalf("payload %d byte memakai versi %d, diharapkan 8-9", len(token), version)
+	}
+
+	got, err := decodeQRForTest(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != token {
+		t.Fatalf("decode %q, seharusnya %q", got, token)
+	}
+	if _, err := VerifyQRPayload(string(got), issued); err != nil {
+		t.Fatalf("payload hasil decode tidak lolos verifikasi: %v", err)
+	}
+}
+
+func TestEncodeQRTooLong(t *testing.T) {
+	if _, err := EncodeQR(qrTestBytes(qrSpecByteCapacityM[10] + 1)); err != ErrQRDataTooLong {
+		t.Fatalf("err = %v, seharusnya ErrQRDataTooLong", err)
+	}
+}
+
+func TestDecodeQRDetectsCorruption(t *testing.T) {
+	q, err := EncodeQR([]byte("https://nf.ac.id/absen"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Balik satu modul data di pojok kanan bawah: decoder referensi harus menolak
+	q.modules[q.Size-1][q.Size-1] = !q.modules[q.Size-1][q.Size-1]
+	if _, err := decodeQRForTest(q); err == nil {
+		t.Fatal("modul rusak tidak terdeteksi")
+	}
+}
+
+func TestQRCodePNG(t *testing.T) {
+	q, err := EncodeQR([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const scale = 3
+	raw, err := q.PNG(scale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dim := (q.Size + 2*qrQuietZone) * scale
+	if b := img.Bounds(); b.Dx() != dim || b.Dy() != dim {
+		t.Fatalf("ukuran gambar %v, seharusnya %dx%d", b, dim, dim)
+	}
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			r, _, _, _ := img.At((x+qrQuietZone)*scale+1, (y+qrQuietZone)*scale+1).RGBA()
+			if (r == 0) != q.Dark(x, y) {
+				t.Fatalf("piksel modul (%d,%d) tidak sesuai", x, y)
+			}
+		}
+	}
+	if r, _, _, _ := img.At(0, 0).RGBA(); r == 0 {
+		t.Fatal("quiet zone tidak putih")
+	}
+}
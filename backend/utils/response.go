@@ -84,4 +84,13 @@ func ForbiddenResponse(c *gin.Context, message string) {
 		Success: false,
 		Message: message,
 	})
-}
\ No newline at end of file
+}
+
+// ConflictResponse - Untuk response konflik beserta detailnya
+func ConflictResponse(c *gin.Context, data interface{}, message string) {
+	c.JSON(http.StatusConflict, Response{
+		Success: false,
+		Data:    data,
+		Message: message,
+	})
+}
@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Mesin kemiripan teks: shingle k-kata + MinHash untuk estimasi Jaccard cepat,
+// lalu Jaccard eksak dan pencarian cuplikan yang sama untuk pasangan yang lolos ambang.
+
+const (
+	// ShingleSize - jumlah kata per shingle
+	ShingleSize = 5
+	// MinHashSize - jumlah fungsi hash pada signature MinHash
+	MinHashSize = 128
+	// maxPassageWords - cuplikan lebih panjang dari ini dipotong
+	maxPassageWords = 60
+)
+
+var minHashSeeds = func() []uint64 {
+	seeds := make([]uint64, MinHashSize)
+	x := uint64(0x9E3779B97F4A7C15)
+	for i := range seeds {
+		x = splitMix64(x)
+		seeds[i] = x
+	}
+	return seeds
+}()
+
+// splitMix64 - pengacak 64-bit untuk menurunkan banyak fungsi hash dari satu hash shingle
+func splitMix64(x uint64) uint64 {
+	x += 0x9E3779B97F4A7C15
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	return x ^ (x >> 31)
+}
+
+// SimilarityDoc - dokumen yang sudah ditokenisasi dan di-shingle
+type SimilarityDoc struct {
+	ID        int
+	Words     []string         // kata asli (untuk cuplikan)
+	Shingles  map[uint64][]int // hash shingle -> posisi kata awal
+	Signature []uint64
+}
+
+// NewSimilarityDoc menokenisasi teks (huruf/angka, huruf kecil) dan menghitung signature MinHash
+func NewSimilarityDoc(id int, text string) *SimilarityDoc {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	doc := &SimilarityDoc{ID: id, Words: words, Shingles: map[uint64][]int{}}
+
+	for i := 0; i+ShingleSize <= len(words); i++ {
+		h := fnv.New64a()
+		for _, w := range words[i : i+ShingleSize] {
+			h.Write([]byte(strings.ToLower(w)))
+			h.Write([]byte{' '})
+		}
+		key := h.Sum64()
+		doc.Shingles[key] = append(doc.Shingles[key], i)
+	}
+
+	if len(doc.Shingles) > 0 {
+		doc.Signature = make([]uint64, MinHashSize)
+		for i := range doc.Signature {
+			doc.Signature[i] = ^uint64(0)
+		}
+		for key := range doc.Shingles {
+			for i, seed := range minHashSeeds {
+				if v := splitMix64(key ^ seed); v < doc.Signature[i] {
+					doc.Signature[i] = v
+				}
+			}
+		}
+	}
+	return doc
+}
+
+// Comparable - dokumen punya cukup kata untuk dibandingkan
+func (d *SimilarityDoc) Comparable() bool {
+	return len(d.Signature) == MinHashSize
+}
+
+// EstimateSimilarity - estimasi Jaccard dari signature MinHash (0-1)
+func EstimateSimilarity(a, b *SimilarityDoc) float64 {
+	if !a.Comparable() || !b.Comparable() {
+		return 0
+	}
+	same := 0
+	for i := range a.Signature {
+		if a.Signature[i] == b.Signature[i] {
+			same++
+		}
+	}
+	return float64(same) / MinHashSize
+}
+
+// Jaccard - kemiripan Jaccard eksak himpunan shingle (0-1)
+func Jaccard(a, b *SimilarityDoc) float64 {
+	if len(a.Shingles) == 0 || len(b.Shingles) == 0 {
+		return 0
+	}
+	inter := 0
+	for key := range a.Shingles {
+		if _, ok := b.Shingles[key]; ok {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a.Shingles)+len(b.Shingles)-inter)
+}
+
+// SimilarityPassage - cuplikan yang sama pada kedua dokumen
+type SimilarityPassage struct {
+	TextA string `json:"text_a"`
+	TextB string `json:"text_b"`
+	Words int    `json:"words"`
+}
+
+// MatchingPassages mencari rentetan kata berurutan yang muncul di kedua dokumen, terpanjang lebih dulu
+func MatchingPassages(a, b *SimilarityDoc, limit int) []SimilarityPassage {
+	// Tandai posisi awal shingle di A yang juga ada di B
+	shared := make([]int, len(a.Words)) // posisi di B + 1, 0 = tidak sama
+	for key, positions := range a.Shingles {
+		if bPos, ok := b.Shingles[key]; ok {
+			for _, p := range positions {
+				shared[p] = bPos[0] + 1
+			}
+		}
+	}
+
+	var passages []SimilarityPassage
+	for i := 0; i < len(shared); {
+		if shared[i] == 0 {
+			i++
+			continue
+		}
+		startA, startB := i, shared[i]-1
+		j := i
+		for j+1 < len(shared) && shared[j+1] != 0 {
+			j++
+		}
+		endA := j + ShingleSize
+		endB := startB + (endA - startA)
+		if endB > len(b.Words) {
+			endB = len(b.Words)
+		}
+		passages = append(passages, SimilarityPassage{
+			TextA: passageText(a.Words[startA:endA]),
+			TextB: passageText(b.Words[startB:endB]),
+			Words: endA - startA,
+		})
+		i = j + 1
+	}
+
+	sort.SliceStable(passages, func(i, j int) bool { return passages[i].Words > passages[j].Words })
+	if limit > 0 && len(passages) > limit {
+		passages = passages[:limit]
+	}
+	return passages
+}
+
+func passageText(words []string) string {
+	if len(words) > maxPassageWords {
+		return strings.Join(words[:maxPassageWords], " ") + " …"
+	}
+	return strings.Join(words, " ")
+}
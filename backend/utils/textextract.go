@@ -0,0 +1,320 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MaxExtractFileSize - file lebih besar dari ini tidak diekstrak (20 MB)
+const MaxExtractFileSize = 20 << 20
+
+// Batas hasil dekompresi content stream PDF dan word/document.xml DOCX agar file kecil yang
+// mengembang besar (decompression bomb) tidak menghabiskan memori
+const (
+	maxPDFStreamSize   = 8 << 20
+	maxPDFInflatedSize = 32 << 20
+	maxDOCXXMLSize     = 32 << 20
+)
+
+// ErrUnsupportedDocument - tipe file tidak bisa diekstrak teksnya
+var ErrUnsupportedDocument = errors.New("tipe file tidak didukung untuk ekstraksi teks")
+
+// ExtractDocumentText mengambil teks polos dari file .pdf, .docx, atau .txt
+func ExtractDocumentText(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > MaxExtractFileSize {
+		return "", errors.New("file terlalu besar untuk diekstrak")
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".docx":
+		return extractDOCXText(path)
+	case ".pdf":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return extractPDFText(data), nil
+	case ".txt":
+		data, err := os.ReadFile(path)
+		return string(data), err
+	}
+	return "", ErrUnsupportedDocument
+}
+
+// extractDOCXText membaca word/document.xml: teks ada di <w:t>, paragraf di <w:p>
+func extractDOCXText(path string) (string, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.Name != "word/document.xml" {
+			continue
+		}
+		if f.UncompressedSize64 > maxDOCXXMLSize {
+			return "", errors.New("word/document.xml terlalu besar untuk diekstrak")
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+
+		var b strings.Builder
+		dec := xml.NewDecoder(io.LimitReader(rc, maxDOCXXMLSize))
+		inText := false
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return b.String(), err
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				switch t.Name.Local {
+				case "t":
+					inText = true
+				case "tab":
+					b.WriteByte(' ')
+				case "br":
+					b.WriteByte('\n')
+				}
+			case xml.EndElement:
+				switch t.Name.Local {
+				case "t":
+					inText = false
+				case "p":
+					b.WriteByte('\n')
+				}
+			case xml.CharData:
+				if inText {
+					b.Write(t)
+				}
+			}
+		}
+		return b.String(), nil
+	}
+	return "", errors.New("word/document.xml tidak ditemukan")
+}
+
+var pdfStreamDict = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+
+// extractPDFText mengambil teks dari operator Tj/TJ/'/" pada content stream PDF.
+// Hanya mendukung font dengan encoding 1 byte (WinAnsi/Standard); font CID (Identity-H)
+// menghasilkan teks yang tidak terbaca dan disaring oleh pemanggil lewat skor kemiripan yang rendah.
+func extractPDFText(data []byte) string {
+	var out strings.Builder
+	inflatedTotal := 0
+	for _, loc := range pdfStreamDict.FindAllSubmatchIndex(data, -1) {
+		dict := data[loc[2]:loc[3]]
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+		raw := data[start : start+end]
+
+		if bytes.Contains(dict, []byte("/Image")) || bytes.Contains(dict, []byte("/DCTDecode")) {
+			continue
+		}
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			zr, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				continue
+			}
+			limit := maxPDFStreamSize
+			if remaining := maxPDFInflatedSize - inflatedTotal; remaining < limit {
+				limit = remaining
+			}
+			// Baca satu byte lebih dari batas untuk mendeteksi stream yang terpotong
+			inflated, err := io.ReadAll(io.LimitReader(zr, int64(limit)+1))
+			zr.Close()
+			if len(inflated) > limit {
+				break
+			}
+			if err != nil && len(inflated) == 0 {
+				continue
+			}
+			inflatedTotal += len(inflated)
+			raw = inflated
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue
+		}
+
+		if bytes.Contains(raw, []byte("BT")) {
+			parsePDFContent(raw, &out)
+		}
+	}
+	return out.String()
+}
+
+// parsePDFContent menelusuri operator teks di satu content stream
+func parsePDFContent(content []byte, out *strings.Builder) {
+	var pending []string
+	flush := func(sep string) {
+		for _, s := range pending {
+			out.WriteString(s)
+		}
+		pending = pending[:0]
+		out.WriteString(sep)
+	}
+
+	inArray := false
+	for i := 0; i < len(content); i++ {
+		ch := content[i]
+		switch {
+		case ch == '(':
+			s, next := readPDFLiteral(content, i)
+			pending = append(pending, s)
+			i = next
+		case ch == '<' && i+1 < len(content) && content[i+1] != '<':
+			s, next := readPDFHex(content, i)
+			pending = append(pending, s)
+			i = next
+		case ch == '[':
+			inArray = true
+		case ch == ']':
+			inArray = false
+		case ch == '%' && !inArray:
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case inArray && (ch == '-' || (ch >= '0' && ch <= '9')):
+			// Kerning besar di dalam TJ biasanya berarti spasi antar kata
+			j := i + 1
+			for j < len(content) && (content[j] == '.' || (content[j] >= '0' && content[j] <= '9')) {
+				j++
+			}
+			if ch == '-' && j-i >= 4 {
+				pending = append(pending, " ")
+			}
+			i = j - 1
+		case isPDFOperatorByte(ch) && !inArray:
+			j := i
+			for j < len(content) && isPDFOperatorByte(content[j]) {
+				j++
+			}
+			switch string(content[i:j]) {
+			case "Tj", "TJ":
+				flush("")
+			case "'", "\"", "T*", "Td", "TD":
+				flush("\n")
+			case "ET":
+				flush("\n")
+			default:
+				pending = pending[:0]
+			}
+			i = j - 1
+		}
+	}
+}
+
+func isPDFOperatorByte(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '*' || ch == '\'' || ch == '"'
+}
+
+// readPDFLiteral membaca string (...) dengan kurung bersarang dan escape, mengembalikan posisi ')' penutup
+func readPDFLiteral(content []byte, i int) (string, int) {
+	var b strings.Builder
+	depth := 0
+	for i++; i < len(content); i++ {
+		ch := content[i]
+		switch ch {
+		case '\\':
+			i++
+			if i >= len(content) {
+				return b.String(), i
+			}
+			switch e := content[i]; e {
+			case 'n', 'r':
+				b.WriteByte(' ')
+			case 't':
+				b.WriteByte(' ')
+			case 'b', 'f':
+			case '\r', '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					for k := 0; k < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; k++ {
+						v = v*8 + int(content[i]-'0')
+						i++
+					}
+					i--
+					b.WriteRune(pdfByteRune(byte(v)))
+				} else {
+					b.WriteByte(e)
+				}
+			}
+		case '(':
+			depth++
+			b.WriteByte(ch)
+		case ')':
+			if depth == 0 {
+				return b.String(), i
+			}
+			depth--
+			b.WriteByte(ch)
+		default:
+			b.WriteRune(pdfByteRune(ch))
+		}
+	}
+	return b.String(), i
+}
+
+// readPDFHex membaca string <48656C6C6F>, mengembalikan posisi '>' penutup
+func readPDFHex(content []byte, i int) (string, int) {
+	end := bytes.IndexByte(content[i:], '>')
+	if end < 0 {
+		return "", len(content)
+	}
+	var digits []byte
+	for _, ch := range content[i+1 : i+end] {
+		if unicode.Is(unicode.ASCII_Hex_Digit, rune(ch)) {
+			digits = append(digits, ch)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	var b strings.Builder
+	for k := 0; k < len(digits); k += 2 {
+		b.WriteRune(pdfByteRune(hexNibble(digits[k])<<4 | hexNibble(digits[k+1])))
+	}
+	return b.String(), i + end
+}
+
+func hexNibble(ch byte) byte {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return ch - '0'
+	case ch >= 'a' && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
+}
+
+// pdfByteRune - byte WinAnsi ke rune (Latin-1 cukup untuk teks Indonesia/Inggris)
+func pdfByteRune(ch byte) rune {
+	if ch < 0x20 {
+		return ' '
+	}
+	return rune(ch)
+}
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+)
+
+// readZipEntries - isi seluruh file di dalam arsip zip
+func readZipEntries(t *testing.T, raw []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatalf("bukan zip yang valid: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = body
+	}
+	return files
+}
+
+type xlsxTestCell struct {
+	Ref   string `xml:"r,attr"`
+	Type  string `xml:"t,attr"`
+	Value string `xml:"v"`
+	Text  string `xml:"is>t"`
+}
+
+type xlsxTestWorksheet struct {
+	Rows []struct {
+		Ref   int            `xml:"r,attr"`
+		Cells []xlsxTestCell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteXLSX(&buf, []XLSXSheet{
+		{Name: "IF-101/A", Rows: [][]interface{}{
+			{"NIM", "Nama", "Hadir", "% Kehadiran"},
+			{"2301001", "Budi <Santoso> & Co", 14, 87.5},
+			{nil, "", 0, 100.0},
+		}},
+		{Name: "if-101/a", Rows: [][]interface{}{{"duplikat"}}},
+		{Name: "", Rows: nil},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := readZipEntries(t, buf.Bytes())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels",
+		"xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml", "xl/worksheets/sheet3.xml"} {
+		body, ok := files[name]
+		if !ok {
+			t.Fatalf("file %s tidak ada", name)
+		}
+		d := xml.NewDecoder(bytes.NewReader(body))
+		for {
+			_, err := d.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s bukan XML yang valid: %v", name, err)
+			}
+		}
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(files["xl/workbook.xml"], &workbook); err != nil {
+		t.Fatal(err)
+	}
+	wantNames := []string{"IF-101-A", "if-101-a (2)", "Sheet3"}
+	if len(workbook.Sheets) != len(wantNames) {
+		t.Fatalf("jumlah sheet %d, seharusnya %d", len(workbook.Sheets), len(wantNames))
+	}
+	for i, want := range wantNames {
+		if workbook.Sheets[i].Name != want {
+			t.Errorf("nama sheet %d = %q, seharusnya %q", i+1, workbook.Sheets[i].Name, want)
+		}
+	}
+
+	var sheet xlsxTestWorksheet
+	if err := xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatal(err)
+	}
+	cells := map[string]xlsxTestCell{}
+	for _, row := range sheet.Rows {
+		for _, c := range row.Cells {
+			cells[c.Ref] = c
+		}
+	}
+
+	tests := []struct {
+		ref, typ, value string
+	}{
+		{"A1", "inlineStr", "NIM"},
+		{"D1", "inlineStr", "% Kehadiran"},
+		{"B2", "inlineStr", "Budi <Santoso> & Co"},
+		{"C2", "", "14"},
+		{"D2", "", "87.50"},
+		{"C3", "", "0"},
+		{"D3", "", "100.00"},
+	}
+	for _, tt := range tests {
+		c, ok := cells[tt.ref]
+		if !ok {
+			t.Errorf("sel %s tidak ada", tt.ref)
+			continue
+		}
+		got := c.Value
+		if c.Type == "inlineStr" {
+			got = c.Text
+		}
+		if c.Type != tt.typ || got != tt.value {
+			t.Errorf("sel %s = (%q, %q), seharusnya (%q, %q)", tt.ref, c.Type, got, tt.typ, tt.value)
+		}
+	}
+	for _, ref := range []string{"A3", "B3"} {
+		if _, ok := cells[ref]; ok {
+			t.Errorf("sel kosong %s seharusnya tidak ditulis", ref)
+		}
+	}
+}
+
+func TestXLSXColumnName(t *testing.T) {
+	tests := map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"}
+	for col, want := range tests {
+		if got := xlsxColumnName(col); got != want {
+			t.Errorf("xlsxColumnName(%d) = %q, seharusnya %q", col, got, want)
+		}
+	}
+}
+
+func TestXLSXSheetName(t *testing.T) {
+	used := map[string]bool{}
+	long := "Rekap Absensi Pemrograman Berorientasi Objek"
+
+	first := xlsxSheetName(long, 1, used)
+	second := xlsxSheetName(long, 2, used)
+	if len(first) > 31 || len(second) > 31 {
+		t.Fatalf("nama sheet melebihi 31 karakter: %q, %q", first, second)
+	}
+	if first == second {
+		t.Fatalf("nama sheet kembar: %q", first)
+	}
+	if got := xlsxSheetName(`a[b]c:d*e?f/g\h`, 3, used); got != "a-b-c-d-e-f-g-h" {
+		t.Fatalf("karakter terlarang tidak diganti: %q", got)
+	}
+}